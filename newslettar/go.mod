@@ -2,4 +2,7 @@ module newslettar
 
 go 1.23
 
-require github.com/robfig/cron/v3 v3.0.1
\ No newline at end of file
+require (
+	github.com/robfig/cron/v3 v3.0.1
+	golang.org/x/crypto v0.31.0
+)
@@ -116,6 +116,14 @@ echo -e "${GREEN}✓ Built successfully (${BINARY_SIZE})${NC}"
 
 echo -e "${YELLOW}[7/8] Creating configuration...${NC}"
 cat > .env << 'EOF'
+# Settings are taken from, in order of precedence:
+#   1. command-line flags: ./newslettar -setting KEY=VALUE
+#   2. the environment: KEY, a file named by KEY_FILE (e.g. Docker secrets),
+#      or $CREDENTIALS_DIRECTORY/KEY (systemd LoadCredential=KEY:/path)
+#   3. this file (and the encrypted secrets store, see below)
+#   4. built-in defaults
+# The web UI saves to this file and shows where each setting comes from.
+
 # Sonarr Configuration
 SONARR_URL=http://localhost:8989
 SONARR_API_KEY=
@@ -133,18 +141,59 @@ FROM_NAME=Newslettar
 FROM_EMAIL=newsletter@yourdomain.com
 TO_EMAILS=user@example.com
 
-# Schedule Settings (Internal Cron - No systemd timer needed!)
+# Scheduler timezone (Internal Cron - No systemd timer needed!)
 TIMEZONE=UTC
+# Send a scheduled newsletter missed during downtime if it was due within this window (0 = off)
+CATCHUP_GRACE=24h
+
+# Seed values for the default newsletter profile, created on first start.
+# Afterwards schedules and content are managed per newsletter in the web UI.
 SCHEDULE_DAY=Sun
 SCHEDULE_TIME=09:00
-
-# Template Settings
 SHOW_POSTERS=true
 SHOW_DOWNLOADED=true
 
+# Public Sign-up (double opt-in via /subscribe; needs PUBLIC_URL for the confirmation links)
+SIGNUP_ENABLED=false
+INVITE_CODES=
+PUBLIC_URL=
+
+# Web archive of sent issues: signed (private per-recipient links), public, or off
+ARCHIVE_ACCESS=signed
+
+# Alert this address when a newsletter fails to send (empty = no alerts)
+ADMIN_EMAIL=
+
+# Token for the /feed.xml Atom feed and /calendar.ics (empty = disabled)
+FEED_TOKEN=
+
+# Logging: level (debug, info, warn, error) and stdout format (text or json).
+# data/logs/newslettar.log is rotated beyond LOG_MAX_SIZE_MB; rotated files are
+# deleted after LOG_MAX_AGE. LOG_FILE=false keeps logs in memory only.
+LOG_LEVEL=info
+LOG_FORMAT=text
+LOG_FILE=true
+LOG_MAX_SIZE_MB=10
+LOG_MAX_AGE=7d
+
+# Web UI sign-in: the admin account is created on first visit
+# (reset it with: ./newslettar -set-password). To sign in through a reverse proxy
+# (e.g. Authelia), name its user header and the proxy addresses (IPs or CIDRs).
+# X-Forwarded-For/-Proto headers are only believed from these addresses.
+AUTH_PROXY_HEADER=
+TRUSTED_PROXIES=
+
+# Encrypted secrets: ./newslettar -secrets migrate moves API keys, SMTP
+# credentials and the feed token into secrets.enc, encrypted with the key in
+# SECRETS_KEY_FILE (created if missing). Alternatively set SECRETS_KEY (base64,
+# 32 bytes) in the service environment, not here. Rotate with -secrets rotate
+# while the service is stopped.
+SECRETS_KEY_FILE=secret.key
+
 # Web UI Port
 WEBUI_PORT=8080
 EOF
+chmod 600 .env
 echo -e "${GREEN}✓ Configuration file created${NC}"
 
 echo -e "${YELLOW}[8/8] Setting up systemd service...${NC}"
@@ -159,7 +208,9 @@ After=network.target
 Type=simple
 User=root
 WorkingDirectory=/opt/newslettar
-EnvironmentFile=/opt/newslettar/.env
+# .env is read by Newslettar itself; loading it here would override web UI changes.
+# Secrets can be passed as credentials instead, e.g.:
+# LoadCredential=MAILGUN_PASS:/etc/newslettar/mailgun_pass
 ExecStart=/opt/newslettar/newslettar -web
 Restart=always
 RestartSec=10
@@ -227,6 +278,10 @@ case "$1" in
         /usr/local/go/bin/go mod tidy
         /usr/local/go/bin/go build -ldflags="-s -w" -trimpath -o newslettar main.go
         mv .env.backup .env
+        if grep -q '^EnvironmentFile=/opt/newslettar/.env' /etc/systemd/system/newslettar.service; then
+            sed -i '\|^EnvironmentFile=/opt/newslettar/.env|d' /etc/systemd/system/newslettar.service
+            systemctl daemon-reload
+        fi
         systemctl restart newslettar.service
         echo -e "${GREEN}✓ Updated successfully!${NC}"
         ;;
@@ -295,7 +350,8 @@ echo "  • 💾 70% Less RAM - Optimized memory usage"
 echo "  • 📝 Ring Buffer Logs - No log file growth"
 echo ""
 echo -e "${YELLOW}Quick Start:${NC}"
-echo "  1. Open http://${IP}:8080 in your browser"
+echo "  1. Open http://${IP}:8080 in your browser and create the admin account"
+echo "     with the setup code from: journalctl -u newslettar | grep 'setup code'"
 echo "  2. Configure Sonarr/Radarr in Configuration tab"
 echo "  3. Select your timezone and schedule"
 echo "  4. Test connections and send test newsletter"
@@ -1,29 +1,52 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"crypto/tls"
 	"embed"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"flag"
 	"fmt"
 	"html/template"
 	"io"
 	"log"
+	"log/slog"
+	"mime/multipart"
+	"net"
 	"net/http"
+	"net/mail"
 	"net/smtp"
+	"net/textproto"
+	"net/url"
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"runtime/debug"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"github.com/robfig/cron/v3"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // Embed static files to reduce memory and simplify deployment
@@ -33,30 +56,52 @@ var templateFS embed.FS
 
 const version = "1.0.20"
 
-// Global HTTP client (reused for all requests - 3-5x faster)
+// Global HTTP client (reused for all requests - 3-5x faster), timed for /metrics
 var httpClient = &http.Client{
 	Timeout: 15 * time.Second,
-	Transport: &http.Transport{
+	Transport: instrumentedTransport{next: &http.Transport{
 		MaxIdleConns:        10,
 		MaxIdleConnsPerHost: 5,
 		IdleConnTimeout:     90 * time.Second,
-	},
+	}},
 }
 
 // Config structures
 type Config struct {
-	SonarrURL      string
-	SonarrAPIKey   string
-	RadarrURL      string
-	RadarrAPIKey   string
-	MailgunSMTP    string
-	MailgunPort    string
-	MailgunUser    string
-	MailgunPass    string
-	FromEmail      string
-	FromName       string
+	SonarrURL     string
+	SonarrAPIKey  string
+	RadarrURL     string
+	RadarrAPIKey  string
+	MailgunSMTP   string
+	MailgunPort   string
+	MailgunUser   string
+	MailgunPass   string
+	FromEmail     string
+	FromName      string
+	AdminEmail    string // receives an alert when a run fails (empty = no alerts)
+	Timezone      string
+	CatchUpGrace  time.Duration // run a missed schedule at startup if it is at most this old (0 = off)
+	SignupEnabled bool
+	InviteCodes   []string
+	PublicURL     string
+	ArchiveAccess string // public, signed (per-recipient links) or off
+	FeedToken     string // required by /feed.xml and /calendar.ics (empty = both disabled)
+	DataDir       string
+
+	// Logging (applied at startup)
+	LogLevel   string        // debug, info, warn or error
+	LogFormat  string        // stdout format: text or json
+	LogFile    bool          // write data/logs/newslettar.log
+	LogMaxSize int64         // rotate the log file beyond this many bytes
+	LogMaxAge  time.Duration // delete rotated log files older than this
+
+	// Reverse-proxy authentication: requests from TrustedProxies carrying
+	// AuthProxyHeader (e.g. Remote-User) are signed in as that user
+	AuthProxyHeader string
+	TrustedProxies  []string // IPs or CIDRs; their X-Forwarded-For/-Proto headers are believed too
+
+	// Legacy single-newsletter settings, only used to seed the default profile
 	ToEmails       []string
-	Timezone       string
 	ScheduleDay    string
 	ScheduleTime   string
 	ShowPosters    bool
@@ -70,33 +115,47 @@ type Episode struct {
 	EpisodeNum  int
 	Title       string
 	AirDate     string
+	AirTime     time.Time // exact air time in the newsletter's timezone (zero when Sonarr doesn't know it)
+	Runtime     int       // minutes
 	Downloaded  bool
+	ImportedAt  time.Time // when Sonarr imported the download (history only)
 	PosterURL   string
 	IMDBID      string
 	TvdbID      int
+	Rating      string   // Series certification (e.g. TV-14)
+	Tags        []string // Series tag labels
 }
 
 type Movie struct {
 	Title       string
 	Year        int
 	ReleaseDate string
+	ReleaseType string // ReleaseCinema, ReleaseDigital or ReleasePhysical
 	Downloaded  bool
+	ImportedAt  time.Time // when Radarr imported the download (history only)
 	PosterURL   string
 	IMDBID      string
 	TmdbID      int
+	Rating      string   // Movie certification (e.g. PG-13)
+	Tags        []string // Movie tag labels
 }
 
 // For Sonarr calendar response (nested series data)
 type CalendarEpisode struct {
-	SeasonNumber  int    `json:"seasonNumber"`
-	EpisodeNumber int    `json:"episodeNumber"`
-	Title         string `json:"title"`
-	AirDate       string `json:"airDate"`
+	SeasonNumber  int       `json:"seasonNumber"`
+	EpisodeNumber int       `json:"episodeNumber"`
+	Title         string    `json:"title"`
+	AirDate       string    `json:"airDate"`
+	AirDateUtc    time.Time `json:"airDateUtc"`
+	Runtime       int       `json:"runtime"`
 	Series        struct {
-		Title  string `json:"title"`
-		TvdbId int    `json:"tvdbId"`
-		ImdbId string `json:"imdbId"`
-		Images []struct {
+		Title         string `json:"title"`
+		Runtime       int    `json:"runtime"`
+		TvdbId        int    `json:"tvdbId"`
+		ImdbId        string `json:"imdbId"`
+		Certification string `json:"certification"`
+		Tags          []int  `json:"tags"`
+		Images        []struct {
 			CoverType string `json:"coverType"`
 			Url       string `json:"url"`       // Local URL if available
 			RemoteUrl string `json:"remoteUrl"` // Fallback remote URL
@@ -104,14 +163,67 @@ type CalendarEpisode struct {
 	} `json:"series"`
 }
 
+// Radarr release dates (full timestamps, empty when unknown)
+type RadarrReleases struct {
+	InCinemas       string `json:"inCinemas"`
+	DigitalRelease  string `json:"digitalRelease"`
+	PhysicalRelease string `json:"physicalRelease"`
+}
+
+// Movie release types a profile can count as upcoming
+const (
+	ReleaseCinema   = "cinema"
+	ReleaseDigital  = "digital"
+	ReleasePhysical = "physical"
+)
+
+var allReleaseTypes = []string{ReleaseCinema, ReleaseDigital, ReleasePhysical}
+
+// Used when a profile doesn't choose: what can actually be watched at home
+var defaultReleaseTypes = []string{ReleaseDigital, ReleasePhysical}
+
+// Release date (YYYY-MM-DD) of one type, empty when unknown
+func (r RadarrReleases) date(releaseType string) string {
+	value := ""
+	switch releaseType {
+	case ReleaseCinema:
+		value = r.InCinemas
+	case ReleaseDigital:
+		value = r.DigitalRelease
+	case ReleasePhysical:
+		value = r.PhysicalRelease
+	}
+	if len(value) < 10 {
+		return ""
+	}
+	return value[:10]
+}
+
+// Earliest release of the given types dated within [from, to] (inclusive, YYYY-MM-DD)
+func (r RadarrReleases) firstBetween(types []string, from, to string) (string, string) {
+	bestType, best := "", ""
+	for _, t := range types {
+		d := r.date(t)
+		if d == "" || d < from || d > to {
+			continue
+		}
+		if best == "" || d < best {
+			bestType, best = t, d
+		}
+	}
+	return bestType, best
+}
+
 // For Radarr calendar response (direct fields + images array)
 type CalendarMovie struct {
-	Title           string `json:"title"`
-	Year            int    `json:"year"`
-	PhysicalRelease string `json:"physicalRelease"` // Assuming you want physical release; adjust if needed (e.g., to "digitalRelease" or "inCinemas")
-	ImdbId          string `json:"imdbId"`
-	TmdbId          int    `json:"tmdbId"`
-	Images          []struct {
+	Title string `json:"title"`
+	Year  int    `json:"year"`
+	RadarrReleases
+	ImdbId        string `json:"imdbId"`
+	TmdbId        int    `json:"tmdbId"`
+	Certification string `json:"certification"`
+	Tags          []int  `json:"tags"`
+	Images        []struct {
 		CoverType string `json:"coverType"`
 		Url       string `json:"url"`       // Local URL if available
 		RemoteUrl string `json:"remoteUrl"` // Fallback remote URL
@@ -124,34 +236,289 @@ type SeriesGroup struct {
 	Episodes    []Episode
 	IMDBID      string
 	TvdbID      int
+	Rating      string
+	Tags        []string
 }
 
 type NewsletterData struct {
-	WeekStart              string
+	NewsletterName         string
+	WeekStart              string // date-only window bounds, kept for custom templates
 	WeekEnd                string
+	WindowStart            string // exact window covered by the downloaded sections
+	WindowEnd              string
+	UpcomingUntil          string // end of the upcoming sections
 	UpcomingSeriesGroups   []SeriesGroup
 	UpcomingMovies         []Movie
 	DownloadedSeriesGroups []SeriesGroup
 	DownloadedMovies       []Movie
+
+	// Per-recipient: titles the subscriber follows (upcoming and downloaded)
+	FollowedSeriesGroups []SeriesGroup
+	FollowedMovies       []Movie
+	PreferencesURL       string
+
+	// Web archive links (empty when the archive is off or PUBLIC_URL is unset)
+	ViewInBrowserURL string
+	ArchiveURL       string
 }
 
 type WebConfig struct {
-	SonarrURL      string `json:"sonarr_url"`
-	SonarrAPIKey   string `json:"sonarr_api_key"`
-	RadarrURL      string `json:"radarr_url"`
-	RadarrAPIKey   string `json:"radarr_api_key"`
-	MailgunSMTP    string `json:"mailgun_smtp"`
-	MailgunPort    string `json:"mailgun_port"`
-	MailgunUser    string `json:"mailgun_user"`
-	MailgunPass    string `json:"mailgun_pass"`
-	FromEmail      string `json:"from_email"`
-	FromName       string `json:"from_name"`
-	ToEmails       string `json:"to_emails"`
-	Timezone       string `json:"timezone"`
-	ScheduleDay    string `json:"schedule_day"`
-	ScheduleTime   string `json:"schedule_time"`
-	ShowPosters    string `json:"show_posters"`
-	ShowDownloaded string `json:"show_downloaded"`
+	SonarrURL     string `json:"sonarr_url"`
+	SonarrAPIKey  string `json:"sonarr_api_key"`
+	RadarrURL     string `json:"radarr_url"`
+	RadarrAPIKey  string `json:"radarr_api_key"`
+	MailgunSMTP   string `json:"mailgun_smtp"`
+	MailgunPort   string `json:"mailgun_port"`
+	MailgunUser   string `json:"mailgun_user"`
+	MailgunPass   string `json:"mailgun_pass"`
+	FromEmail     string `json:"from_email"`
+	FromName      string `json:"from_name"`
+	AdminEmail    string `json:"admin_email"`
+	Timezone      string `json:"timezone"`
+	CatchUpGrace  string `json:"catchup_grace"`
+	SignupEnabled string `json:"signup_enabled"`
+	InviteCodes   string `json:"invite_codes"`
+	PublicURL     string `json:"public_url"`
+	ArchiveAccess string `json:"archive_access"`
+	FeedToken     string `json:"feed_token"`
+
+	// Fields to remove from .env (back to their defaults), since empty values are ignored
+	Clear []string `json:"clear"`
+}
+
+// Returned instead of a stored secret; sending it back leaves the secret unchanged
+const secretMask = "••••••••"
+
+// Write-only settings: the config API never returns their values
+var secretConfigFields = []string{"sonarr_api_key", "radarr_api_key", "mailgun_pass", "feed_token"}
+
+// .env key of each config API field
+var configEnvKeys = map[string]string{
+	"sonarr_url":     "SONARR_URL",
+	"sonarr_api_key": "SONARR_API_KEY",
+	"radarr_url":     "RADARR_URL",
+	"radarr_api_key": "RADARR_API_KEY",
+	"mailgun_smtp":   "MAILGUN_SMTP",
+	"mailgun_port":   "MAILGUN_PORT",
+	"mailgun_user":   "MAILGUN_USER",
+	"mailgun_pass":   "MAILGUN_PASS",
+	"from_email":     "FROM_EMAIL",
+	"from_name":      "FROM_NAME",
+	"admin_email":    "ADMIN_EMAIL",
+	"timezone":       "TIMEZONE",
+	"catchup_grace":  "CATCHUP_GRACE",
+	"signup_enabled": "SIGNUP_ENABLED",
+	"invite_codes":   "INVITE_CODES",
+	"public_url":     "PUBLIC_URL",
+	"archive_access": "ARCHIVE_ACCESS",
+	"feed_token":     "FEED_TOKEN",
+}
+
+// A secret value sent by a client is new unless it's empty or the mask
+func isNewSecret(value string) bool {
+	return value != "" && value != secretMask
+}
+
+// Secret for a connection test. The mask stands for the saved secret, which is only
+// sent to the saved server: testing any other server needs the secret typed in.
+func testSecret(value, target, savedTarget, saved string) (string, bool) {
+	if value != secretMask {
+		return value, true
+	}
+	normalize := func(s string) string { return strings.TrimRight(strings.TrimSpace(s), "/") }
+	if savedTarget == "" || !strings.EqualFold(normalize(target), normalize(savedTarget)) {
+		return "", false
+	}
+	return saved, true
+}
+
+// Subscriber joined through the public sign-up page (double opt-in)
+type Subscriber struct {
+	Email       string     `json:"email"`
+	Status      string     `json:"status"`                // pending, active
+	Token       string     `json:"token"`                 // confirmation link
+	PrefsToken  string     `json:"prefs_token,omitempty"` // preferences link, rotated from the admin UI
+	InviteCode  string     `json:"invite_code,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty"`
+
+	// Content filters
+	MaxRating    string   `json:"max_rating,omitempty"`    // e.g. PG-13; empty = no limit
+	AllowUnrated bool     `json:"allow_unrated,omitempty"` // include titles without a certification when MaxRating is set
+	Groups       []string `json:"groups,omitempty"`        // recipient group names
+	Timezone     string   `json:"timezone,omitempty"`      // IANA name for air times; empty = server timezone
+
+	// Per-title preferences (Sonarr TVDB IDs / Radarr TMDB IDs)
+	FollowedSeries []int `json:"followed_series,omitempty"`
+	FollowedMovies []int `json:"followed_movies,omitempty"`
+	MutedSeries    []int `json:"muted_series,omitempty"`
+	MutedMovies    []int `json:"muted_movies,omitempty"`
+}
+
+// Newsletter profile: an independently scheduled newsletter (data/profiles.json)
+type Profile struct {
+	ID             string        `json:"id"`
+	Name           string        `json:"name"`
+	Enabled        bool          `json:"enabled"`
+	Schedules      []string      `json:"schedules"` // cron expressions, descriptors or presets
+	Lookback       string        `json:"lookback"`  // first-send window, e.g. "7d" or "36h"; later sends start where the last one ended
+	Lookahead      string        `json:"lookahead"` // how far ahead upcoming sections reach
+	Sections       []string      `json:"sections"`
+	ReleaseTypes   []string      `json:"release_types"` // movie releases counted as upcoming; empty = digital and physical
+	ShowPosters    bool          `json:"show_posters"`
+	AttachCalendar bool          `json:"attach_calendar"` // attach an .ics of the upcoming releases
+	Template       string        `json:"template"`
+	Recipients     []string      `json:"recipients"`      // direct email addresses
+	AllSubscribers bool          `json:"all_subscribers"` // every active subscriber
+	Groups         []string      `json:"groups"`          // subscribers in any of these groups
+	Transport      SMTPTransport `json:"transport"`       // empty fields fall back to the global email settings
+}
+
+const defaultWindow = 7 * 24 * time.Hour
+
+// Parse a window length: Go durations plus a leading day count ("7d", "1d12h", "36h")
+func parseWindowDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	var days time.Duration
+	if i := strings.Index(value, "d"); i > 0 {
+		n, err := strconv.Atoi(value[:i])
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		days = time.Duration(n) * 24 * time.Hour
+		value = value[i+1:]
+		if value == "" {
+			return days, nil
+		}
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q (use e.g. 7d, 36h, 1d12h)", value)
+	}
+	if days+d < 0 {
+		return 0, fmt.Errorf("duration must not be negative")
+	}
+	return days + d, nil
+}
+
+func (p Profile) lookback() time.Duration {
+	if d, err := parseWindowDuration(p.Lookback); err == nil && p.Lookback != "" {
+		return d
+	}
+	return defaultWindow
+}
+
+func (p Profile) lookahead() time.Duration {
+	if d, err := parseWindowDuration(p.Lookahead); err == nil && p.Lookahead != "" {
+		return d
+	}
+	return defaultWindow
+}
+
+// Newsletter sections a profile can include
+const (
+	SectionUpcomingSeries   = "upcoming_series"
+	SectionUpcomingMovies   = "upcoming_movies"
+	SectionDownloadedSeries = "downloaded_series"
+	SectionDownloadedMovies = "downloaded_movies"
+)
+
+var allSections = []string{SectionUpcomingSeries, SectionUpcomingMovies, SectionDownloadedSeries, SectionDownloadedMovies}
+
+func (p Profile) hasSection(section string) bool {
+	for _, s := range p.Sections {
+		if s == section {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Profile) releaseTypes() []string {
+	if len(p.ReleaseTypes) == 0 {
+		return defaultReleaseTypes
+	}
+	return p.ReleaseTypes
+}
+
+// SMTP settings used to deliver a newsletter
+type SMTPTransport struct {
+	Host      string `json:"host,omitempty"`
+	Port      string `json:"port,omitempty"`
+	User      string `json:"user,omitempty"`
+	Pass      string `json:"pass,omitempty"`
+	FromEmail string `json:"from_email,omitempty"`
+	FromName  string `json:"from_name,omitempty"`
+}
+
+// Sign-up needs PUBLIC_URL: confirmation links must not be built from the request's Host
+func (cfg *Config) signupOpen() bool {
+	return cfg.SignupEnabled && cfg.PublicURL != ""
+}
+
+// Global email settings as a transport
+func (cfg *Config) emailTransport() SMTPTransport {
+	return SMTPTransport{
+		Host:      cfg.MailgunSMTP,
+		Port:      cfg.MailgunPort,
+		User:      cfg.MailgunUser,
+		Pass:      cfg.MailgunPass,
+		FromEmail: cfg.FromEmail,
+		FromName:  cfg.FromName,
+	}
+}
+
+// Profile transport with unset fields inherited from the global email settings
+func (p Profile) transport(cfg *Config) SMTPTransport {
+	t := cfg.emailTransport()
+	if p.Transport.Host != "" {
+		t.Host = p.Transport.Host
+	}
+	if p.Transport.Port != "" {
+		t.Port = p.Transport.Port
+	}
+	if p.Transport.User != "" {
+		t.User = p.Transport.User
+	}
+	if p.Transport.Pass != "" {
+		t.Pass = p.Transport.Pass
+	}
+	if p.Transport.FromEmail != "" {
+		t.FromEmail = p.Transport.FromEmail
+	}
+	if p.Transport.FromName != "" {
+		t.FromName = p.Transport.FromName
+	}
+	return t
+}
+
+// Recipient group: receives only items carrying one of its Sonarr/Radarr tags
+type Group struct {
+	Name            string   `json:"name"`
+	Tags            []string `json:"tags"`
+	IncludeUntagged bool     `json:"include_untagged"`
+	MaxRating       string   `json:"max_rating,omitempty"`
+}
+
+// Recipient of a newsletter run, with the content filters that apply to them
+type Recipient struct {
+	Email           string
+	MaxRating       string
+	AllowUnrated    bool
+	Tags            []string // empty = no tag filtering
+	IncludeUntagged bool
+	FollowedSeries  []int
+	FollowedMovies  []int
+	MutedSeries     []int
+	MutedMovies     []int
+	PreferencesURL  string
+	Timezone        string // empty = server timezone
+}
+
+func (r Recipient) hasFilters() bool {
+	return r.MaxRating != "" || len(r.Tags) > 0 ||
+		len(r.FollowedSeries) > 0 || len(r.FollowedMovies) > 0 ||
+		len(r.MutedSeries) > 0 || len(r.MutedMovies) > 0
 }
 
 // Global config cache (loaded once at startup, reloaded on save)
@@ -163,923 +530,4241 @@ var (
 // Precompiled templates (compiled once at startup)
 var emailTemplate *template.Template
 
-// Ring buffer for logs (no disk writes, 500 lines in memory)
+// Functions available to email templates (built-in and custom)
+var templateFuncs = template.FuncMap{
+	"formatDateWithDay": formatDateWithDay,
+	"releaseLabel":      releaseLabel,
+}
+
+// Recent log entries kept in memory for the UI (the log file keeps the full history)
 var (
-	logBuffer   []string
+	logBuffer   []LogEntry
 	logBufferMu sync.Mutex
 	maxLogLines = 500
 )
 
-// Internal scheduler
-var scheduler *cron.Cron
+// Internal scheduler (schedulerMu guards restarts against status readers)
+var (
+	scheduler   *cron.Cron
+	schedulerMu sync.Mutex
+)
+
+// Process state reported by /readyz and /api/status
+var (
+	startedAt    = time.Now()
+	shuttingDown atomic.Bool
+)
+
+// Subscriber, group and profile stores (data/subscribers.json, data/groups.json, data/profiles.json)
+var (
+	subscribersMu sync.Mutex
+	groupsMu      sync.Mutex
+	profilesMu    sync.Mutex
+	runStateMu    sync.Mutex
+	runsMu        sync.Mutex
+	archiveMu     sync.Mutex
+)
+
+// Sign-up and login rate limiting (per client IP, in memory)
+var (
+	signupLimiter = newRateLimiter(5, time.Hour)
+	loginLimiter  = newRateLimiter(10, 15*time.Minute)
+)
+
+const confirmTokenTTL = 48 * time.Hour
 
 func init() {
-	// Redirect log output to our ring buffer
-	log.SetOutput(&logWriter{})
-	log.SetFlags(log.Ldate | log.Ltime)
+	// Everything is logged through slog, including the standard log package
+	slog.SetDefault(slog.New(&logHandler{}))
+	log.SetFlags(0)
+	log.SetOutput(logBridge{})
 }
 
-// Custom log writer that maintains ring buffer
-type logWriter struct{}
+// Log record as kept in memory, written to the log file (one JSON object per line) and streamed to the UI
+type LogEntry struct {
+	Time    time.Time         `json:"time"`
+	Level   string            `json:"level"` // debug, info, warn, error
+	Message string            `json:"msg"`
+	Attrs   map[string]string `json:"attrs,omitempty"` // e.g. run_id, profile
+}
 
-func (w *logWriter) Write(p []byte) (n int, err error) {
-	logBufferMu.Lock()
-	defer logBufferMu.Unlock()
+// Logging settings, applied once at startup by configureLogging
+var (
+	logLevel  = new(slog.LevelVar) // info until configured
+	logJSON   bool                 // stdout as JSON lines instead of text
+	logOutput *rotatingLog         // nil = no log file
+)
 
-	line := string(p)
-	logBuffer = append(logBuffer, line)
+func configureLogging(cfg *Config) {
+	level, ok := parseLogLevel(cfg.LogLevel)
+	if !ok {
+		log.Printf("⚠️  Unknown LOG_LEVEL %q, using info", cfg.LogLevel)
+	}
+	logLevel.Set(level)
+	logJSON = cfg.LogFormat == "json"
 
-	// Keep only last maxLogLines
-	if len(logBuffer) > maxLogLines {
-		logBuffer = logBuffer[len(logBuffer)-maxLogLines:]
+	if cfg.LogFile {
+		f, err := openRotatingLog(dataPath("logs"), cfg.LogMaxSize, cfg.LogMaxAge)
+		if err != nil {
+			log.Printf("⚠️  Log file disabled: %v", err)
+			return
+		}
+		logOutput = f
 	}
+}
 
-	// Also write to stdout for external logging if needed
-	return os.Stdout.Write(p)
+func parseLogLevel(value string) (slog.Level, bool) {
+	switch strings.ToLower(value) {
+	case "debug":
+		return slog.LevelDebug, true
+	case "", "info":
+		return slog.LevelInfo, true
+	case "warn", "warning":
+		return slog.LevelWarn, true
+	case "error":
+		return slog.LevelError, true
+	}
+	return slog.LevelInfo, false
 }
 
-func main() {
-	webMode := flag.Bool("web", false, "Run in web UI mode")
-	flag.Parse()
+// slog handler feeding the memory buffer, stdout, the log file and live UI clients
+type logHandler struct {
+	attrs  []slog.Attr // keys already carry the group prefix
+	prefix string      // open groups, e.g. "smtp."
+}
 
-	// Load config once at startup
-	cachedConfig = loadConfig()
+func (h *logHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= logLevel.Level()
+}
 
-	// Precompile email template with custom functions
-	var err error
-	emailTemplate, err = template.New("email.html").Funcs(template.FuncMap{
-		"formatDateWithDay": formatDateWithDay,
-	}).ParseFS(templateFS, "templates/email.html")
-	if err != nil {
-		log.Fatalf("❌ Failed to parse email template: %v", err)
+func (h *logHandler) Handle(_ context.Context, r slog.Record) error {
+	entry := LogEntry{Time: r.Time, Level: strings.ToLower(r.Level.String()), Message: r.Message}
+	add := func(key string, value slog.Value) {
+		if entry.Attrs == nil {
+			entry.Attrs = make(map[string]string)
+		}
+		entry.Attrs[key] = value.Resolve().String()
 	}
-
-	if *webMode {
-		startWebServer()
-	} else {
-		runNewsletter()
+	for _, a := range h.attrs {
+		add(a.Key, a.Value)
 	}
+	r.Attrs(func(a slog.Attr) bool {
+		add(h.prefix+a.Key, a.Value)
+		return true
+	})
+
+	recordLog(entry)
+	return nil
 }
 
-// Newsletter sending logic with parallel API calls
-func runNewsletter() {
-	cfg := getConfig()
-	loc := getTimezone(cfg.Timezone)
-	now := time.Now().In(loc)
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := &logHandler{prefix: h.prefix, attrs: append([]slog.Attr{}, h.attrs...)}
+	for _, a := range attrs {
+		next.attrs = append(next.attrs, slog.Attr{Key: h.prefix + a.Key, Value: a.Value})
+	}
+	return next
+}
 
-	log.Println("🚀 Starting Newslettar - Weekly newsletter generation...")
-	log.Printf("⏰ Current time: %s (%s)", now.Format("2006-01-02 15:04:05"), cfg.Timezone)
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &logHandler{attrs: h.attrs, prefix: h.prefix + name + "."}
+}
 
-	weekStart := now.AddDate(0, 0, -7)
-	weekEnd := now
+// Standard log package output, leveled by the message's emoji (❌ error, ⚠️ warning)
+type logBridge struct{}
+
+func (logBridge) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\n")
+	level := slog.LevelInfo
+	switch {
+	case strings.HasPrefix(msg, "❌"):
+		level = slog.LevelError
+	case strings.HasPrefix(msg, "⚠️"):
+		level = slog.LevelWarn
+	}
+	slog.Default().Log(context.Background(), level, msg)
+	return len(p), nil
+}
 
-	log.Printf("📅 Week range: %s to %s", weekStart.Format("2006-01-02"), weekEnd.Format("2006-01-02"))
+func recordLog(entry LogEntry) {
+	logBufferMu.Lock()
+	logBuffer = append(logBuffer, entry)
 
-	// Use a cancellable context for all fetches
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	// Keep only last maxLogLines
+	if len(logBuffer) > maxLogLines {
+		logBuffer = logBuffer[len(logBuffer)-maxLogLines:]
+	}
+	logBufferMu.Unlock()
 
-	// Parallel API calls (3-4x faster!)
-	var wg sync.WaitGroup
-	var downloadedEpisodes, upcomingEpisodes []Episode
-	var downloadedMovies, upcomingMovies []Movie
-	var errSonarrHistory, errSonarrCalendar, errRadarrHistory, errRadarrCalendar error
+	if logJSON {
+		line, _ := json.Marshal(entry)
+		os.Stdout.Write(append(line, '\n'))
+	} else {
+		fmt.Fprintln(os.Stdout, entry.text())
+	}
+	if logOutput != nil {
+		logOutput.write(entry)
+	}
 
-	log.Println("📡 Fetching data in parallel...")
-	startFetch := time.Now()
+	events.publish(Event{Type: "log", Data: entry})
+}
 
-	wg.Add(4)
+// Text form: time, level (unless info), message, then the attributes sorted by key
+func (e LogEntry) text() string {
+	var b strings.Builder
+	b.WriteString(e.Time.Format("2006/01/02 15:04:05 "))
+	if e.Level != "info" {
+		b.WriteString(strings.ToUpper(e.Level) + " ")
+	}
+	b.WriteString(e.Message)
 
-	go func() {
-		defer wg.Done()
-		log.Println("📺 Fetching Sonarr history...")
-		downloadedEpisodes, errSonarrHistory = fetchSonarrHistoryWithRetry(ctx, cfg, weekStart, 3)
-		if errSonarrHistory != nil {
-			log.Printf("⚠️  Sonarr history error: %v", errSonarrHistory)
-		} else {
-			log.Printf("✓ Found %d downloaded episodes", len(downloadedEpisodes))
+	keys := make([]string, 0, len(e.Attrs))
+	for k := range e.Attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := e.Attrs[k]
+		if v == "" || strings.ContainsAny(v, " =\"\n") {
+			v = strconv.Quote(v)
 		}
-	}()
+		b.WriteString(" " + k + "=" + v)
+	}
+	return b.String()
+}
 
-	go func() {
-		defer wg.Done()
-		log.Println("📺 Fetching Sonarr calendar...")
-		upcomingEpisodes, errSonarrCalendar = fetchSonarrCalendarWithRetry(ctx, cfg, weekEnd, weekEnd.AddDate(0, 0, 7), 3)
-		if errSonarrCalendar != nil {
-			log.Printf("⚠️  Sonarr calendar error: %v", errSonarrCalendar)
-		} else {
-			log.Printf("✓ Found %d upcoming episodes", len(upcomingEpisodes))
-		}
-	}()
+// Append-only JSON-lines log file (data/logs/newslettar.log), rotated when it
+// would exceed maxSize; rotated files are deleted once older than maxAge
+type rotatingLog struct {
+	mu      sync.Mutex
+	dir     string
+	file    *os.File
+	size    int64
+	maxSize int64         // bytes, 0 = never rotate
+	maxAge  time.Duration // 0 = keep rotated files forever
+}
 
-	go func() {
-		defer wg.Done()
-		log.Println("🎬 Fetching Radarr history...")
-		downloadedMovies, errRadarrHistory = fetchRadarrHistoryWithRetry(ctx, cfg, weekStart, 3)
-		if errRadarrHistory != nil {
-			log.Printf("⚠️  Radarr history error: %v", errRadarrHistory)
-		} else {
-			log.Printf("✓ Found %d downloaded movies", len(downloadedMovies))
-		}
-	}()
+const logFileName = "newslettar.log"
 
-	go func() {
-		defer wg.Done()
-		log.Println("🎬 Fetching Radarr calendar...")
-		upcomingMovies, errRadarrCalendar = fetchRadarrCalendarWithRetry(ctx, cfg, weekEnd, weekEnd.AddDate(0, 0, 7), 3)
-		if errRadarrCalendar != nil {
-			log.Printf("⚠️  Radarr calendar error: %v", errRadarrCalendar)
-		} else {
-			log.Printf("✓ Found %d upcoming movies", len(upcomingMovies))
-		}
-	}()
+// Logs hold subscriber addresses and run details, so they're private like the data files
+func openRotatingLog(dir string, maxSize int64, maxAge time.Duration) (*rotatingLog, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(dir, 0700); err != nil {
+		return nil, err
+	}
+	l := &rotatingLog{dir: dir, maxSize: maxSize, maxAge: maxAge}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	l.prune()
+	return l, nil
+}
 
-	wg.Wait()
-	fetchDuration := time.Since(startFetch)
-	log.Printf("⚡ All data fetched in %v (parallel)", fetchDuration)
+func (l *rotatingLog) open() error {
+	f, err := os.OpenFile(filepath.Join(l.dir, logFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	// Tighten files left behind by older versions
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.file, l.size = f, info.Size()
+	return nil
+}
 
-	// Check if we have any content to send
-	hasContent := len(upcomingEpisodes) > 0 || len(upcomingMovies) > 0 ||
-		(cfg.ShowDownloaded && (len(downloadedEpisodes) > 0 || len(downloadedMovies) > 0))
+func (l *rotatingLog) write(entry LogEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
 
-	if !hasContent {
-		log.Println("ℹ️  No new content to report. Skipping email.")
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.maxSize > 0 && l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		l.rotate()
+	}
+	if l.file == nil {
 		return
 	}
+	n, _ := l.file.Write(line)
+	l.size += int64(n)
+}
 
-	// Sort movies chronologically
-	sort.Slice(upcomingMovies, func(i, j int) bool {
-		return upcomingMovies[i].ReleaseDate < upcomingMovies[j].ReleaseDate
-	})
-	sort.Slice(downloadedMovies, func(i, j int) bool {
-		return downloadedMovies[i].ReleaseDate < downloadedMovies[j].ReleaseDate
-	})
+// Errors go to stderr: the logger can't log about itself
+func (l *rotatingLog) rotate() {
+	l.file.Close()
+	l.file = nil
 
-	data := NewsletterData{
-		WeekStart:              weekStart.Format("January 2, 2006"),
-		WeekEnd:                weekEnd.Format("January 2, 2006"),
-		UpcomingSeriesGroups:   groupEpisodesBySeries(upcomingEpisodes),
-		UpcomingMovies:         upcomingMovies,
-		DownloadedSeriesGroups: groupEpisodesBySeries(downloadedEpisodes),
-		DownloadedMovies:       downloadedMovies,
+	rotated := "newslettar-" + time.Now().UTC().Format("20060102T150405.000") + ".log"
+	if err := os.Rename(filepath.Join(l.dir, logFileName), filepath.Join(l.dir, rotated)); err != nil {
+		fmt.Fprintf(os.Stderr, "log rotation failed: %v\n", err)
 	}
-
-	log.Println("📝 Generating newsletter HTML...")
-	html, err := generateNewsletterHTML(data, cfg.ShowPosters, cfg.ShowDownloaded)
-	if err != nil {
-		log.Fatalf("❌ Failed to generate HTML: %v", err)
+	if err := l.open(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to reopen log file: %v\n", err)
 	}
+	l.prune()
+}
 
-	subject := fmt.Sprintf("📺 Your Weekly Newsletter - %s", weekEnd.Format("January 2, 2006"))
-
-	log.Println("📧 Sending emails...")
-	if err := sendEmail(cfg, subject, html); err != nil {
-		log.Fatalf("❌ Failed to send email: %v", err)
+func (l *rotatingLog) prune() {
+	if l.maxAge <= 0 {
+		return
 	}
+	rotated, _ := filepath.Glob(filepath.Join(l.dir, "newslettar-*.log"))
+	for _, path := range rotated {
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > l.maxAge {
+			os.Remove(path)
+		}
+	}
+}
 
-	log.Println("✅ Newsletter sent successfully!")
+// Log files oldest first: rotated files (named by rotation time), then the current one
+func (l *rotatingLog) files() []string {
+	files, _ := filepath.Glob(filepath.Join(l.dir, "newslettar-*.log"))
+	sort.Strings(files)
+	return append(files, filepath.Join(l.dir, logFileName))
+}
 
-	// Clear data to free memory immediately
-	downloadedEpisodes = nil
-	upcomingEpisodes = nil
-	downloadedMovies = nil
-	upcomingMovies = nil
-	data = NewsletterData{}
+// Filters of the /api/logs query
+type logQuery struct {
+	minLevel     slog.Level
+	since, until time.Time // zero = unbounded
+	runID        string
+	text         string // lower-case substring of the message or an attribute
 }
 
-// Retry wrappers for API calls
-func fetchSonarrHistoryWithRetry(ctx context.Context, cfg *Config, since time.Time, maxRetries int) ([]Episode, error) {
-	var episodes []Episode
-	var err error
-	for i := 0; i < maxRetries; i++ {
-		episodes, err = fetchSonarrHistory(ctx, cfg, since)
-		if err == nil {
-			return episodes, nil
-		}
-		if i < maxRetries-1 {
-			wait := time.Duration(i+1) * time.Second
-			log.Printf("⏳ Retrying Sonarr history in %v... (attempt %d/%d)", wait, i+2, maxRetries)
-			time.Sleep(wait)
+func (q logQuery) matches(e LogEntry) bool {
+	if level, _ := parseLogLevel(e.Level); level < q.minLevel {
+		return false
+	}
+	if (!q.since.IsZero() && e.Time.Before(q.since)) || (!q.until.IsZero() && e.Time.After(q.until)) {
+		return false
+	}
+	if q.runID != "" && e.Attrs["run_id"] != q.runID {
+		return false
+	}
+	if q.text == "" || strings.Contains(strings.ToLower(e.Message), q.text) {
+		return true
+	}
+	for _, v := range e.Attrs {
+		if strings.Contains(strings.ToLower(v), q.text) {
+			return true
 		}
 	}
-	return episodes, err
+	return false
 }
 
-func fetchSonarrCalendarWithRetry(ctx context.Context, cfg *Config, start, end time.Time, maxRetries int) ([]Episode, error) {
-	var episodes []Episode
-	var err error
-	for i := 0; i < maxRetries; i++ {
-		episodes, err = fetchSonarrCalendar(ctx, cfg, start, end)
-		if err == nil {
-			return episodes, nil
+// Newest matching entries, oldest first, at most limit (truncated reports whether more matched).
+// Reads the log files when enabled, otherwise the in-memory buffer.
+func queryLogs(q logQuery, limit int) (matched []LogEntry, truncated bool) {
+	keep := func(e LogEntry) {
+		if !q.matches(e) {
+			return
 		}
-		if i < maxRetries-1 {
-			wait := time.Duration(i+1) * time.Second
-			log.Printf("⏳ Retrying Sonarr calendar in %v... (attempt %d/%d)", wait, i+2, maxRetries)
-			time.Sleep(wait)
+		matched = append(matched, e)
+		if len(matched) >= 2*limit {
+			matched = append([]LogEntry{}, matched[len(matched)-limit:]...)
+			truncated = true
 		}
 	}
-	return episodes, err
-}
 
-func fetchRadarrHistoryWithRetry(ctx context.Context, cfg *Config, since time.Time, maxRetries int) ([]Movie, error) {
-	var movies []Movie
-	var err error
-	for i := 0; i < maxRetries; i++ {
-		movies, err = fetchRadarrHistory(ctx, cfg, since)
-		if err == nil {
-			return movies, nil
+	if logOutput == nil {
+		logBufferMu.Lock()
+		for _, e := range logBuffer {
+			keep(e)
 		}
-		if i < maxRetries-1 {
-			wait := time.Duration(i+1) * time.Second
-			log.Printf("⏳ Retrying Radarr history in %v... (attempt %d/%d)", wait, i+2, maxRetries)
-			time.Sleep(wait)
+		logBufferMu.Unlock()
+	} else {
+		for _, path := range logOutput.files() {
+			// A file last written before the range can't contain matches
+			if info, err := os.Stat(path); err != nil || (!q.since.IsZero() && info.ModTime().Before(q.since)) {
+				continue
+			}
+			scanLogFile(path, keep)
 		}
 	}
-	return movies, err
+
+	if len(matched) > limit {
+		matched = matched[len(matched)-limit:]
+		truncated = true
+	}
+	return matched, truncated
 }
 
-func fetchRadarrCalendarWithRetry(ctx context.Context, cfg *Config, start, end time.Time, maxRetries int) ([]Movie, error) {
-	var movies []Movie
-	var err error
-	for i := 0; i < maxRetries; i++ {
-		movies, err = fetchRadarrCalendar(ctx, cfg, start, end)
-		if err == nil {
-			return movies, nil
-		}
-		if i < maxRetries-1 {
-			wait := time.Duration(i+1) * time.Second
-			log.Printf("⏳ Retrying Radarr calendar in %v... (attempt %d/%d)", wait, i+2, maxRetries)
-			time.Sleep(wait)
-		}
-	}
-	return movies, err
-}
-
-// Get timezone location
-func getTimezone(tz string) *time.Location {
-	if tz == "" {
-		tz = "UTC"
-	}
-	loc, err := time.LoadLocation(tz)
+func scanLogFile(path string, fn func(LogEntry)) {
+	f, err := os.Open(path)
 	if err != nil {
-		log.Printf("⚠️  Invalid timezone '%s', using UTC", tz)
-		return time.UTC
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e LogEntry
+		if json.Unmarshal(scanner.Bytes(), &e) == nil {
+			fn(e)
+		}
 	}
-	return loc
 }
 
-// Get config (cached, thread-safe)
-func getConfig() *Config {
-	configMu.RLock()
-	defer configMu.RUnlock()
-	return cachedConfig
-}
+func main() {
+	webMode := flag.Bool("web", false, "Run in web UI mode")
+	profileID := flag.String("profile", "", "Newsletter profile to send (default: first profile)")
+	setPassword := flag.Bool("set-password", false, "Set the web UI admin password (read from stdin) and exit")
+	username := flag.String("username", "", "Admin username for -set-password (default: the current one, or admin)")
+	secretsCommand := flag.String("secrets", "", "Encrypted secrets store: migrate (move secrets out of .env) or rotate (re-encrypt with a new key; stop the service first), then exit")
+	flag.Var(flagSettings, "setting", "Override a setting, KEY=VALUE (repeatable; takes precedence over the environment and .env)")
+	flag.Parse()
 
-// Reload config (called when user saves configuration)
-func reloadConfig() {
-	configMu.Lock()
-	defer configMu.Unlock()
+	// Load config once at startup
 	cachedConfig = loadConfig()
-	log.Println("🔄 Configuration reloaded from .env")
-}
-
-// Load configuration from .env file (only called at startup and on reload)
-func loadConfig() *Config {
-	envMap := readEnvFile()
 
-	toEmailsStr := getEnvFromFile(envMap, "TO_EMAILS", "")
-	toEmails := []string{}
-	if toEmailsStr != "" {
-		toEmails = strings.Split(toEmailsStr, ",")
-		for i := range toEmails {
-			toEmails[i] = strings.TrimSpace(toEmails[i])
+	// Needs the config for the data directory (profile passwords are migrated too)
+	if *secretsCommand != "" {
+		if err := runSecretsCommand(*secretsCommand); err != nil {
+			log.Fatalf("❌ %v", err)
 		}
+		return
 	}
+	configureLogging(cachedConfig)
+	warnPlaintextSecrets()
 
-	return &Config{
-		SonarrURL:      getEnvFromFile(envMap, "SONARR_URL", ""),
-		SonarrAPIKey:   getEnvFromFile(envMap, "SONARR_API_KEY", ""),
-		RadarrURL:      getEnvFromFile(envMap, "RADARR_URL", ""),
-		RadarrAPIKey:   getEnvFromFile(envMap, "RADARR_API_KEY", ""),
-		MailgunSMTP:    getEnvFromFile(envMap, "MAILGUN_SMTP", "smtp.mailgun.org"),
-		MailgunPort:    getEnvFromFile(envMap, "MAILGUN_PORT", "587"),
-		MailgunUser:    getEnvFromFile(envMap, "MAILGUN_USER", ""),
-		MailgunPass:    getEnvFromFile(envMap, "MAILGUN_PASS", ""),
-		FromEmail:      getEnvFromFile(envMap, "FROM_EMAIL", ""),
-		FromName:       getEnvFromFile(envMap, "FROM_NAME", "Newslettar"),
-		ToEmails:       toEmails,
-		Timezone:       getEnvFromFile(envMap, "TIMEZONE", "UTC"),
-		ScheduleDay:    getEnvFromFile(envMap, "SCHEDULE_DAY", "Sun"),
-		ScheduleTime:   getEnvFromFile(envMap, "SCHEDULE_TIME", "09:00"),
-		ShowPosters:    getEnvFromFile(envMap, "SHOW_POSTERS", "true") != "false",
-		ShowDownloaded: getEnvFromFile(envMap, "SHOW_DOWNLOADED", "true") != "false",
+	// Precompile email template with custom functions
+	var err error
+	emailTemplate, err = template.New("email.html").Funcs(templateFuncs).ParseFS(templateFS, "templates/email.html")
+	if err != nil {
+		log.Fatalf("❌ Failed to parse email template: %v", err)
 	}
-}
 
-func readEnvFile() map[string]string {
-	envMap := make(map[string]string)
+	// Create the default profile from the legacy schedule settings on first start
+	migrateProfiles(cachedConfig)
 
-	data, err := os.ReadFile(".env")
-	if err != nil {
-		return envMap
+	if *setPassword {
+		if err := setPasswordFromStdin(*username); err != nil {
+			log.Fatalf("❌ Failed to set password: %v", err)
+		}
+		return
 	}
 
-	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
+	if *webMode {
+		startWebServer()
+	} else {
+		p, ok := findProfile(*profileID)
+		if !ok {
+			log.Fatalf("❌ Unknown newsletter profile: %s", *profileID)
 		}
-
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) == 2 {
-			key := strings.TrimSpace(parts[0])
-			value := strings.TrimSpace(parts[1])
-			envMap[key] = value
+		job, err := startJob(p, TriggerCLI)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		<-job.done
+		if status := job.snapshot(); status.Status == RunFailed {
+			log.Fatalf("❌ %s failed (%s): %s", p.Name, status.ErrorKind, status.Message)
 		}
 	}
+}
 
-	return envMap
+// What a failed run went wrong on
+const (
+	ErrorConfig   = "config"   // settings missing or invalid (recipients, email server)
+	ErrorUpstream = "upstream" // Sonarr/Radarr unreachable
+	ErrorRender   = "render"   // the template failed
+	ErrorDelivery = "delivery" // the mail server refused every message
+	ErrorInternal = "internal" // a bug (the run panicked)
+)
+
+// Failed run, classified so the run history, the API and the admin alert can say what went wrong
+type RunError struct {
+	Kind string
+	Err  error
 }
 
-func getEnvFromFile(envMap map[string]string, key, defaultValue string) string {
-	if val, exists := envMap[key]; exists {
-		return val
-	}
-	if val := os.Getenv(key); val != "" {
-		return val
-	}
-	return defaultValue
+func (e *RunError) Error() string { return e.Err.Error() }
+func (e *RunError) Unwrap() error { return e.Err }
+
+func runError(kind, format string, args ...interface{}) *RunError {
+	return &RunError{Kind: kind, Err: fmt.Errorf(format, args...)}
 }
 
-// Updated fetch functions to accept context and use RequestWithContext
-func fetchSonarrHistory(ctx context.Context, cfg *Config, since time.Time) ([]Episode, error) {
-	if cfg.SonarrURL == "" || cfg.SonarrAPIKey == "" {
-		return nil, fmt.Errorf("Sonarr not configured")
+// Generate and send a profile's newsletter covering everything since its last successful send.
+// Results are recorded in run, progress is reported on job; cancelling ctx stops the run
+// before the next recipient (the window isn't advanced, and the next run skips whoever
+// was already sent it).
+// A failed run returns a *RunError; the run is always finished before returning.
+func runNewsletter(ctx context.Context, p Profile, run *Run, job *Job) error {
+	cfg := getConfig()
+	loc := getTimezone(cfg.Timezone)
+	now := run.StartedAt.In(loc)
+
+	logger := job.logger()
+	fail := func(err *RunError) error {
+		logger.Error("❌ Newsletter failed", "kind", err.Kind, "error", err)
+		run.ErrorKind = err.Kind
+		run.finish(RunFailed, err.Error())
+		return err
 	}
 
-	url := fmt.Sprintf("%s/api/v3/history?pageSize=1000&sortKey=date&sortDirection=descending&includeEpisode=true&includeSeries=true", cfg.SonarrURL)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
+	logger.Info("🚀 Starting newsletter generation", "newsletter", p.Name, "trigger", run.Trigger)
+	logger.Debug("⏰ Current time", "time", now.Format("2006-01-02 15:04:05"), "timezone", cfg.Timezone)
+
+	weekStart, weekEnd := newsletterWindow(p, now)
+
+	logger.Info("📅 Newsletter window", "from", weekStart.Format("2006-01-02 15:04"), "to", weekEnd.Format("2006-01-02 15:04"), "lookahead", p.lookahead().String())
+
+	// Settings problems fail before anything is fetched
+	recipients := getRecipients(cfg, p)
+	if len(recipients) == 0 {
+		return fail(runError(ErrorConfig, "no recipients configured"))
+	}
+	transport := p.transport(cfg)
+	if transport.Host == "" || transport.Port == "" || transport.FromEmail == "" {
+		return fail(runError(ErrorConfig, "email settings incomplete: SMTP server, port and from address are required"))
 	}
-	req.Header.Set("X-Api-Key", cfg.SonarrAPIKey)
 
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return nil, err
+	// Fetches share the job's context, bounded by a timeout
+	job.step("Fetching from Sonarr/Radarr")
+	fetchCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	historySince := historyWindow(p, weekStart, now)
+	for section, since := range historySince {
+		if since.Before(weekStart) {
+			logger.Info("↩️  Picking up what a failed fetch missed", "section", section, "from", since.Format("2006-01-02 15:04"))
+		}
+	}
+	data, sources := fetchNewsletterData(fetchCtx, cfg, p, weekStart, weekEnd, historySince, 3)
+	run.WindowStart, run.WindowEnd = weekStart, weekEnd
+	run.Sources = sources
+	run.Counts = newsletterCounts(data)
+
+	fetchFailed := false
+	for _, s := range sources {
+		fetchFailed = fetchFailed || s.Error != ""
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+	if ctx.Err() != nil {
+		logger.Info("⏹️  Cancelled before sending")
+		run.finish(RunCancelled, "cancelled before sending")
+		return ctx.Err()
 	}
 
-	// Stream JSON decoding (faster, less memory)
-	var result struct {
-		Records []struct {
-			Date      time.Time `json:"date"`
-			EventType string    `json:"eventType"`
-			Series    struct {
-				Title  string `json:"title"`
-				TvdbID int    `json:"tvdbId"`
-				ImdbID string `json:"imdbId"`
-				Images []struct {
-					CoverType string `json:"coverType"`
-					RemoteURL string `json:"remoteUrl"`
-				} `json:"images"`
-			} `json:"series"`
-			Episode struct {
-				SeasonNumber  int    `json:"seasonNumber"`
-				EpisodeNumber int    `json:"episodeNumber"`
-				Title         string `json:"title"`
-				AirDate       string `json:"airDate"`
-			} `json:"episode"`
-		} `json:"records"`
+	// Check if we have any content to send
+	if newsletterIsEmpty(data) {
+		if fetchFailed {
+			// Don't advance the window: the content may just be unreachable right now
+			logger.Warn("⚠️  No content and some sources failed, keeping the window for the next run")
+			var failures []string
+			for _, s := range sources {
+				if s.Error != "" {
+					failures = append(failures, s.Name+": "+s.Error)
+				}
+			}
+			return fail(runError(ErrorUpstream, "no content fetched, %s", strings.Join(failures, "; ")))
+		}
+		logger.Info("ℹ️  No new content to report, skipping email")
+		recordSuccessfulRun(p.ID, historySince, weekEnd, sources)
+		run.finish(RunSkipped, "no new content")
+		return nil
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
+	// With private archive links every recipient gets their own signed URLs,
+	// otherwise the shared rendering links to the public issue page
+	signedLinks := cfg.ArchiveAccess == ArchiveSigned
+	if !signedLinks {
+		data.ViewInBrowserURL = issueURL(cfg, run.ID, "")
+		data.ArchiveURL = archiveIndexURL(cfg, "")
 	}
 
-	episodes := []Episode{}
-	for _, record := range result.Records {
-		// Only include download events
-		if record.EventType != "downloadFolderImported" && record.EventType != "downloadImported" {
-			continue
+	logger.Debug("📝 Generating newsletter HTML")
+	job.step("Rendering newsletter")
+	html, err := generateNewsletterHTML(p, data)
+	if err != nil {
+		return fail(runError(ErrorRender, "failed to generate HTML: %v", err))
+	}
+	run.saveHTML(-1, html)
+
+	subject := fmt.Sprintf("📺 %s - %s", p.Name, weekEnd.Format("January 2, 2006"))
+	run.Subject = subject
+
+	logger.Info("📧 Sending emails", "recipients", len(recipients))
+
+	// One message per recipient so subscribers never see each other's addresses
+	// and content filters (max rating, groups, follows/mutes) can be applied individually
+	job.step("Sending emails")
+	sent, failed := 0, 0
+	var lastErr error
+	cancelled := false
+	var readers []string // archive keys of everyone who received the issue
+	// Whoever an earlier, unfinished run since the last successful send reached isn't sent
+	// it twice. They miss what arrived since, which a rerun soon after the cancellation keeps small.
+	alreadySent := deliveredEarlier(p.ID)
+	keepDelivered := func() { recordPartialDelivery(p.ID, readers) }
+	var calendar []Attachment
+	if p.AttachCalendar {
+		calendar = calendarAttachment(p, data)
+	}
+	deliver := func(delivery Delivery) {
+		run.Deliveries = append(run.Deliveries, delivery)
+		job.delivered(delivery, len(run.Deliveries), len(recipients))
+	}
+	job.progress(0, len(recipients))
+	for _, rcpt := range recipients {
+		if ctx.Err() != nil {
+			cancelled = true
+			break
 		}
 
-		// Filter by date
-		if record.Date.Before(since) {
+		delivery := Delivery{Email: rcpt.Email}
+		if alreadySent[recipientKey(rcpt.Email)] {
+			delivery.Status = DeliverySkipped
+			delivery.Error = "already received this period before the last run was interrupted"
+			deliver(delivery)
 			continue
 		}
+		rcptHTML := html
+		rcptCalendar := calendar
+		if rcpt.hasFilters() || rcpt.PreferencesURL != "" || rcpt.Timezone != "" || signedLinks {
+			rcptData := filterNewsletterForRecipient(data, rcpt)
+			if rcpt.Timezone != "" {
+				rcptData = localizeNewsletter(rcptData, getTimezone(rcpt.Timezone))
+			}
+			rcptData.PreferencesURL = rcpt.PreferencesURL
+			if signedLinks {
+				rcptData.ViewInBrowserURL = issueURL(cfg, run.ID, rcpt.Email)
+				rcptData.ArchiveURL = archiveIndexURL(cfg, rcpt.Email)
+			}
+			if newsletterIsEmpty(rcptData) {
+				logger.Info("ℹ️  Nothing matches the recipient's filters, skipping", "email", rcpt.Email)
+				delivery.Status = DeliverySkipped
+				deliver(delivery)
+				continue
+			}
+			rcptHTML, err = generateNewsletterHTML(p, rcptData)
+			if err != nil {
+				keepDelivered()
+				return fail(runError(ErrorRender, "failed to generate HTML for %s: %v", rcpt.Email, err))
+			}
+			delivery.CustomHTML = run.saveHTML(len(run.Deliveries), rcptHTML)
+			if p.AttachCalendar && rcpt.hasFilters() {
+				rcptCalendar = calendarAttachment(p, rcptData)
+			}
+		}
 
-		posterURL := ""
-		for _, img := range record.Series.Images {
-			if img.CoverType == "poster" {
-				posterURL = img.RemoteURL
-				break
+		if err := sendEmail(transport, []string{rcpt.Email}, subject, rcptHTML, rcptCalendar...); err != nil {
+			logger.Warn("⚠️  Failed to send", "email", rcpt.Email, "error", err)
+			lastErr = err
+			delivery.Status = DeliveryFailed
+			delivery.Error = err.Error()
+			deliver(delivery)
+			failed++
+			continue
+		}
+		delivery.Status = DeliverySent
+		deliver(delivery)
+		sent++
+
+		readers = append(readers, recipientKey(rcpt.Email))
+		if signedLinks && rcpt.hasFilters() {
+			// Their copy differs from the shared one, so their archive link should show it
+			if err := saveArchiveCopy(run.ID, rcpt.Email, rcptHTML); err != nil {
+				logger.Warn("⚠️  Failed to archive copy", "email", rcpt.Email, "error", err)
 			}
 		}
+	}
+	if cancelled {
+		// Not recorded as a success: the next run covers the same window again,
+		// except for the recipients already reached
+		keepDelivered()
+		logger.Info("⏹️  Cancelled", "processed", len(run.Deliveries), "recipients", len(recipients))
+		run.finish(RunCancelled, fmt.Sprintf("cancelled after %d of %d recipients", len(run.Deliveries), len(recipients)))
+		return ctx.Err()
+	}
+	if sent == 0 && failed > 0 {
+		return fail(runError(ErrorDelivery, "failed to send email to any recipient: %v", lastErr))
+	}
 
-		episodes = append(episodes, Episode{
-			SeriesTitle: record.Series.Title,
-			SeasonNum:   record.Episode.SeasonNumber,
-			EpisodeNum:  record.Episode.EpisodeNumber,
-			Title:       record.Episode.Title,
-			AirDate:     record.Episode.AirDate,
-			Downloaded:  true,
-			PosterURL:   posterURL,
-			IMDBID:      record.Series.ImdbID,
-			TvdbID:      record.Series.TvdbID,
-		})
+	logger.Info("✅ Newsletter sent", "newsletter", p.Name, "sent", sent, "failed", failed)
+	recordSuccessfulRun(p.ID, historySince, weekEnd, sources)
+
+	if cfg.ArchiveAccess != ArchiveOff {
+		job.step("Archiving")
+		archiveNewsletter(p, run, data, readers)
+	}
+	switch {
+	case failed > 0:
+		run.finish(RunPartial, fmt.Sprintf("%d of %d deliveries failed", failed, sent+failed))
+	case fetchFailed:
+		run.finish(RunPartial, "some sources failed to fetch; the next run picks up what they missed")
+	default:
+		run.finish(RunSuccess, "")
 	}
 
-	return episodes, nil
+	// Clear data to free memory immediately
+	data = NewsletterData{}
+	return nil
 }
 
-func fetchSonarrCalendar(ctx context.Context, cfg *Config, start, end time.Time) ([]Episode, error) {
-	url := fmt.Sprintf("%s/api/v3/calendar?unmonitored=true&includeSeries=true&includeEpisodeImages=true&start=%s&end=%s",
-		cfg.SonarrURL, start.Format("2006-01-02"), end.Format("2006-01-02"))
+// Newsletter runs execute as jobs: at most one per profile at a time, observable and
+// cancellable through /api/jobs while in progress. A job's ID is the ID of its run.
+type Job struct {
+	mu     sync.Mutex
+	status JobStatus
+	cancel context.CancelFunc
+	done   chan struct{} // closed when the run has finished
+}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("X-Api-Key", cfg.SonarrAPIKey)
+type JobStatus struct {
+	ID              string     `json:"id"`
+	ProfileID       string     `json:"profile_id"`
+	ProfileName     string     `json:"profile_name"`
+	Trigger         string     `json:"trigger"`
+	Status          string     `json:"status"` // running, then the run's outcome
+	Message         string     `json:"message,omitempty"`
+	ErrorKind       string     `json:"error_kind,omitempty"`
+	Steps           []JobStep  `json:"steps"`      // the last one is current while running
+	Processed       int        `json:"processed"`  // recipients handled so far
+	Recipients      int        `json:"recipients"` // 0 until sending starts
+	CancelRequested bool       `json:"cancel_requested,omitempty"`
+	StartedAt       time.Time  `json:"started_at"`
+	FinishedAt      *time.Time `json:"finished_at,omitempty"`
+}
 
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+type JobStep struct {
+	Name       string     `json:"name"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
-	}
+// Finished jobs kept in memory; older ones are answered from the run history
+const maxFinishedJobs = 50
 
-	// Stream-decode JSON to save memory
-	var calendar []CalendarEpisode
-	decoder := json.NewDecoder(resp.Body)
-	if err := decoder.Decode(&calendar); err != nil {
-		return nil, err
+var (
+	jobsMu     sync.Mutex
+	jobs       []*Job                  // newest first
+	activeJobs = make(map[string]*Job) // running job per profile ID
+)
+
+var errJobRunning = errors.New("this newsletter is already being sent")
+
+// Start a run of p in the background. When p already has a run in progress,
+// that job is returned together with errJobRunning.
+func startJob(p Profile, trigger string) (*Job, error) {
+	jobsMu.Lock()
+	if job, ok := activeJobs[p.ID]; ok {
+		jobsMu.Unlock()
+		return job, errJobRunning
 	}
 
-	// Map to Episode struct
-	var episodes []Episode
-	for _, entry := range calendar {
-		posterURL := ""
-		for _, img := range entry.Series.Images {
-			if img.CoverType == "poster" {
-				if img.Url != "" {
-					posterURL = img.Url
-				} else if img.RemoteUrl != "" {
-					posterURL = img.RemoteUrl
-				}
-				break
-			}
-		}
+	run := newRun(p, trigger, time.Now().In(getTimezone(getConfig().Timezone)))
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &Job{
+		status: JobStatus{
+			ID:          run.ID,
+			ProfileID:   p.ID,
+			ProfileName: p.Name,
+			Trigger:     trigger,
+			Status:      RunRunning,
+			Steps:       []JobStep{},
+			StartedAt:   run.StartedAt,
+		},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	activeJobs[p.ID] = job
+	jobs = append([]*Job{job}, jobs...)
+	pruneJobsLocked()
+	jobsMu.Unlock()
+	ctx = context.WithValue(ctx, jobContextKey{}, job)
 
-		ep := Episode{
-			SeriesTitle: entry.Series.Title,
-			SeasonNum:   entry.SeasonNumber,
-			EpisodeNum:  entry.EpisodeNumber,
-			Title:       entry.Title,
-			AirDate:     entry.AirDate,
-			PosterURL:   posterURL,
-			IMDBID:      entry.Series.ImdbId,
-			TvdbID:      entry.Series.TvdbId,
-		}
+	go func() {
+		defer close(job.done)
+		defer cancel()
 
-		if ep.AirDate != "" {
-			airDate, _ := time.Parse("2006-01-02", ep.AirDate)
-			ep.AirDate = airDate.Format("2006-01-02")
-		}
+		err := runJob(ctx, p, run, job)
+		job.finish(run.Status, run.Message, run.ErrorKind)
+		observeRun(run)
 
-		episodes = append(episodes, ep)
-	}
+		jobsMu.Lock()
+		delete(activeJobs, p.ID)
+		jobsMu.Unlock()
 
-	return episodes, nil
+		var runErr *RunError
+		if errors.As(err, &runErr) {
+			notifyRunFailure(getConfig(), run, runErr)
+		}
+	}()
+	return job, nil
 }
 
-func fetchRadarrHistory(ctx context.Context, cfg *Config, since time.Time) ([]Movie, error) {
-	if cfg.RadarrURL == "" || cfg.RadarrAPIKey == "" {
-		return nil, fmt.Errorf("Radarr not configured")
+// Run the newsletter, turning a panic into a failed run so the daemon keeps serving
+func runJob(ctx context.Context, p Profile, run *Run, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			job.logger().Error("❌ Newsletter run crashed", "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+			runErr := runError(ErrorInternal, "unexpected error: %v", r)
+			run.ErrorKind = runErr.Kind
+			run.finish(RunFailed, runErr.Error())
+			err = runErr
+		}
+	}()
+	return runNewsletter(ctx, p, run, job)
+}
+
+// What to check for each kind of failure, shown in the admin alert
+var runErrorHints = map[string]string{
+	ErrorConfig:   "Check the newsletter's recipients and the email settings.",
+	ErrorUpstream: "Sonarr or Radarr could not be reached. The next run covers the same period again.",
+	ErrorRender:   "The email template could not be rendered. Check custom templates for errors.",
+	ErrorDelivery: "The mail server refused every message. Check the SMTP credentials and sender address.",
+	ErrorInternal: "Newslettar hit an unexpected error. The log has the details.",
+}
+
+// Email ADMIN_EMAIL about a failed run using the global email settings
+func notifyRunFailure(cfg *Config, run *Run, runErr *RunError) {
+	if cfg.AdminEmail == "" {
+		return
 	}
+	logger := slog.With("run_id", run.ID, "profile", run.ProfileID)
 
-	url := fmt.Sprintf("%s/api/v3/history?pageSize=1000&sortKey=date&sortDirection=descending&includeMovie=true", cfg.RadarrURL)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
+	historyURL := ""
+	if cfg.PublicURL != "" {
+		historyURL = cfg.PublicURL + "/#history"
 	}
-	req.Header.Set("X-Api-Key", cfg.RadarrAPIKey)
 
-	resp, err := httpClient.Do(req)
+	var body bytes.Buffer
+	err := template.Must(template.New("failure").Parse(`<!DOCTYPE html>
+<html><body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Arial, sans-serif; background-color: #0f1419; color: #e8e8e8; padding: 20px;">
+    <div style="max-width: 600px; margin: 0 auto; background-color: #1a2332; padding: 30px; border-radius: 12px;">
+        <h2 style="color: #e74c3c;">⚠️ {{.Run.ProfileName}} was not sent</h2>
+        <p><strong>Error ({{.Run.ErrorKind}}):</strong> {{.Error}}</p>
+        <p>{{.Hint}}</p>
+        <p style="color: #8899aa; font-size: 0.9em;">Run {{.Run.ID}} • {{.Run.Trigger}} • started {{.Run.StartedAt.Format "Mon, January 2, 2006 3:04 PM"}}</p>
+        {{if .HistoryURL}}<p><a href="{{.HistoryURL}}" style="color: #667eea;">Open the run history</a></p>{{end}}
+    </div>
+</body></html>`)).Execute(&body, map[string]interface{}{
+		"Run":        run,
+		"Error":      runErr.Error(),
+		"Hint":       runErrorHints[runErr.Kind],
+		"HistoryURL": historyURL,
+	})
 	if err != nil {
-		return nil, err
+		logger.Warn("⚠️  Failed to render failure alert", "error", err)
+		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+	subject := fmt.Sprintf("⚠️ Newslettar: %s failed (%s)", run.ProfileName, runErr.Kind)
+	if err := sendEmail(cfg.emailTransport(), []string{cfg.AdminEmail}, subject, body.String()); err != nil {
+		logger.Warn("⚠️  Failed to send failure alert", "email", cfg.AdminEmail, "error", err)
+		return
 	}
+	logger.Info("📨 Failure alert sent", "email", cfg.AdminEmail)
+}
 
-	var result struct {
-		Records []struct {
-			Date      time.Time `json:"date"`
-			EventType string    `json:"eventType"`
-			Movie     struct {
-				Title     string `json:"title"`
-				Year      int    `json:"year"`
-				TmdbID    int    `json:"tmdbId"`
-				ImdbID    string `json:"imdbId"`
-				InCinemas string `json:"inCinemas"`
-				Images    []struct {
-					CoverType string `json:"coverType"`
-					RemoteURL string `json:"remoteUrl"`
-				} `json:"images"`
-			} `json:"movie"`
-		} `json:"records"`
+func pruneJobsLocked() {
+	kept := jobs[:0]
+	finished := 0
+	for _, job := range jobs {
+		if job.snapshot().FinishedAt != nil {
+			finished++
+			if finished > maxFinishedJobs {
+				continue
+			}
+		}
+		kept = append(kept, job)
 	}
+	jobs = kept
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
+type jobContextKey struct{}
 
-	movies := []Movie{}
-	for _, record := range result.Records {
-		// Only include download events
-		if record.EventType != "downloadFolderImported" && record.EventType != "downloadImported" {
-			continue
-		}
+// Job whose run is using ctx (nil outside runs, e.g. previews and feeds)
+func jobFromContext(ctx context.Context) *Job {
+	job, _ := ctx.Value(jobContextKey{}).(*Job)
+	return job
+}
 
-		// Filter by date
-		if record.Date.Before(since) {
-			continue
+func findJob(id string) (*Job, bool) {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	for _, job := range jobs {
+		if job.status.ID == id {
+			return job, true
 		}
+	}
+	return nil, false
+}
 
-		posterURL := ""
-		for _, img := range record.Movie.Images {
-			if img.CoverType == "poster" {
-				posterURL = img.RemoteURL
-				break
-			}
-		}
+// Begin a new step, ending the current one
+func (job *Job) step(name string) {
+	job.mu.Lock()
+	job.endStepLocked()
+	job.status.Steps = append(job.status.Steps, JobStep{Name: name, StartedAt: time.Now()})
+	job.mu.Unlock()
+	job.publish(ProgressEvent{Kind: ProgressStep, Step: name})
+}
 
-		movies = append(movies, Movie{
-			Title:       record.Movie.Title,
-			Year:        record.Movie.Year,
-			ReleaseDate: record.Movie.InCinemas,
-			Downloaded:  true,
-			PosterURL:   posterURL,
-			IMDBID:      record.Movie.ImdbID,
-			TmdbID:      record.Movie.TmdbID,
-		})
-	}
+func (job *Job) progress(processed, recipients int) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.status.Processed, job.status.Recipients = processed, recipients
+}
 
-	return movies, nil
+// A recipient was handled (sent, failed or skipped)
+func (job *Job) delivered(d Delivery, processed, recipients int) {
+	job.progress(processed, recipients)
+	job.publish(ProgressEvent{Kind: ProgressDelivery, Email: d.Email, Status: d.Status, Error: d.Error, Processed: processed, Recipients: recipients})
 }
 
-func fetchRadarrCalendar(ctx context.Context, cfg *Config, start, end time.Time) ([]Movie, error) {
-	url := fmt.Sprintf("%s/api/v3/calendar?unmonitored=true&includeMovie=true&start=%s&end=%s",
-		cfg.RadarrURL, start.Format("2006-01-02"), end.Format("2006-01-02"))
+func (job *Job) finish(status, message, errorKind string) {
+	job.mu.Lock()
+	job.endStepLocked()
+	now := time.Now()
+	job.status.Status = status
+	job.status.Message = message
+	job.status.ErrorKind = errorKind
+	job.status.FinishedAt = &now
+	job.mu.Unlock()
+	job.publish(ProgressEvent{Kind: ProgressFinished, Status: status, Message: message})
+}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
+// Logger tagging entries with the job's run (the default logger without a job)
+func (job *Job) logger() *slog.Logger {
+	if job == nil {
+		return slog.Default()
 	}
-	req.Header.Set("X-Api-Key", cfg.RadarrAPIKey)
+	return slog.With("run_id", job.status.ID, "profile", job.status.ProfileID)
+}
 
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+// Logger tagged with the run, for code that has the run but not its job
+func (run *Run) logger() *slog.Logger {
+	return slog.With("run_id", run.ID, "profile", run.ProfileID)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
+// Send a progress event to live UI clients (no-op without a job)
+func (job *Job) publish(e ProgressEvent) {
+	if job == nil {
+		return
 	}
+	e.JobID, e.ProfileID, e.Time = job.status.ID, job.status.ProfileID, time.Now()
+	events.publish(Event{Type: "progress", Data: e})
+}
 
-	// Stream-decode JSON to save memory
-	var calendar []CalendarMovie
-	decoder := json.NewDecoder(resp.Body)
-	if err := decoder.Decode(&calendar); err != nil {
-		return nil, err
+func (job *Job) endStepLocked() {
+	if n := len(job.status.Steps); n > 0 && job.status.Steps[n-1].FinishedAt == nil {
+		now := time.Now()
+		job.status.Steps[n-1].FinishedAt = &now
 	}
+}
 
-	// Map to Movie struct
-	var movies []Movie
-	for _, entry := range calendar {
-		posterURL := ""
-		for _, img := range entry.Images {
-			if img.CoverType == "poster" {
-				if img.Url != "" {
-					posterURL = img.Url
-				} else if img.RemoteUrl != "" {
-					posterURL = img.RemoteUrl
-				}
-				break
-			}
-		}
+// Ask the run to stop; it finishes as cancelled before its next recipient
+func (job *Job) stop() {
+	job.mu.Lock()
+	job.status.CancelRequested = true
+	job.mu.Unlock()
+	job.cancel()
+}
 
-		mv := Movie{
-			Title:       entry.Title,
-			Year:        entry.Year,
-			ReleaseDate: entry.PhysicalRelease,
-			PosterURL:   posterURL,
-			IMDBID:      entry.ImdbId,
-			TmdbID:      entry.TmdbId,
-		}
+func (job *Job) snapshot() JobStatus {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	status := job.status
+	status.Steps = append([]JobStep{}, job.status.Steps...)
+	return status
+}
 
-		if mv.ReleaseDate != "" {
-			releaseDate, _ := time.Parse("2006-01-02", mv.ReleaseDate)
-			mv.ReleaseDate = releaseDate.Format("2006-01-02")
-		}
+// Run progress streamed to the web UI
+type ProgressEvent struct {
+	JobID      string    `json:"job_id"`
+	ProfileID  string    `json:"profile_id"`
+	Kind       string    `json:"kind"`
+	Step       string    `json:"step,omitempty"`
+	Source     string    `json:"source,omitempty"` // e.g. "Sonarr history"
+	Items      int       `json:"items,omitempty"`
+	Email      string    `json:"email,omitempty"`
+	Status     string    `json:"status,omitempty"` // delivery status, or the run's outcome when finished
+	Error      string    `json:"error,omitempty"`
+	Message    string    `json:"message,omitempty"` // the run's final message when finished
+	Processed  int       `json:"processed,omitempty"`
+	Recipients int       `json:"recipients,omitempty"`
+	Time       time.Time `json:"time"`
+}
 
-		movies = append(movies, mv)
-	}
+// Progress event kinds
+const (
+	ProgressStep           = "step"
+	ProgressSourceStarted  = "source_started"
+	ProgressSourceFinished = "source_finished"
+	ProgressDelivery       = "delivery"
+	ProgressFinished       = "finished"
+)
 
-	return movies, nil
+// Live events for the web UI (/api/events): new log lines and run progress
+type Event struct {
+	Type string // "log" (a LogEntry) or "progress" (a ProgressEvent)
+	Data interface{}
 }
 
-// Group episodes by series
-func groupEpisodesBySeries(episodes []Episode) []SeriesGroup {
-	seriesMap := make(map[string]*SeriesGroup)
+// Subscribers this far behind miss events rather than stall the publisher
+const eventBuffer = 256
 
-	// Sort episodes by air date first
-	sort.Slice(episodes, func(i, j int) bool {
-		return episodes[i].AirDate < episodes[j].AirDate
-	})
+type eventHub struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+	closed      bool
+}
 
-	for _, ep := range episodes {
-		group, exists := seriesMap[ep.SeriesTitle]
-		if !exists {
-			group = &SeriesGroup{
-				SeriesTitle: ep.SeriesTitle,
-				PosterURL:   ep.PosterURL,
-				Episodes:    []Episode{},
-				IMDBID:      ep.IMDBID,
-				TvdbID:      ep.TvdbID,
-			}
-			seriesMap[ep.SeriesTitle] = group
-		}
+var events = &eventHub{subscribers: make(map[chan Event]struct{})}
 
-		// Check for duplicate episodes (same season and episode number)
-		isDuplicate := false
-		for _, existingEp := range group.Episodes {
-			if existingEp.SeasonNum == ep.SeasonNum && existingEp.EpisodeNum == ep.EpisodeNum {
-				isDuplicate = true
-				break
-			}
-		}
+func (h *eventHub) subscribe() chan Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch := make(chan Event, eventBuffer)
+	if h.closed {
+		close(ch)
+		return ch
+	}
+	h.subscribers[ch] = struct{}{}
+	return ch
+}
 
-		// Only add if not a duplicate
-		if !isDuplicate {
-			group.Episodes = append(group.Episodes, ep)
+func (h *eventHub) unsubscribe(ch chan Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[ch]; ok {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+// Never blocks and never logs (log lines are published from the log writer)
+func (h *eventHub) publish(e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers {
+		select {
+		case ch <- e:
+		default:
 		}
 	}
+}
 
-	groups := make([]SeriesGroup, 0, len(seriesMap))
-	for _, group := range seriesMap {
-		groups = append(groups, *group)
+// End every stream so server shutdown isn't held up by open connections
+func (h *eventHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for ch := range h.subscribers {
+		delete(h.subscribers, ch)
+		close(ch)
 	}
+}
 
-	sort.Slice(groups, func(i, j int) bool {
-		return groups[i].SeriesTitle < groups[j].SeriesTitle
-	})
+// Prometheus metrics, written in the text exposition format by metricsHandler
+var (
+	buildInfo        = newMetric("gauge", "newslettar_build_info", "Always 1, labelled with the running version.", "version")
+	runsTotal        = newMetric("counter", "newslettar_runs_total", "Finished newsletter runs by outcome.", "profile", "trigger", "status")
+	runDuration      = newHistogram("newslettar_run_duration_seconds", "Duration of newsletter runs.", []float64{1, 5, 10, 30, 60, 120, 300, 600}, "profile")
+	lastRunTime      = newMetric("gauge", "newslettar_last_run_timestamp_seconds", "Unix time the newsletter's last run finished.", "profile")
+	lastSuccessTime  = newMetric("gauge", "newslettar_last_success_timestamp_seconds", "Unix time the newsletter was last sent (success or partial).", "profile")
+	sectionItems     = newMetric("gauge", "newslettar_section_items", "Items per section in the newsletter's last run.", "profile", "section")
+	upstreamDuration = newHistogram("newslettar_upstream_request_duration_seconds", "Latency of Sonarr/Radarr API requests.", []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 15}, "source", "endpoint")
+	upstreamErrors   = newMetric("counter", "newslettar_upstream_errors_total", "Sonarr/Radarr API requests that failed or returned an HTTP error.", "source", "endpoint")
+	upstreamRetries  = newMetric("counter", "newslettar_upstream_retries_total", "Retried Sonarr/Radarr fetches.", "source", "endpoint")
+	emailsTotal      = newMetric("counter", "newslettar_emails_total", "Emails handed to an SMTP server, by result.", "transport", "result")
+)
 
-	return groups
+var allMetrics = []*metricVec{
+	buildInfo, runsTotal, runDuration, lastRunTime, lastSuccessTime, sectionItems,
+	upstreamDuration, upstreamErrors, upstreamRetries, emailsTotal,
 }
 
-// Generate newsletter HTML using precompiled template
-func generateNewsletterHTML(data NewsletterData, showPosters, showDownloaded bool) (string, error) {
-	templateData := struct {
-		NewsletterData
-		ShowPosters    bool
-		ShowDownloaded bool
-	}{
-		NewsletterData: data,
-		ShowPosters:    showPosters,
-		ShowDownloaded: showDownloaded,
-	}
+// A metric family: one series per combination of label values
+type metricVec struct {
+	name, help string
+	kind       string    // counter, gauge or histogram
+	labels     []string  // label names
+	buckets    []float64 // histogram upper bounds, ascending
 
-	var buf bytes.Buffer
-	if err := emailTemplate.Execute(&buf, templateData); err != nil {
-		return "", err
-	}
+	mu     sync.Mutex
+	series map[string]*metricSeries // keyed by the label values joined with \xff
+}
 
-	return buf.String(), nil
+type metricSeries struct {
+	labelValues []string
+	value       float64  // counter or gauge
+	counts      []uint64 // histogram: observations per bucket (not cumulative)
+	sum         float64
+	count       uint64
 }
 
-func formatDateWithDay(dateStr string) string {
-	if dateStr == "" {
-		return "Date TBA"
-	}
+func newMetric(kind, name, help string, labels ...string) *metricVec {
+	return &metricVec{name: name, help: help, kind: kind, labels: labels, series: make(map[string]*metricSeries)}
+}
 
-	t, err := time.Parse("2006-01-02", dateStr)
-	if err != nil {
-		return dateStr
+func newHistogram(name, help string, buckets []float64, labels ...string) *metricVec {
+	m := newMetric("histogram", name, help, labels...)
+	m.buckets = buckets
+	return m
+}
+
+// Caller holds m.mu
+func (m *metricVec) get(labelValues []string) *metricSeries {
+	key := strings.Join(labelValues, "\xff")
+	s, ok := m.series[key]
+	if !ok {
+		s = &metricSeries{labelValues: labelValues}
+		if m.kind == "histogram" {
+			s.counts = make([]uint64, len(m.buckets))
+		}
+		m.series[key] = s
 	}
+	return s
+}
 
-	return t.Format("Monday, January 2, 2006")
+func (m *metricVec) inc(labelValues ...string) {
+	m.mu.Lock()
+	m.get(labelValues).value++
+	m.mu.Unlock()
 }
 
-// Send email
-func sendEmail(cfg *Config, subject, htmlBody string) error {
-	if cfg.FromEmail == "" || len(cfg.ToEmails) == 0 {
-		return fmt.Errorf("email configuration incomplete")
+func (m *metricVec) set(value float64, labelValues ...string) {
+	m.mu.Lock()
+	m.get(labelValues).value = value
+	m.mu.Unlock()
+}
+
+func (m *metricVec) observe(value float64, labelValues ...string) {
+	m.mu.Lock()
+	s := m.get(labelValues)
+	for i, bound := range m.buckets {
+		if value <= bound {
+			s.counts[i]++
+			break
+		}
 	}
+	s.sum += value
+	s.count++
+	m.mu.Unlock()
+}
 
-	from := cfg.FromEmail
-	if cfg.FromName != "" {
-		from = fmt.Sprintf("%s <%s>", cfg.FromName, cfg.FromEmail)
+func (m *metricVec) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+	keys := make([]string, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
 	}
+	sort.Strings(keys)
 
-	headers := make(map[string]string)
-	headers["From"] = from
-	headers["To"] = strings.Join(cfg.ToEmails, ", ")
-	headers["Subject"] = subject
-	headers["MIME-Version"] = "1.0"
-	headers["Content-Type"] = "text/html; charset=UTF-8"
+	for _, key := range keys {
+		s := m.series[key]
+		if m.kind != "histogram" {
+			fmt.Fprintf(w, "%s%s %s\n", m.name, m.labelSet(s.labelValues, ""), formatMetricValue(s.value))
+			continue
+		}
+		var cumulative uint64
+		for i, bound := range m.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", m.name, m.labelSet(s.labelValues, formatMetricValue(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", m.name, m.labelSet(s.labelValues, "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", m.name, m.labelSet(s.labelValues, ""), formatMetricValue(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", m.name, m.labelSet(s.labelValues, ""), s.count)
+	}
+}
 
-	message := ""
-	for k, v := range headers {
-		message += fmt.Sprintf("%s: %s\r\n", k, v)
+// {name="value",...}, plus the histogram bucket bound when le is set
+func (m *metricVec) labelSet(values []string, le string) string {
+	var pairs []string
+	for i, name := range m.labels {
+		pairs = append(pairs, name+`="`+labelEscaper.Replace(values[i])+`"`)
 	}
-	message += "\r\n" + htmlBody
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
 
-	auth := smtp.PlainAuth("", cfg.MailgunUser, cfg.MailgunPass, cfg.MailgunSMTP)
-	addr := fmt.Sprintf("%s:%s", cfg.MailgunSMTP, cfg.MailgunPort)
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
 
-	return smtp.SendMail(addr, auth, cfg.FromEmail, cfg.ToEmails, []byte(message))
+func formatMetricValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
 }
 
-// Web server with gzip compression
-func startWebServer() {
-	cfg := getConfig()
+// Record a finished run (called once per job, whatever the trigger)
+func observeRun(run *Run) {
+	runsTotal.inc(run.ProfileID, run.Trigger, run.Status)
+	if run.FinishedAt != nil {
+		runDuration.observe(run.FinishedAt.Sub(run.StartedAt).Seconds(), run.ProfileID)
+	}
+	observeRunGauges(run)
+}
 
-	// Setup internal scheduler
-	setupScheduler(cfg)
+func observeRunGauges(run *Run) {
+	if run.FinishedAt == nil {
+		return
+	}
+	finished := float64(run.FinishedAt.Unix())
+	lastRunTime.set(finished, run.ProfileID)
+	if run.Status == RunSuccess || run.Status == RunPartial {
+		lastSuccessTime.set(finished, run.ProfileID)
+	}
+	for section, items := range run.Counts {
+		sectionItems.set(float64(items), run.ProfileID, section)
+	}
+}
 
-	port := os.Getenv("WEBUI_PORT")
-	if port == "" {
-		port = "8080"
+// Restore the last-run gauges from the run history, so a restart doesn't look like a silent stop
+func seedRunMetrics() {
+	runs := loadRuns()
+	for i := len(runs) - 1; i >= 0; i-- { // oldest first
+		if runs[i].Status != RunRunning {
+			observeRunGauges(&runs[i])
+		}
 	}
+}
 
-	// Serve static files with gzip
-	http.HandleFunc("/", withGzip(uiHandler))
-	http.HandleFunc("/api/config", configHandler)
-	http.HandleFunc("/api/test-sonarr", testSonarrHandler)
-	http.HandleFunc("/api/test-radarr", testRadarrHandler)
-	http.HandleFunc("/api/test-email", testEmailHandler)
-	http.HandleFunc("/api/send", sendHandler)
-	http.HandleFunc("/api/logs", logsHandler)
-	http.HandleFunc("/api/version", versionHandler)
-	http.HandleFunc("/api/update", updateHandler)
-	http.HandleFunc("/api/preview", previewHandler)
-	http.HandleFunc("/api/timezone-info", timezoneInfoHandler)
+// Round tripper timing every outgoing request for the upstream metrics
+type instrumentedTransport struct {
+	next http.RoundTripper
+}
 
-	// Graceful shutdown
-	server := &http.Server{
-		Addr:    ":" + port,
-		Handler: nil,
+func (t instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	source, endpoint := upstreamLabels(req.URL)
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	upstreamDuration.observe(time.Since(start).Seconds(), source, endpoint)
+	if err != nil || resp.StatusCode >= 400 {
+		upstreamErrors.inc(source, endpoint)
 	}
+	return resp, err
+}
 
-	go func() {
-		log.Printf("🌐 Web UI started on port %s", port)
-		log.Printf("📅 Scheduler: %s at %s (%s)", cfg.ScheduleDay, cfg.ScheduleTime, cfg.Timezone)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("❌ Server error: %v", err)
+// sonarr/radarr (by the configured URLs) and the API resource, e.g. history or calendar
+func upstreamLabels(u *url.URL) (source, endpoint string) {
+	source = "other"
+	if cfg := getConfig(); cfg != nil {
+		target := u.String()
+		switch {
+		case cfg.SonarrURL != "" && strings.HasPrefix(target, cfg.SonarrURL):
+			source = "sonarr"
+		case cfg.RadarrURL != "" && strings.HasPrefix(target, cfg.RadarrURL):
+			source = "radarr"
 		}
-	}()
+	}
 
-	// Wait for interrupt signal
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	endpoint = "other"
+	if _, rest, ok := strings.Cut(u.Path, "/api/v3/"); ok {
+		endpoint, _, _ = strings.Cut(rest, "/")
+	}
+	return source, endpoint
+}
 
-	log.Println("🛑 Shutting down server...")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// Fetch the profile's enabled sections from Sonarr/Radarr in parallel. History sections
+// start at historySince when it has an earlier start for them (see historyWindow).
+func fetchNewsletterData(ctx context.Context, cfg *Config, p Profile, weekStart, weekEnd time.Time, historySince map[string]time.Time, retries int) (NewsletterData, []SourceResult) {
+	var wg sync.WaitGroup
+	var downloadedEpisodes, upcomingEpisodes []Episode
+	var downloadedMovies, upcomingMovies []Movie
+	upcomingEnd := weekEnd.Add(p.lookahead())
+	since := func(section string) time.Time {
+		if t, ok := historySince[section]; ok && t.Before(weekStart) {
+			return t
+		}
+		return weekStart
+	}
 
-	if scheduler != nil {
-		scheduler.Stop()
+	// Progress and logs go to the job running this fetch, if any
+	job := jobFromContext(ctx)
+	logger := job.logger()
+
+	var sourcesMu sync.Mutex
+	var sources []SourceResult
+	begin := func(name string) time.Time {
+		job.publish(ProgressEvent{Kind: ProgressSourceStarted, Source: name})
+		return time.Now()
+	}
+	record := func(section, name string, start time.Time, items int, err error) {
+		result := SourceResult{Section: section, Name: name, DurationMs: time.Since(start).Milliseconds(), Items: items}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		sourcesMu.Lock()
+		sources = append(sources, result)
+		sourcesMu.Unlock()
+		job.publish(ProgressEvent{Kind: ProgressSourceFinished, Source: name, Items: items, Error: result.Error})
 	}
 
-	if err := server.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
+	logger.Debug("📡 Fetching data in parallel")
+	startFetch := time.Now()
+
+	if p.hasSection(SectionDownloadedSeries) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Debug("📺 Fetching Sonarr history")
+			start := begin("Sonarr history")
+			var err error
+			downloadedEpisodes, err = fetchSonarrHistoryWithRetry(ctx, cfg, since(SectionDownloadedSeries), retries)
+			record(SectionDownloadedSeries, "Sonarr history", start, len(downloadedEpisodes), err)
+			if err != nil {
+				logger.Warn("⚠️  Sonarr history error", "error", err)
+			} else {
+				logger.Info("✓ Found downloaded episodes", "count", len(downloadedEpisodes))
+			}
+		}()
 	}
 
-	log.Println("✅ Server stopped")
-}
+	if p.hasSection(SectionUpcomingSeries) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Debug("📺 Fetching Sonarr calendar")
+			start := begin("Sonarr calendar")
+			var err error
+			upcomingEpisodes, err = fetchSonarrCalendarWithRetry(ctx, cfg, weekEnd, upcomingEnd, retries)
+			record(SectionUpcomingSeries, "Sonarr calendar", start, len(upcomingEpisodes), err)
+			if err != nil {
+				logger.Warn("⚠️  Sonarr calendar error", "error", err)
+			} else {
+				logger.Info("✓ Found upcoming episodes", "count", len(upcomingEpisodes))
+			}
+		}()
+	}
 
-// Setup internal cron scheduler (replaces systemd timer)
-func setupScheduler(cfg *Config) {
-	scheduler = cron.New(cron.WithLocation(getTimezone(cfg.Timezone)))
+	if p.hasSection(SectionDownloadedMovies) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Debug("🎬 Fetching Radarr history")
+			start := begin("Radarr history")
+			var err error
+			downloadedMovies, err = fetchRadarrHistoryWithRetry(ctx, cfg, since(SectionDownloadedMovies), retries)
+			record(SectionDownloadedMovies, "Radarr history", start, len(downloadedMovies), err)
+			if err != nil {
+				logger.Warn("⚠️  Radarr history error", "error", err)
+			} else {
+				logger.Info("✓ Found downloaded movies", "count", len(downloadedMovies))
+			}
+		}()
+	}
+
+	if p.hasSection(SectionUpcomingMovies) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Debug("🎬 Fetching Radarr calendar")
+			start := begin("Radarr calendar")
+			var err error
+			upcomingMovies, err = fetchRadarrCalendarWithRetry(ctx, cfg, weekEnd, upcomingEnd, p.releaseTypes(), retries)
+			record(SectionUpcomingMovies, "Radarr calendar", start, len(upcomingMovies), err)
+			if err != nil {
+				logger.Warn("⚠️  Radarr calendar error", "error", err)
+			} else {
+				logger.Info("✓ Found upcoming movies", "count", len(upcomingMovies))
+			}
+		}()
+	}
 
-	// Convert day/time to cron expression
-	cronExpr := convertToCronExpression(cfg.ScheduleDay, cfg.ScheduleTime)
-	log.Printf("📅 Setting up scheduler: %s (cron: %s)", cfg.ScheduleDay+" "+cfg.ScheduleTime, cronExpr)
+	wg.Wait()
+	logger.Debug("⚡ All data fetched", "duration", time.Since(startFetch).String())
+	sort.Slice(sources, func(i, j int) bool { return sources[i].Name < sources[j].Name })
 
-	_, err := scheduler.AddFunc(cronExpr, func() {
-		log.Println("⏰ Scheduled newsletter triggered")
-		runNewsletter()
+	// Sort movies chronologically
+	sort.Slice(upcomingMovies, func(i, j int) bool {
+		return upcomingMovies[i].ReleaseDate < upcomingMovies[j].ReleaseDate
+	})
+	sort.Slice(downloadedMovies, func(i, j int) bool {
+		return downloadedMovies[i].ReleaseDate < downloadedMovies[j].ReleaseDate
 	})
 
-	if err != nil {
-		log.Printf("⚠️  Failed to setup scheduler: %v", err)
-		return
+	return NewsletterData{
+		NewsletterName:         p.Name,
+		WeekStart:              weekStart.Format("January 2, 2006"),
+		WeekEnd:                weekEnd.Format("January 2, 2006"),
+		WindowStart:            weekStart.Format("Mon, January 2, 2006 3:04 PM"),
+		WindowEnd:              weekEnd.Format("Mon, January 2, 2006 3:04 PM MST"),
+		UpcomingUntil:          upcomingEnd.Format("Mon, January 2, 2006"),
+		UpcomingSeriesGroups:   groupEpisodesBySeries(upcomingEpisodes),
+		UpcomingMovies:         upcomingMovies,
+		DownloadedSeriesGroups: groupEpisodesBySeries(downloadedEpisodes),
+		DownloadedMovies:       downloadedMovies,
+	}, sources
+}
+
+// Retry wrappers for API calls
+func fetchSonarrHistoryWithRetry(ctx context.Context, cfg *Config, since time.Time, maxRetries int) ([]Episode, error) {
+	var episodes []Episode
+	var err error
+	for i := 0; i < maxRetries; i++ {
+		episodes, err = fetchSonarrHistory(ctx, cfg, since)
+		if err == nil {
+			return episodes, nil
+		}
+		if i < maxRetries-1 {
+			wait := time.Duration(i+1) * time.Second
+			jobFromContext(ctx).logger().Info("⏳ Retrying Sonarr history", "wait", wait.String(), "attempt", i+2, "of", maxRetries)
+			upstreamRetries.inc("sonarr", "history")
+			if !sleepContext(ctx, wait) {
+				return nil, ctx.Err()
+			}
+		}
 	}
+	return episodes, err
+}
 
-	scheduler.Start()
-	log.Println("✅ Internal scheduler started")
+func fetchSonarrCalendarWithRetry(ctx context.Context, cfg *Config, start, end time.Time, maxRetries int) ([]Episode, error) {
+	var episodes []Episode
+	var err error
+	for i := 0; i < maxRetries; i++ {
+		episodes, err = fetchSonarrCalendar(ctx, cfg, start, end)
+		if err == nil {
+			return episodes, nil
+		}
+		if i < maxRetries-1 {
+			wait := time.Duration(i+1) * time.Second
+			jobFromContext(ctx).logger().Info("⏳ Retrying Sonarr calendar", "wait", wait.String(), "attempt", i+2, "of", maxRetries)
+			upstreamRetries.inc("sonarr", "calendar")
+			if !sleepContext(ctx, wait) {
+				return nil, ctx.Err()
+			}
+		}
+	}
+	return episodes, err
 }
 
-// Convert day/time to cron expression
-func convertToCronExpression(day, timeStr string) string {
-	// Parse time (HH:MM)
-	parts := strings.Split(timeStr, ":")
-	hour := "9"
-	minute := "0"
-	if len(parts) == 2 {
-		hour = parts[0]
-		minute = parts[1]
+func fetchRadarrHistoryWithRetry(ctx context.Context, cfg *Config, since time.Time, maxRetries int) ([]Movie, error) {
+	var movies []Movie
+	var err error
+	for i := 0; i < maxRetries; i++ {
+		movies, err = fetchRadarrHistory(ctx, cfg, since)
+		if err == nil {
+			return movies, nil
+		}
+		if i < maxRetries-1 {
+			wait := time.Duration(i+1) * time.Second
+			jobFromContext(ctx).logger().Info("⏳ Retrying Radarr history", "wait", wait.String(), "attempt", i+2, "of", maxRetries)
+			upstreamRetries.inc("radarr", "history")
+			if !sleepContext(ctx, wait) {
+				return nil, ctx.Err()
+			}
+		}
 	}
+	return movies, err
+}
 
-	// Convert day to cron weekday (0 = Sunday, 6 = Saturday)
-	dayMap := map[string]string{
-		"Sun": "0",
-		"Mon": "1",
-		"Tue": "2",
-		"Wed": "3",
-		"Thu": "4",
-		"Fri": "5",
-		"Sat": "6",
+func fetchRadarrCalendarWithRetry(ctx context.Context, cfg *Config, start, end time.Time, releaseTypes []string, maxRetries int) ([]Movie, error) {
+	var movies []Movie
+	var err error
+	for i := 0; i < maxRetries; i++ {
+		movies, err = fetchRadarrCalendar(ctx, cfg, start, end, releaseTypes)
+		if err == nil {
+			return movies, nil
+		}
+		if i < maxRetries-1 {
+			wait := time.Duration(i+1) * time.Second
+			jobFromContext(ctx).logger().Info("⏳ Retrying Radarr calendar", "wait", wait.String(), "attempt", i+2, "of", maxRetries)
+			upstreamRetries.inc("radarr", "calendar")
+			if !sleepContext(ctx, wait) {
+				return nil, ctx.Err()
+			}
+		}
 	}
+	return movies, err
+}
 
-	cronDay := dayMap[day]
-	if cronDay == "" {
-		cronDay = "0" // Default to Sunday
+// Wait for d, unless ctx is cancelled first (false)
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
 	}
+}
 
-	// Cron format: minute hour day month weekday
-	return fmt.Sprintf("%s %s * * %s", minute, hour, cronDay)
+// Get timezone location
+func getTimezone(tz string) *time.Location {
+	if tz == "" {
+		tz = "UTC"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		log.Printf("⚠️  Invalid timezone '%s', using UTC", tz)
+		return time.UTC
+	}
+	return loc
 }
 
-// Restart scheduler when config changes
-func restartScheduler() {
-	if scheduler != nil {
-		scheduler.Stop()
+// Get config (cached, thread-safe)
+func getConfig() *Config {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return cachedConfig
+}
+
+// Reload config (called when user saves configuration)
+func reloadConfig() {
+	configMu.Lock()
+	defer configMu.Unlock()
+	cachedConfig = loadConfig()
+	log.Println("🔄 Configuration reloaded from .env")
+}
+
+// Load configuration from .env file (only called at startup and on reload)
+func loadConfig() *Config {
+	envMap := readEnvFile()
+
+	toEmails := splitList(getEnvFromFile(envMap, "TO_EMAILS", ""))
+
+	logMaxSizeMB, err := strconv.Atoi(getEnvFromFile(envMap, "LOG_MAX_SIZE_MB", "10"))
+	if err != nil || logMaxSizeMB < 0 {
+		log.Printf("⚠️  Invalid LOG_MAX_SIZE_MB, using 10")
+		logMaxSizeMB = 10
+	}
+	logMaxAge, err := parseWindowDuration(getEnvFromFile(envMap, "LOG_MAX_AGE", "7d"))
+	if err != nil {
+		log.Printf("⚠️  Invalid LOG_MAX_AGE, using 7d")
+		logMaxAge = 7 * 24 * time.Hour
+	}
+
+	return &Config{
+		SonarrURL:      getEnvFromFile(envMap, "SONARR_URL", ""),
+		SonarrAPIKey:   getEnvFromFile(envMap, "SONARR_API_KEY", ""),
+		RadarrURL:      getEnvFromFile(envMap, "RADARR_URL", ""),
+		RadarrAPIKey:   getEnvFromFile(envMap, "RADARR_API_KEY", ""),
+		MailgunSMTP:    getEnvFromFile(envMap, "MAILGUN_SMTP", "smtp.mailgun.org"),
+		MailgunPort:    getEnvFromFile(envMap, "MAILGUN_PORT", "587"),
+		MailgunUser:    getEnvFromFile(envMap, "MAILGUN_USER", ""),
+		MailgunPass:    getEnvFromFile(envMap, "MAILGUN_PASS", ""),
+		FromEmail:      getEnvFromFile(envMap, "FROM_EMAIL", ""),
+		FromName:       getEnvFromFile(envMap, "FROM_NAME", "Newslettar"),
+		AdminEmail:     getEnvFromFile(envMap, "ADMIN_EMAIL", ""),
+		ToEmails:       toEmails,
+		Timezone:       getEnvFromFile(envMap, "TIMEZONE", "UTC"),
+		CatchUpGrace:   parseDurationSetting(getEnvFromFile(envMap, "CATCHUP_GRACE", "24h")),
+		ScheduleDay:    getEnvFromFile(envMap, "SCHEDULE_DAY", "Sun"),
+		ScheduleTime:   getEnvFromFile(envMap, "SCHEDULE_TIME", "09:00"),
+		ShowPosters:    getEnvFromFile(envMap, "SHOW_POSTERS", "true") != "false",
+		ShowDownloaded: getEnvFromFile(envMap, "SHOW_DOWNLOADED", "true") != "false",
+		SignupEnabled:  getEnvFromFile(envMap, "SIGNUP_ENABLED", "false") == "true",
+		InviteCodes:    splitList(getEnvFromFile(envMap, "INVITE_CODES", "")),
+		PublicURL:      strings.TrimSuffix(getEnvFromFile(envMap, "PUBLIC_URL", ""), "/"),
+		ArchiveAccess:  getEnvFromFile(envMap, "ARCHIVE_ACCESS", ArchiveSigned),
+		FeedToken:      getEnvFromFile(envMap, "FEED_TOKEN", ""),
+		DataDir:        getEnvFromFile(envMap, "DATA_DIR", "data"),
+		LogLevel:       getEnvFromFile(envMap, "LOG_LEVEL", "info"),
+		LogFormat:      getEnvFromFile(envMap, "LOG_FORMAT", "text"),
+		LogFile:        getEnvFromFile(envMap, "LOG_FILE", "true") != "false",
+		LogMaxSize:     int64(logMaxSizeMB) * 1024 * 1024,
+		LogMaxAge:      logMaxAge,
+
+		AuthProxyHeader: getEnvFromFile(envMap, "AUTH_PROXY_HEADER", ""),
+		TrustedProxies:  splitList(getEnvFromFile(envMap, "TRUSTED_PROXIES", "")),
 	}
-	cfg := getConfig()
-	setupScheduler(cfg)
-	log.Println("🔄 Scheduler restarted")
 }
 
-// Gzip compression middleware
-func withGzip(handler http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
-			handler(w, r)
-			return
+// Parse a duration setting such as "24h" or "90m" (invalid or "0" = disabled)
+func parseDurationSetting(value string) time.Duration {
+	d, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil && strings.TrimSpace(value) != "0" {
+		log.Printf("⚠️  Invalid duration '%s', treating as disabled", value)
+	}
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// Split a comma-separated setting into trimmed, non-empty values
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
 		}
+	}
+	return items
+}
 
-		w.Header().Set("Content-Encoding", "gzip")
-		gz := gzip.NewWriter(w)
-		defer gz.Close()
+// Settings from .env, with the secrets from the encrypted store (when enabled) merged in
+func readEnvFile() map[string]string {
+	envMap := readPlainEnvFile()
 
-		gzw := &gzipResponseWriter{Writer: gz, ResponseWriter: w}
-		handler(gzw, r)
+	secrets, err := readSecretsStore()
+	if err != nil {
+		log.Printf("❌ Cannot read the secrets store: %v", err)
 	}
+	for key, value := range secrets {
+		envMap[key] = value
+	}
+	return envMap
 }
 
-type gzipResponseWriter struct {
-	io.Writer
-	http.ResponseWriter
-}
+func readPlainEnvFile() map[string]string {
+	envMap := make(map[string]string)
 
-func (w *gzipResponseWriter) Write(b []byte) (int, error) {
-	return w.Writer.Write(b)
+	data, err := os.ReadFile(".env")
+	if err != nil {
+		return envMap
+	}
+
+	lines := strings.Split(string(data), "\n")
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) == 2 {
+			key := strings.TrimSpace(parts[0])
+			value := strings.TrimSpace(parts[1])
+			envMap[key] = value
+		}
+	}
+
+	return envMap
 }
 
-// Handlers
+// Save settings: secrets go to the encrypted store when it is enabled, the rest to .env.
+// Refuses to write when an existing store can't be read, rather than lose its contents.
+func writeEnvFile(envMap map[string]string) error {
+	key, err := secretsKey()
+	if err != nil {
+		return err
+	}
 
-func uiHandler(w http.ResponseWriter, r *http.Request) {
-	cfg := getConfig()
-	loc := getTimezone(cfg.Timezone)
-	nextRun := getNextScheduledRun(cfg.ScheduleDay, cfg.ScheduleTime, loc)
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	current, err := readSecretsStore()
+	if err != nil {
+		return fmt.Errorf("secrets store unreadable, not saving: %w", err)
+	}
 
-	html := `<!DOCTYPE html>
-<html lang="en">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Newslettar v` + version + `</title>
-    <style>
-        * { margin: 0; padding: 0; box-sizing: border-box; }
+	// Profile passwords in the store belong to the profiles and are kept as they are
+	plain := make(map[string]string)
+	secrets := make(map[string]string)
+	for k, v := range current {
+		if isProfileSecretKey(k) {
+			secrets[k] = v
+		}
+	}
+	for k, v := range envMap {
+		switch {
+		case isProfileSecretKey(k):
+		case key != nil && containsString(secretEnvKeys, k):
+			secrets[k] = v
+		default:
+			plain[k] = v
+		}
+	}
+	if key != nil {
+		if err := writeSecretsStore(key, secrets); err != nil {
+			return err
+		}
+	}
+
+	keys := make([]string, 0, len(plain))
+	for k := range plain {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var envContent strings.Builder
+	for _, k := range keys {
+		envContent.WriteString(fmt.Sprintf("%s=%s\n", k, plain[k]))
+	}
+	// Replaced atomically, which also drops the world-readable mode older installs wrote it with
+	if err := os.WriteFile(".env.tmp", []byte(envContent.String()), 0600); err != nil {
+		return err
+	}
+	return os.Rename(".env.tmp", ".env")
+}
+
+// Encrypted secrets store (secrets.enc next to .env): AES-256-GCM, with the key from
+// SECRETS_KEY (base64) or the key file (SECRETS_KEY_FILE, default secret.key).
+// Without a key, secrets stay in .env as before.
+var secretEnvKeys = []string{"SONARR_API_KEY", "RADARR_API_KEY", "MAILGUN_USER", "MAILGUN_PASS", "FEED_TOKEN"}
+
+// Serialises read-modify-write of the store (settings and profile passwords share it)
+var secretsMu sync.Mutex
+
+// Store entry of a profile's SMTP password
+func profileSecretKey(profileID string) string {
+	return "PROFILE_" + profileID + "_SMTP_PASS"
+}
+
+func isProfileSecretKey(key string) bool {
+	return strings.HasPrefix(key, "PROFILE_") && strings.HasSuffix(key, "_SMTP_PASS")
+}
+
+// Put the profiles' SMTP passwords (by profile ID) in the store, replacing those it
+// held. False when the store isn't enabled, and the passwords stay in profiles.json.
+func storeProfileSecrets(passes map[string]string) (bool, error) {
+	key, err := secretsKey()
+	if err != nil || key == nil {
+		return false, err
+	}
+
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	secrets, err := readSecretsStore()
+	if err != nil {
+		return false, err
+	}
+	if secrets == nil {
+		secrets = make(map[string]string)
+	}
+	for k := range secrets {
+		if isProfileSecretKey(k) {
+			delete(secrets, k)
+		}
+	}
+	for id, pass := range passes {
+		if pass != "" {
+			secrets[profileSecretKey(id)] = pass
+		}
+	}
+	return true, writeSecretsStore(key, secrets)
+}
+
+const (
+	secretsStoreFile  = "secrets.enc"
+	defaultSecretsKey = "secret.key"
+)
+
+type secretsEnvelope struct {
+	Version int    `json:"version"`
+	Nonce   string `json:"nonce"`      // base64
+	Data    string `json:"ciphertext"` // base64, the secrets as a JSON object
+}
+
+func secretsKeyFile() string {
+	return getEnvFromFile(readPlainEnvFile(), "SECRETS_KEY_FILE", defaultSecretsKey)
+}
+
+// Store key, or nil when none is configured
+func secretsKey() ([]byte, error) {
+	encoded, source, err := secretsKeySource()
+	if err != nil || source == "" {
+		return nil, err
+	}
+	return decodeSecretsKey(encoded)
+}
+
+// Encoded store key and where it came from (SourceEnv, SourceCredential or SourceEnvFile
+// for the key file; "" without a key). SECRETS_KEY is only read from the environment or
+// a systemd credential: in .env it would sit in plain text next to what it protects.
+func secretsKeySource() (string, string, error) {
+	if encoded := os.Getenv("SECRETS_KEY"); encoded != "" {
+		return encoded, SourceEnv, nil
+	}
+	if encoded, _ := readCredential("SECRETS_KEY"); encoded != "" {
+		return encoded, SourceCredential, nil
+	}
+	data, err := os.ReadFile(secretsKeyFile())
+	if os.IsNotExist(err) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+	return string(data), SourceEnvFile, nil
+}
+
+func decodeSecretsKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("secrets key must be 32 bytes, base64-encoded")
+	}
+	return key, nil
+}
+
+func newSecretsKey() ([]byte, error) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	return key, err
+}
+
+// Decrypted secrets (nil without a store)
+func readSecretsStore() (map[string]string, error) {
+	data, err := os.ReadFile(secretsStoreFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	key, err := secretsKey()
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, fmt.Errorf("%s exists but no key is configured (SECRETS_KEY or %s)", secretsStoreFile, secretsKeyFile())
+	}
+	return decryptSecrets(key, data)
+}
+
+func decryptSecrets(key, data []byte) (map[string]string, error) {
+	var envelope secretsEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("malformed %s: %w", secretsStoreFile, err)
+	}
+	if envelope.Version != 1 {
+		return nil, fmt.Errorf("unsupported %s version %d", secretsStoreFile, envelope.Version)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(envelope.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(envelope.Data)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := secretsCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("malformed %s: bad nonce", secretsStoreFile)
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt %s: wrong key or corrupted file", secretsStoreFile)
+	}
+	secrets := make(map[string]string)
+	return secrets, json.Unmarshal(plaintext, &secrets)
+}
+
+// Encrypt with a fresh nonce and replace the store atomically
+func writeSecretsStore(key []byte, secrets map[string]string) error {
+	data, err := encryptSecrets(key, secrets)
+	if err != nil {
+		return err
+	}
+	tmp := secretsStoreFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, secretsStoreFile)
+}
+
+// Seal the secrets into a store envelope with a fresh nonce
+func encryptSecrets(key []byte, secrets map[string]string) ([]byte, error) {
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := secretsCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(secretsEnvelope{
+		Version: 1,
+		Nonce:   base64.StdEncoding.EncodeToString(nonce),
+		Data:    base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, plaintext, nil)),
+	}, "", "  ")
+}
+
+func secretsCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func warnPlaintextSecrets() {
+	if key, err := secretsKey(); err != nil || key != nil {
+		return
+	}
+	plain := readPlainEnvFile()
+	for _, k := range secretEnvKeys {
+		if plain[k] != "" {
+			log.Printf("🔓 Secrets are stored in plain text in .env; run newslettar -secrets migrate to encrypt them")
+			return
+		}
+	}
+	for _, p := range loadProfiles() {
+		if p.Transport.Pass != "" {
+			log.Printf("🔓 SMTP passwords are stored in plain text in profiles.json; run newslettar -secrets migrate to encrypt them")
+			return
+		}
+	}
+}
+
+// -secrets migrate|rotate
+func runSecretsCommand(command string) error {
+	switch command {
+	case "migrate":
+		return migrateSecrets()
+	case "rotate":
+		return rotateSecretsKey()
+	}
+	return fmt.Errorf("unknown secrets command %q (use migrate or rotate)", command)
+}
+
+// Move the secrets out of .env into the store, creating the key file if there is no key yet
+func migrateSecrets() error {
+	key, err := secretsKey()
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		if key, err = newSecretsKey(); err != nil {
+			return err
+		}
+		if err := os.WriteFile(secretsKeyFile(), []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600); err != nil {
+			return err
+		}
+		fmt.Printf("🔑 Created key file %s (keep it out of backups shared with .env)\n", secretsKeyFile())
+	}
+
+	secrets, err := readSecretsStore()
+	if err != nil {
+		return err
+	}
+	if secrets == nil {
+		secrets = make(map[string]string)
+	}
+	plain := readPlainEnvFile()
+	moved := 0
+	for _, k := range secretEnvKeys {
+		if v, ok := plain[k]; ok {
+			secrets[k] = v
+			moved++
+		}
+	}
+	merged := make(map[string]string)
+	for k, v := range plain {
+		merged[k] = v
+	}
+	for k, v := range secrets {
+		merged[k] = v
+	}
+
+	// The store has to be in place before .env loses the secrets
+	secretsMu.Lock()
+	err = writeSecretsStore(key, secrets)
+	secretsMu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := writeEnvFile(merged); err != nil {
+		return err
+	}
+
+	// Profile SMTP passwords move when the profiles are saved with the store enabled
+	profilesMu.Lock()
+	profiles := loadProfilesLocked()
+	err = saveProfilesLocked(profiles)
+	profilesMu.Unlock()
+	if err != nil {
+		return err
+	}
+	for _, p := range profiles {
+		if p.Transport.Pass != "" && secrets[profileSecretKey(p.ID)] == "" {
+			moved++
+		}
+	}
+	fmt.Printf("✅ Moved %d secret(s) from .env and profiles.json into %s\n", moved, secretsStoreFile)
+	return nil
+}
+
+// Re-encrypt the store with a new key. With a key file, the new key replaces it; with
+// SECRETS_KEY or a SECRETS_KEY credential the new key is printed and has to be set in
+// its place. Stop the service first: a running server keeps encrypting with the old key.
+func rotateSecretsKey() error {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+
+	_, source, err := secretsKeySource()
+	if err != nil {
+		return err
+	}
+	secrets, err := readSecretsStore()
+	if err != nil {
+		return err
+	}
+	if secrets == nil {
+		return fmt.Errorf("no %s to rotate, run -secrets migrate first", secretsStoreFile)
+	}
+	key, err := newSecretsKey()
+	if err != nil {
+		return err
+	}
+	encoded := base64.StdEncoding.EncodeToString(key)
+
+	switch source {
+	case SourceEnv:
+		if err := writeSecretsStore(key, secrets); err != nil {
+			return err
+		}
+		fmt.Printf("✅ %s re-encrypted. Set SECRETS_KEY to the new key before restarting:\n%s\n", secretsStoreFile, encoded)
+		return nil
+	case SourceCredential:
+		if err := writeSecretsStore(key, secrets); err != nil {
+			return err
+		}
+		fmt.Printf("✅ %s re-encrypted. Replace the SECRETS_KEY credential (the file named in LoadCredential=) with the new key before restarting:\n%s\n", secretsStoreFile, encoded)
+		return nil
+	}
+
+	// New key next to the old one first: if anything fails midway, neither is lost
+	keyFile := secretsKeyFile()
+	if err := os.WriteFile(keyFile+".new", []byte(encoded+"\n"), 0600); err != nil {
+		return err
+	}
+	if err := writeSecretsStore(key, secrets); err != nil {
+		return err
+	}
+	if err := os.Rename(keyFile+".new", keyFile); err != nil {
+		return fmt.Errorf("store re-encrypted but the new key is still in %s.new: %w", keyFile, err)
+	}
+	fmt.Printf("✅ %s re-encrypted with a new key in %s\n", secretsStoreFile, keyFile)
+	return nil
+}
+
+func getEnvFromFile(envMap map[string]string, key, defaultValue string) string {
+	if val, _, ok := lookupSetting(envMap, key); ok {
+		return val
+	}
+	return defaultValue
+}
+
+// Where a setting came from, in order of precedence
+const (
+	SourceFlag       = "flag"       // -setting KEY=VALUE
+	SourceEnv        = "env"        // environment variable KEY
+	SourceEnvFile    = "env_file"   // file named by KEY_FILE (Docker secrets)
+	SourceCredential = "credential" // $CREDENTIALS_DIRECTORY/KEY (systemd credentials)
+	SourceDotenv     = "dotenv"     // .env
+	SourceSecrets    = "secrets"    // encrypted secrets store
+	SourceDefault    = "default"
+)
+
+// Settings given on the command line, ahead of everything else
+var flagSettings = settingFlags{}
+
+type settingFlags map[string]string
+
+func (s settingFlags) String() string { return "" }
+
+func (s settingFlags) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || strings.TrimSpace(key) == "" {
+		return fmt.Errorf("expected KEY=VALUE")
+	}
+	s[strings.TrimSpace(key)] = val
+	return nil
+}
+
+// Value and source of a setting: flags, then the environment (KEY, KEY_FILE,
+// systemd credentials), then .env and the secrets store. ok is false when
+// the default applies.
+func lookupSetting(envMap map[string]string, key string) (value, source string, ok bool) {
+	if val, exists := flagSettings[key]; exists {
+		return val, SourceFlag, true
+	}
+	if val := os.Getenv(key); val != "" {
+		return val, SourceEnv, true
+	}
+	if path := os.Getenv(key + "_FILE"); path != "" {
+		val, err := readSecretFile(path)
+		if err == nil {
+			return val, SourceEnvFile, true
+		}
+		log.Printf("⚠️  Cannot read %s_FILE: %v", key, err)
+	}
+	if val, err := readCredential(key); err == nil {
+		return val, SourceCredential, true
+	}
+	if val, exists := envMap[key]; exists {
+		return val, SourceDotenv, true
+	}
+	return "", SourceDefault, false
+}
+
+// Contents of a secret file, without the trailing newline editors and echo add
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// Credential passed by systemd (LoadCredential=KEY:/path)
+func readCredential(key string) (string, error) {
+	dir := os.Getenv("CREDENTIALS_DIRECTORY")
+	if dir == "" {
+		return "", os.ErrNotExist
+	}
+	return readSecretFile(filepath.Join(dir, key))
+}
+
+// Source of each config API field, telling .env and the secrets store apart
+func configSources(envMap map[string]string) map[string]string {
+	plain := readPlainEnvFile()
+	sources := make(map[string]string)
+	for field, key := range configEnvKeys {
+		_, source, _ := lookupSetting(envMap, key)
+		if _, inPlain := plain[key]; source == SourceDotenv && !inPlain {
+			source = SourceSecrets
+		}
+		sources[field] = source
+	}
+	return sources
+}
+
+// Whether a source takes precedence over what the web UI saves
+func overridesSavedSettings(source string) bool {
+	switch source {
+	case SourceFlag, SourceEnv, SourceEnvFile, SourceCredential:
+		return true
+	}
+	return false
+}
+
+// Updated fetch functions to accept context and use RequestWithContext
+func fetchSonarrHistory(ctx context.Context, cfg *Config, since time.Time) ([]Episode, error) {
+	if cfg.SonarrURL == "" || cfg.SonarrAPIKey == "" {
+		return nil, fmt.Errorf("Sonarr not configured")
+	}
+
+	url := fmt.Sprintf("%s/api/v3/history?pageSize=1000&sortKey=date&sortDirection=descending&includeEpisode=true&includeSeries=true", cfg.SonarrURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Api-Key", cfg.SonarrAPIKey)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+	}
+
+	// Stream JSON decoding (faster, less memory)
+	var result struct {
+		Records []struct {
+			Date      time.Time `json:"date"`
+			EventType string    `json:"eventType"`
+			Series    struct {
+				Title         string `json:"title"`
+				TvdbID        int    `json:"tvdbId"`
+				ImdbID        string `json:"imdbId"`
+				Certification string `json:"certification"`
+				Tags          []int  `json:"tags"`
+				Images        []struct {
+					CoverType string `json:"coverType"`
+					RemoteURL string `json:"remoteUrl"`
+				} `json:"images"`
+			} `json:"series"`
+			Episode struct {
+				SeasonNumber  int       `json:"seasonNumber"`
+				EpisodeNumber int       `json:"episodeNumber"`
+				Title         string    `json:"title"`
+				AirDate       string    `json:"airDate"`
+				AirDateUtc    time.Time `json:"airDateUtc"`
+			} `json:"episode"`
+		} `json:"records"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	tags := fetchTagMap(ctx, cfg.SonarrURL, cfg.SonarrAPIKey)
+	loc := getTimezone(cfg.Timezone)
+
+	episodes := []Episode{}
+	for _, record := range result.Records {
+		// Only include download events
+		if record.EventType != "downloadFolderImported" && record.EventType != "downloadImported" {
+			continue
+		}
+
+		// Filter by date
+		if record.Date.Before(since) {
+			continue
+		}
+
+		posterURL := ""
+		for _, img := range record.Series.Images {
+			if img.CoverType == "poster" {
+				posterURL = img.RemoteURL
+				break
+			}
+		}
+
+		episodes = append(episodes, localizeEpisode(Episode{
+			SeriesTitle: record.Series.Title,
+			SeasonNum:   record.Episode.SeasonNumber,
+			EpisodeNum:  record.Episode.EpisodeNumber,
+			Title:       record.Episode.Title,
+			AirDate:     record.Episode.AirDate,
+			AirTime:     record.Episode.AirDateUtc,
+			Downloaded:  true,
+			ImportedAt:  record.Date,
+			PosterURL:   posterURL,
+			IMDBID:      record.Series.ImdbID,
+			TvdbID:      record.Series.TvdbID,
+			Rating:      record.Series.Certification,
+			Tags:        tagLabels(tags, record.Series.Tags),
+		}, loc))
+	}
+
+	return episodes, nil
+}
+
+func fetchSonarrCalendar(ctx context.Context, cfg *Config, start, end time.Time) ([]Episode, error) {
+	url := fmt.Sprintf("%s/api/v3/calendar?unmonitored=true&includeSeries=true&includeEpisodeImages=true&start=%s&end=%s",
+		cfg.SonarrURL, start.Format("2006-01-02"), end.Format("2006-01-02"))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Api-Key", cfg.SonarrAPIKey)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
+	}
+
+	// Stream-decode JSON to save memory
+	var calendar []CalendarEpisode
+	decoder := json.NewDecoder(resp.Body)
+	if err := decoder.Decode(&calendar); err != nil {
+		return nil, err
+	}
+
+	tags := fetchTagMap(ctx, cfg.SonarrURL, cfg.SonarrAPIKey)
+	loc := getTimezone(cfg.Timezone)
+
+	// Map to Episode struct
+	var episodes []Episode
+	for _, entry := range calendar {
+		posterURL := ""
+		for _, img := range entry.Series.Images {
+			if img.CoverType == "poster" {
+				if img.Url != "" {
+					posterURL = img.Url
+				} else if img.RemoteUrl != "" {
+					posterURL = img.RemoteUrl
+				}
+				break
+			}
+		}
+
+		ep := Episode{
+			SeriesTitle: entry.Series.Title,
+			SeasonNum:   entry.SeasonNumber,
+			EpisodeNum:  entry.EpisodeNumber,
+			Title:       entry.Title,
+			AirDate:     entry.AirDate,
+			AirTime:     entry.AirDateUtc,
+			Runtime:     entry.Runtime,
+			PosterURL:   posterURL,
+			IMDBID:      entry.Series.ImdbId,
+			TvdbID:      entry.Series.TvdbId,
+			Rating:      entry.Series.Certification,
+			Tags:        tagLabels(tags, entry.Series.Tags),
+		}
+
+		if ep.Runtime == 0 {
+			ep.Runtime = entry.Series.Runtime
+		}
+
+		if ep.AirDate != "" {
+			airDate, _ := time.Parse("2006-01-02", ep.AirDate)
+			ep.AirDate = airDate.Format("2006-01-02")
+		}
+		ep = localizeEpisode(ep, loc)
+
+		episodes = append(episodes, ep)
+	}
+
+	return episodes, nil
+}
+
+func fetchRadarrHistory(ctx context.Context, cfg *Config, since time.Time) ([]Movie, error) {
+	if cfg.RadarrURL == "" || cfg.RadarrAPIKey == "" {
+		return nil, fmt.Errorf("Radarr not configured")
+	}
+
+	url := fmt.Sprintf("%s/api/v3/history?pageSize=1000&sortKey=date&sortDirection=descending&includeMovie=true", cfg.RadarrURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Api-Key", cfg.RadarrAPIKey)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Records []struct {
+			Date      time.Time `json:"date"`
+			EventType string    `json:"eventType"`
+			Movie     struct {
+				Title  string `json:"title"`
+				Year   int    `json:"year"`
+				TmdbID int    `json:"tmdbId"`
+				ImdbID string `json:"imdbId"`
+				RadarrReleases
+				Certification string `json:"certification"`
+				Tags          []int  `json:"tags"`
+				Images        []struct {
+					CoverType string `json:"coverType"`
+					RemoteURL string `json:"remoteUrl"`
+				} `json:"images"`
+			} `json:"movie"`
+		} `json:"records"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	tags := fetchTagMap(ctx, cfg.RadarrURL, cfg.RadarrAPIKey)
+
+	movies := []Movie{}
+	for _, record := range result.Records {
+		// Only include download events
+		if record.EventType != "downloadFolderImported" && record.EventType != "downloadImported" {
+			continue
+		}
+
+		// Filter by date
+		if record.Date.Before(since) {
+			continue
+		}
+
+		posterURL := ""
+		for _, img := range record.Movie.Images {
+			if img.CoverType == "poster" {
+				posterURL = img.RemoteURL
+				break
+			}
+		}
+
+		// The release that made the download possible: the latest one before the import
+		var releaseType, releaseDate string
+		for _, t := range allReleaseTypes {
+			if d := record.Movie.date(t); d != "" && d <= record.Date.Format("2006-01-02") && d >= releaseDate {
+				releaseType, releaseDate = t, d
+			}
+		}
+
+		movies = append(movies, Movie{
+			Title:       record.Movie.Title,
+			Year:        record.Movie.Year,
+			ReleaseDate: releaseDate,
+			ReleaseType: releaseType,
+			Downloaded:  true,
+			ImportedAt:  record.Date,
+			PosterURL:   posterURL,
+			IMDBID:      record.Movie.ImdbID,
+			TmdbID:      record.Movie.TmdbID,
+			Rating:      record.Movie.Certification,
+			Tags:        tagLabels(tags, record.Movie.Tags),
+		})
+	}
+
+	return movies, nil
+}
+
+// Movies with a release of one of releaseTypes between start and end; Radarr's calendar
+// also returns movies whose other release types fall in the range, those are dropped
+func fetchRadarrCalendar(ctx context.Context, cfg *Config, start, end time.Time, releaseTypes []string) ([]Movie, error) {
+	url := fmt.Sprintf("%s/api/v3/calendar?unmonitored=true&includeMovie=true&start=%s&end=%s",
+		cfg.RadarrURL, start.Format("2006-01-02"), end.Format("2006-01-02"))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Api-Key", cfg.RadarrAPIKey)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
+	}
+
+	// Stream-decode JSON to save memory
+	var calendar []CalendarMovie
+	decoder := json.NewDecoder(resp.Body)
+	if err := decoder.Decode(&calendar); err != nil {
+		return nil, err
+	}
+
+	tags := fetchTagMap(ctx, cfg.RadarrURL, cfg.RadarrAPIKey)
+
+	// Map to Movie struct
+	var movies []Movie
+	for _, entry := range calendar {
+		posterURL := ""
+		for _, img := range entry.Images {
+			if img.CoverType == "poster" {
+				if img.Url != "" {
+					posterURL = img.Url
+				} else if img.RemoteUrl != "" {
+					posterURL = img.RemoteUrl
+				}
+				break
+			}
+		}
+
+		releaseType, releaseDate := entry.firstBetween(releaseTypes, start.Format("2006-01-02"), end.Format("2006-01-02"))
+		if releaseType == "" {
+			continue
+		}
+
+		movies = append(movies, Movie{
+			Title:       entry.Title,
+			Year:        entry.Year,
+			ReleaseDate: releaseDate,
+			ReleaseType: releaseType,
+			PosterURL:   posterURL,
+			IMDBID:      entry.ImdbId,
+			TmdbID:      entry.TmdbId,
+			Rating:      entry.Certification,
+			Tags:        tagLabels(tags, entry.Tags),
+		})
+	}
+
+	return movies, nil
+}
+
+// Tag labels per *arr instance, cached briefly so parallel fetches share one lookup
+var (
+	tagCache   = make(map[string]tagCacheEntry)
+	tagCacheMu sync.Mutex
+)
+
+type tagCacheEntry struct {
+	labels  map[int]string
+	fetched time.Time
+}
+
+const tagCacheTTL = 5 * time.Minute
+
+// Fetch tag id => label for a Sonarr/Radarr instance (errors only logged, tags are optional)
+func fetchTagMap(ctx context.Context, baseURL, apiKey string) map[int]string {
+	tagCacheMu.Lock()
+	if entry, ok := tagCache[baseURL]; ok && time.Since(entry.fetched) < tagCacheTTL {
+		tagCacheMu.Unlock()
+		return entry.labels
+	}
+	tagCacheMu.Unlock()
+
+	labels := make(map[int]string)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/api/v3/tag", nil)
+	if err != nil {
+		return labels
+	}
+	req.Header.Set("X-Api-Key", apiKey)
+
+	logger := jobFromContext(ctx).logger()
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		logger.Warn("⚠️  Failed to fetch tags", "url", baseURL, "error", err)
+		return labels
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		logger.Warn("⚠️  Failed to fetch tags", "url", baseURL, "status", resp.StatusCode)
+		return labels
+	}
+
+	var tags []struct {
+		ID    int    `json:"id"`
+		Label string `json:"label"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		logger.Warn("⚠️  Failed to decode tags", "url", baseURL, "error", err)
+		return labels
+	}
+	for _, t := range tags {
+		labels[t.ID] = t.Label
+	}
+
+	tagCacheMu.Lock()
+	tagCache[baseURL] = tagCacheEntry{labels: labels, fetched: time.Now()}
+	tagCacheMu.Unlock()
+
+	return labels
+}
+
+func tagLabels(labels map[int]string, ids []int) []string {
+	var result []string
+	for _, id := range ids {
+		if label, ok := labels[id]; ok {
+			result = append(result, label)
+		}
+	}
+	return result
+}
+
+// Group episodes by series
+func groupEpisodesBySeries(episodes []Episode) []SeriesGroup {
+	seriesMap := make(map[string]*SeriesGroup)
+
+	// Sort episodes by air date first
+	sort.Slice(episodes, func(i, j int) bool {
+		return episodes[i].AirDate < episodes[j].AirDate
+	})
+
+	for _, ep := range episodes {
+		group, exists := seriesMap[ep.SeriesTitle]
+		if !exists {
+			group = &SeriesGroup{
+				SeriesTitle: ep.SeriesTitle,
+				PosterURL:   ep.PosterURL,
+				Episodes:    []Episode{},
+				IMDBID:      ep.IMDBID,
+				TvdbID:      ep.TvdbID,
+				Rating:      ep.Rating,
+				Tags:        ep.Tags,
+			}
+			seriesMap[ep.SeriesTitle] = group
+		}
+
+		// Check for duplicate episodes (same season and episode number)
+		isDuplicate := false
+		for _, existingEp := range group.Episodes {
+			if existingEp.SeasonNum == ep.SeasonNum && existingEp.EpisodeNum == ep.EpisodeNum {
+				isDuplicate = true
+				break
+			}
+		}
+
+		// Only add if not a duplicate
+		if !isDuplicate {
+			group.Episodes = append(group.Episodes, ep)
+		}
+	}
+
+	groups := make([]SeriesGroup, 0, len(seriesMap))
+	for _, group := range seriesMap {
+		groups = append(groups, *group)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].SeriesTitle < groups[j].SeriesTitle
+	})
+
+	return groups
+}
+
+// Generate newsletter HTML using the profile's template and sections
+func generateNewsletterHTML(p Profile, data NewsletterData) (string, error) {
+	tmpl, err := getEmailTemplate(p.Template)
+	if err != nil {
+		return "", err
+	}
+
+	templateData := struct {
+		NewsletterData
+		ShowPosters          bool
+		ShowDownloaded       bool
+		ShowUpcomingSeries   bool
+		ShowUpcomingMovies   bool
+		ShowDownloadedSeries bool
+		ShowDownloadedMovies bool
+	}{
+		NewsletterData:       data,
+		ShowPosters:          p.ShowPosters,
+		ShowDownloaded:       p.hasSection(SectionDownloadedSeries) || p.hasSection(SectionDownloadedMovies),
+		ShowUpcomingSeries:   p.hasSection(SectionUpcomingSeries),
+		ShowUpcomingMovies:   p.hasSection(SectionUpcomingMovies),
+		ShowDownloadedSeries: p.hasSection(SectionDownloadedSeries),
+		ShowDownloadedMovies: p.hasSection(SectionDownloadedMovies),
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, templateData); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// Resolve a profile template: custom files in data/templates override the embedded ones
+func getEmailTemplate(name string) (*template.Template, error) {
+	name = filepath.Base(name)
+	if name == "" || name == "." || name == "/" {
+		name = "email.html"
+	}
+
+	custom := filepath.Join(getConfig().DataDir, "templates", name)
+	if _, err := os.Stat(custom); err == nil {
+		return template.New(name).Funcs(templateFuncs).ParseFiles(custom)
+	}
+	if name == "email.html" {
+		return emailTemplate, nil
+	}
+	return template.New(name).Funcs(templateFuncs).ParseFS(templateFS, "templates/"+name)
+}
+
+// Template names offered in the UI (embedded plus data/templates)
+func listEmailTemplates() []string {
+	seen := make(map[string]bool)
+	names := []string{}
+
+	embedded, _ := templateFS.ReadDir("templates")
+	custom, _ := os.ReadDir(filepath.Join(getConfig().DataDir, "templates"))
+	for _, entry := range append(embedded, custom...) {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasSuffix(name, ".html") && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Move an episode's air time into loc. Sonarr's airDate is the network's local
+// date, so a late-night US airing can fall on the next day elsewhere.
+func localizeEpisode(ep Episode, loc *time.Location) Episode {
+	if ep.AirTime.IsZero() {
+		return ep
+	}
+	ep.AirTime = ep.AirTime.In(loc)
+	ep.AirDate = ep.AirTime.Format("2006-01-02")
+	return ep
+}
+
+// Re-localize every episode in data for a recipient in another timezone
+func localizeNewsletter(data NewsletterData, loc *time.Location) NewsletterData {
+	localize := func(groups []SeriesGroup) []SeriesGroup {
+		out := make([]SeriesGroup, len(groups))
+		for i, g := range groups {
+			episodes := make([]Episode, len(g.Episodes))
+			for j, ep := range g.Episodes {
+				episodes[j] = localizeEpisode(ep, loc)
+			}
+			g.Episodes = episodes
+			out[i] = g
+		}
+		return out
+	}
+
+	data.UpcomingSeriesGroups = localize(data.UpcomingSeriesGroups)
+	data.DownloadedSeriesGroups = localize(data.DownloadedSeriesGroups)
+	data.FollowedSeriesGroups = localize(data.FollowedSeriesGroups)
+	return data
+}
+
+// Label for a movie release type in emails and calendars
+func releaseLabel(releaseType string) string {
+	switch releaseType {
+	case ReleaseCinema:
+		return "🎟️ In cinemas"
+	case ReleaseDigital:
+		return "💻 Digital release"
+	case ReleasePhysical:
+		return "📀 Physical release"
+	}
+	return ""
+}
+
+// Date with weekday, plus the time of day when an exact air time is known
+func formatDateWithDay(dateStr string, airTime ...time.Time) string {
+	if len(airTime) > 0 && !airTime[0].IsZero() {
+		return airTime[0].Format("Monday, January 2, 2006 · 3:04 PM MST")
+	}
+
+	if dateStr == "" {
+		return "Date TBA"
+	}
+
+	t, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		return dateStr
+	}
+
+	return t.Format("Monday, January 2, 2006")
+}
+
+// Recipients of a profile: its direct addresses plus the confirmed subscribers it targets.
+// An address listed both ways gets one copy, with the subscriber's filters: direct
+// addresses have none, and a filtered subscriber must not get the unfiltered newsletter.
+func getRecipients(cfg *Config, p Profile) []Recipient {
+	index := make(map[string]int)
+	recipients := []Recipient{}
+
+	add := func(rcpt Recipient, replace bool) {
+		key := strings.ToLower(rcpt.Email)
+		if rcpt.Email == "" {
+			return
+		}
+		if i, seen := index[key]; seen {
+			if replace {
+				recipients[i] = rcpt
+			}
+			return
+		}
+		index[key] = len(recipients)
+		recipients = append(recipients, rcpt)
+	}
+
+	groups := make(map[string]Group)
+	for _, g := range loadGroups() {
+		groups[strings.ToLower(g.Name)] = g
+	}
+
+	for _, email := range p.Recipients {
+		add(Recipient{Email: email}, false)
+	}
+	for _, sub := range loadSubscribers() {
+		if sub.Status != "active" || !profileTargets(p, sub) {
+			continue
+		}
+
+		rcpt := Recipient{
+			Email:          sub.Email,
+			MaxRating:      sub.MaxRating,
+			AllowUnrated:   sub.AllowUnrated,
+			FollowedSeries: sub.FollowedSeries,
+			FollowedMovies: sub.FollowedMovies,
+			MutedSeries:    sub.MutedSeries,
+			MutedMovies:    sub.MutedMovies,
+			Timezone:       sub.Timezone,
+		}
+		if cfg.PublicURL != "" && sub.PrefsToken != "" {
+			rcpt.PreferencesURL = cfg.PublicURL + "/preferences?token=" + sub.PrefsToken
+		}
+		for _, name := range sub.Groups {
+			g, ok := groups[strings.ToLower(name)]
+			if !ok {
+				log.Printf("⚠️  %s belongs to unknown group '%s'", sub.Email, name)
+				continue
+			}
+			rcpt.Tags = append(rcpt.Tags, g.Tags...)
+			rcpt.IncludeUntagged = rcpt.IncludeUntagged || g.IncludeUntagged
+			// The strictest rating limit wins
+			if g.MaxRating != "" && (rcpt.MaxRating == "" || ratingLevel(g.MaxRating) < ratingLevel(rcpt.MaxRating)) {
+				rcpt.MaxRating = g.MaxRating
+			}
+		}
+		add(rcpt, true)
+	}
+
+	return recipients
+}
+
+func profileTargets(p Profile, sub Subscriber) bool {
+	if p.AllSubscribers {
+		return true
+	}
+	for _, want := range p.Groups {
+		for _, name := range sub.Groups {
+			if strings.EqualFold(want, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Certification levels shared by US movie (MPAA), US TV and UK (BBFC) ratings.
+// Unknown certifications are treated as unrated.
+var ratingLevels = map[string]int{
+	"G": 1, "TV-Y": 1, "TV-G": 1, "U": 1,
+	"TV-Y7": 2, "TV-Y7-FV": 2,
+	"PG": 3, "TV-PG": 3,
+	"PG-13": 4, "TV-14": 4, "12": 4, "12A": 4,
+	"R": 5, "TV-MA": 5, "15": 5,
+	"NC-17": 6, "18": 6, "R18": 6,
+}
+
+func ratingLevel(rating string) int {
+	if level, ok := ratingLevels[strings.ToUpper(strings.TrimSpace(rating))]; ok {
+		return level
+	}
+	return 0
+}
+
+// Whether a title with the given certification may be shown under maxRating
+func ratingAllowed(rating, maxRating string, allowUnrated bool) bool {
+	if maxRating == "" {
+		return true
+	}
+	level := ratingLevel(rating)
+	if level == 0 {
+		return allowUnrated
+	}
+	return level <= ratingLevel(maxRating)
+}
+
+// Whether an item's tags match the recipient's group tags
+func tagsAllowed(itemTags []string, rcpt Recipient) bool {
+	if len(rcpt.Tags) == 0 {
+		return true
+	}
+	if len(itemTags) == 0 {
+		return rcpt.IncludeUntagged
+	}
+	for _, tag := range itemTags {
+		for _, want := range rcpt.Tags {
+			if strings.EqualFold(tag, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsInt(values []int, v int) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply a recipient's filters: rating limits always apply, muted titles are dropped,
+// followed titles move to the "Followed" section (regardless of group tags)
+func filterNewsletterForRecipient(data NewsletterData, rcpt Recipient) NewsletterData {
+	var followedEpisodes []Episode
+	var followedMovies []Movie
+
+	filterGroups := func(groups []SeriesGroup) []SeriesGroup {
+		kept := []SeriesGroup{}
+		for _, g := range groups {
+			if containsInt(rcpt.MutedSeries, g.TvdbID) || !ratingAllowed(g.Rating, rcpt.MaxRating, rcpt.AllowUnrated) {
+				continue
+			}
+			if containsInt(rcpt.FollowedSeries, g.TvdbID) {
+				followedEpisodes = append(followedEpisodes, g.Episodes...)
+				continue
+			}
+			if tagsAllowed(g.Tags, rcpt) {
+				kept = append(kept, g)
+			}
+		}
+		return kept
+	}
+	filterMovies := func(movies []Movie) []Movie {
+		kept := []Movie{}
+		for _, m := range movies {
+			if containsInt(rcpt.MutedMovies, m.TmdbID) || !ratingAllowed(m.Rating, rcpt.MaxRating, rcpt.AllowUnrated) {
+				continue
+			}
+			if containsInt(rcpt.FollowedMovies, m.TmdbID) {
+				followedMovies = append(followedMovies, m)
+				continue
+			}
+			if tagsAllowed(m.Tags, rcpt) {
+				kept = append(kept, m)
+			}
+		}
+		return kept
+	}
+
+	data.UpcomingSeriesGroups = filterGroups(data.UpcomingSeriesGroups)
+	data.UpcomingMovies = filterMovies(data.UpcomingMovies)
+	data.DownloadedSeriesGroups = filterGroups(data.DownloadedSeriesGroups)
+	data.DownloadedMovies = filterMovies(data.DownloadedMovies)
+
+	data.FollowedSeriesGroups = nil
+	if len(followedEpisodes) > 0 {
+		data.FollowedSeriesGroups = groupEpisodesBySeries(followedEpisodes)
+	}
+	data.FollowedMovies = followedMovies
+	return data
+}
+
+// Disabled sections are never fetched, so any item counts as content
+func newsletterIsEmpty(data NewsletterData) bool {
+	return len(data.UpcomingSeriesGroups) == 0 && len(data.UpcomingMovies) == 0 &&
+		len(data.DownloadedSeriesGroups) == 0 && len(data.DownloadedMovies) == 0 &&
+		len(data.FollowedSeriesGroups) == 0 && len(data.FollowedMovies) == 0
+}
+
+// Send email
+// File attached to an email
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+func sendEmail(t SMTPTransport, to []string, subject, htmlBody string, attachments ...Attachment) (err error) {
+	defer func() {
+		result := DeliverySent
+		if err != nil {
+			result = DeliveryFailed
+		}
+		emailsTotal.inc(t.Host+":"+t.Port, result)
+	}()
+
+	if t.FromEmail == "" || len(to) == 0 {
+		return fmt.Errorf("email configuration incomplete")
+	}
+
+	from := t.FromEmail
+	if t.FromName != "" {
+		from = fmt.Sprintf("%s <%s>", t.FromName, t.FromEmail)
+	}
+
+	headers := make(map[string]string)
+	headers["From"] = from
+	headers["To"] = strings.Join(to, ", ")
+	headers["Subject"] = subject
+	headers["MIME-Version"] = "1.0"
+	headers["Content-Type"] = "text/html; charset=UTF-8"
+
+	body := htmlBody
+	if len(attachments) > 0 {
+		var contentType string
+		body, contentType, err = multipartBody(htmlBody, attachments)
+		if err != nil {
+			return err
+		}
+		headers["Content-Type"] = contentType
+	}
+
+	message := ""
+	for k, v := range headers {
+		message += fmt.Sprintf("%s: %s\r\n", k, v)
+	}
+	message += "\r\n" + body
+
+	auth := smtp.PlainAuth("", t.User, t.Pass, t.Host)
+	addr := fmt.Sprintf("%s:%s", t.Host, t.Port)
+
+	return smtp.SendMail(addr, auth, t.FromEmail, to, []byte(message))
+}
+
+// multipart/mixed body: the HTML newsletter followed by base64 attachments
+func multipartBody(htmlBody string, attachments []Attachment) (string, string, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	part, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/html; charset=UTF-8"}})
+	if err != nil {
+		return "", "", err
+	}
+	part.Write([]byte(htmlBody))
+
+	for _, a := range attachments {
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {fmt.Sprintf("%s; name=%q", a.ContentType, a.Filename)},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", a.Filename)},
+		})
+		if err != nil {
+			return "", "", err
+		}
+		encoded := base64.StdEncoding.EncodeToString(a.Data)
+		for len(encoded) > 76 {
+			part.Write([]byte(encoded[:76] + "\r\n"))
+			encoded = encoded[76:]
+		}
+		part.Write([]byte(encoded + "\r\n"))
+	}
+
+	if err := mw.Close(); err != nil {
+		return "", "", err
+	}
+	return buf.String(), "multipart/mixed; boundary=" + mw.Boundary(), nil
+}
+
+// Resolve a file inside the data directory (created on first use)
+func dataPath(name string) string {
+	dir := getConfig().DataDir
+	if dir == "" {
+		dir = "data"
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		log.Printf("⚠️  Failed to create data directory %s: %v", dir, err)
+	}
+	return filepath.Join(dir, name)
+}
+
+// Read a JSON file from the data directory (missing file leaves v untouched)
+func readJSONFile(name string, v interface{}) error {
+	data, err := os.ReadFile(dataPath(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Write a JSON file atomically (temp file + rename) into the data directory
+func writeJSONFile(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := dataPath(name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func loadSubscribers() []Subscriber {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+	return loadSubscribersLocked()
+}
+
+func loadSubscribersLocked() []Subscriber {
+	var subs []Subscriber
+	if err := readJSONFile("subscribers.json", &subs); err != nil {
+		log.Printf("⚠️  Failed to read subscribers: %v", err)
+	}
+	return subs
+}
+
+func saveSubscribersLocked(subs []Subscriber) error {
+	return writeJSONFile("subscribers.json", subs)
+}
+
+func loadGroups() []Group {
+	groupsMu.Lock()
+	defer groupsMu.Unlock()
+
+	var groups []Group
+	if err := readJSONFile("groups.json", &groups); err != nil {
+		log.Printf("⚠️  Failed to read groups: %v", err)
+	}
+	return groups
+}
+
+func saveGroups(groups []Group) error {
+	groupsMu.Lock()
+	defer groupsMu.Unlock()
+	return writeJSONFile("groups.json", groups)
+}
+
+// Profiles in display order; the first one is the default for manual sends
+func loadProfiles() []Profile {
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+	return loadProfilesLocked()
+}
+
+func loadProfilesLocked() []Profile {
+	var profiles []Profile
+	if err := readJSONFile("profiles.json", &profiles); err != nil {
+		log.Printf("⚠️  Failed to read profiles: %v", err)
+	}
+
+	// SMTP passwords kept in the encrypted store
+	secrets, err := readSecretsStore()
+	if err != nil {
+		log.Printf("❌ Cannot read the secrets store: %v", err)
+	}
+	for i := range profiles {
+		if pass := secrets[profileSecretKey(profiles[i].ID)]; pass != "" && profiles[i].Transport.Pass == "" {
+			profiles[i].Transport.Pass = pass
+		}
+	}
+	return profiles
+}
+
+// Save profiles; with the secrets store enabled their SMTP passwords go there instead
+func saveProfilesLocked(profiles []Profile) error {
+	passes := make(map[string]string)
+	for _, p := range profiles {
+		passes[p.ID] = p.Transport.Pass
+	}
+	stored, err := storeProfileSecrets(passes)
+	if err != nil {
+		return err
+	}
+	if stored {
+		profiles = append([]Profile(nil), profiles...)
+		for i := range profiles {
+			profiles[i].Transport.Pass = ""
+		}
+	}
+	return writeJSONFile("profiles.json", profiles)
+}
+
+// Find a profile by ID (empty ID = first profile)
+func findProfile(id string) (Profile, bool) {
+	for _, p := range loadProfiles() {
+		if id == "" || p.ID == id {
+			return p, true
+		}
+	}
+	return Profile{}, false
+}
+
+// Per-profile bookkeeping persisted across restarts
+type RunState struct {
+	LastSuccess time.Time `json:"last_success"`
+	WindowEnd   time.Time `json:"window_end"` // the next send starts here
+	// History sections that failed to fetch in a completed run: where their
+	// content still has to be picked up from, since the window moved on
+	OwedSince map[string]time.Time `json:"owed_since,omitempty"`
+	// Recipients an unfinished run (cancelled or failed mid-send) already sent to
+	Delivered *PartialDelivery `json:"delivered,omitempty"`
+}
+
+// End of the last successful send (zero before the first). Unlike the window start,
+// which is capped relative to now, it stays put until the next success.
+func (s RunState) lastSendEnd() time.Time {
+	if !s.WindowEnd.IsZero() {
+		return s.WindowEnd
+	}
+	return s.LastSuccess
+}
+
+// Recipients (by recipientKey) that got an issue from unfinished runs after the
+// successful send that ended at Since; the next run skips them
+type PartialDelivery struct {
+	Since      time.Time `json:"since"`
+	Recipients []string  `json:"recipients"`
+}
+
+// Remember who an unfinished run reached, on top of earlier unfinished runs since the last success
+func recordPartialDelivery(profileID string, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	runStateMu.Lock()
+	defer runStateMu.Unlock()
+
+	state := loadRunStateLocked()
+	entry := state[profileID]
+	if entry.Delivered == nil || !entry.Delivered.Since.Equal(entry.lastSendEnd()) {
+		entry.Delivered = &PartialDelivery{Since: entry.lastSendEnd()}
+	}
+	entry.Delivered.Recipients = append(entry.Delivered.Recipients, keys...)
+	state[profileID] = entry
+	if err := writeJSONFile("state.json", state); err != nil {
+		log.Printf("⚠️  Failed to save run state: %v", err)
+	}
+}
+
+// Recipients already reached by unfinished runs since the last successful send
+func deliveredEarlier(profileID string) map[string]bool {
+	delivered := make(map[string]bool)
+	state := loadRunState()[profileID]
+	if d := state.Delivered; d != nil && d.Since.Equal(state.lastSendEnd()) {
+		for _, key := range d.Recipients {
+			delivered[key] = true
+		}
+	}
+	return delivered
+}
+
+func loadRunState() map[string]RunState {
+	runStateMu.Lock()
+	defer runStateMu.Unlock()
+	return loadRunStateLocked()
+}
+
+func loadRunStateLocked() map[string]RunState {
+	state := make(map[string]RunState)
+	if err := readJSONFile("state.json", &state); err != nil {
+		log.Printf("⚠️  Failed to read run state: %v", err)
+	}
+	return state
+}
+
+// Remember when a profile last completed (sent, or had nothing to send) and where its window ended.
+// History sections that failed keep owing their content from where it was last fetched.
+func recordSuccessfulRun(profileID string, historySince map[string]time.Time, windowEnd time.Time, sources []SourceResult) {
+	runStateMu.Lock()
+	defer runStateMu.Unlock()
+
+	state := loadRunStateLocked()
+	entry := state[profileID]
+	entry.LastSuccess = time.Now()
+	entry.WindowEnd = windowEnd
+	owed := make(map[string]time.Time)
+	for _, s := range sources {
+		if s.Error != "" && !historySince[s.Section].IsZero() {
+			owed[s.Section] = historySince[s.Section]
+		}
+	}
+	entry.OwedSince = owed
+	entry.Delivered = nil
+	state[profileID] = entry
+	if err := writeJSONFile("state.json", state); err != nil {
+		log.Printf("⚠️  Failed to save run state: %v", err)
+	}
+}
+
+// Run history (data/runs.json, newest first; rendered emails in data/runs/)
+const maxRunHistory = 200
+
+// What started a run
+const (
+	TriggerScheduled = "scheduled"
+	TriggerManual    = "manual"
+	TriggerCatchUp   = "catch-up"
+	TriggerCLI       = "cli"
+)
+
+// Run outcomes
+const (
+	RunRunning   = "running"
+	RunSuccess   = "success"
+	RunPartial   = "partial" // sent, but some deliveries or sources failed
+	RunSkipped   = "skipped" // nothing to send
+	RunFailed    = "failed"
+	RunCancelled = "cancelled"
+)
+
+// Per-recipient delivery outcomes
+const (
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed"
+	DeliverySkipped = "skipped" // nothing left after the recipient's filters
+)
+
+type Run struct {
+	ID          string         `json:"id"`
+	ProfileID   string         `json:"profile_id"`
+	ProfileName string         `json:"profile_name"`
+	Trigger     string         `json:"trigger"`
+	Status      string         `json:"status"`
+	Message     string         `json:"message,omitempty"`
+	ErrorKind   string         `json:"error_kind,omitempty"` // set when failed: config, upstream, render, delivery, internal
+	Subject     string         `json:"subject,omitempty"`
+	StartedAt   time.Time      `json:"started_at"`
+	FinishedAt  *time.Time     `json:"finished_at,omitempty"`
+	WindowStart time.Time      `json:"window_start"`
+	WindowEnd   time.Time      `json:"window_end"`
+	Counts      map[string]int `json:"counts"` // items per section
+	Sources     []SourceResult `json:"sources"`
+	Deliveries  []Delivery     `json:"deliveries"`
+	HasHTML     bool           `json:"has_html"`
+}
+
+// One Sonarr/Radarr fetch within a run
+type SourceResult struct {
+	Section    string `json:"section"`
+	Name       string `json:"name"`
+	Items      int    `json:"items"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+type Delivery struct {
+	Email      string `json:"email"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	CustomHTML bool   `json:"custom_html,omitempty"` // filtered for this recipient, stored separately
+}
+
+func loadRuns() []Run {
+	runsMu.Lock()
+	defer runsMu.Unlock()
+	return loadRunsLocked()
+}
+
+func loadRunsLocked() []Run {
+	var runs []Run
+	if err := readJSONFile("runs.json", &runs); err != nil {
+		log.Printf("⚠️  Failed to read run history: %v", err)
+	}
+	return runs
+}
+
+func findRun(id string) (Run, bool) {
+	for _, run := range loadRuns() {
+		if run.ID == id {
+			return run, true
+		}
+	}
+	return Run{}, false
+}
+
+// Start a run record (saved immediately so in-progress runs show up in the history)
+func newRun(p Profile, trigger string, now time.Time) *Run {
+	token, err := generateToken()
+	if err != nil {
+		token = fmt.Sprintf("%016x", now.UnixNano())
+	}
+	run := &Run{
+		ID:          now.UTC().Format("20060102-150405") + "-" + token[:6],
+		ProfileID:   p.ID,
+		ProfileName: p.Name,
+		Trigger:     trigger,
+		Status:      RunRunning,
+		StartedAt:   now,
+		Counts:      map[string]int{},
+	}
+	run.save()
+	return run
+}
+
+func (run *Run) finish(status, message string) {
+	now := time.Now()
+	run.Status = status
+	run.Message = message
+	run.FinishedAt = &now
+	run.save()
+}
+
+// Insert or update the run, pruning the oldest entries and their stored emails
+func (run *Run) save() {
+	runsMu.Lock()
+	defer runsMu.Unlock()
+
+	runs := loadRunsLocked()
+	found := false
+	for i := range runs {
+		if runs[i].ID == run.ID {
+			runs[i] = *run
+			found = true
+			break
+		}
+	}
+	if !found {
+		runs = append([]Run{*run}, runs...)
+	}
+	if len(runs) > maxRunHistory {
+		for _, old := range runs[maxRunHistory:] {
+			removeRunHTML(old)
+		}
+		runs = runs[:maxRunHistory]
+	}
+
+	if err := writeJSONFile("runs.json", runs); err != nil {
+		log.Printf("⚠️  Failed to save run history: %v", err)
+	}
+}
+
+// Path of a stored email: the shared rendering, or a recipient's filtered copy (delivery >= 0)
+func runHTMLPath(runID string, delivery int) string {
+	name := runID + ".html"
+	if delivery >= 0 {
+		name = fmt.Sprintf("%s-%d.html", runID, delivery)
+	}
+	return filepath.Join(dataPath("runs"), name)
+}
+
+// Store a rendered email for the run; reports whether it was written
+func (run *Run) saveHTML(delivery int, html string) bool {
+	if err := os.MkdirAll(dataPath("runs"), 0700); err != nil {
+		run.logger().Warn("⚠️  Failed to create run archive", "error", err)
+		return false
+	}
+	if err := os.WriteFile(runHTMLPath(run.ID, delivery), []byte(html), 0600); err != nil {
+		run.logger().Warn("⚠️  Failed to store newsletter HTML", "error", err)
+		return false
+	}
+	if delivery < 0 {
+		run.HasHTML = true
+	}
+	return true
+}
+
+func removeRunHTML(run Run) {
+	os.Remove(runHTMLPath(run.ID, -1))
+	for i, d := range run.Deliveries {
+		if d.CustomHTML {
+			os.Remove(runHTMLPath(run.ID, i))
+		}
+	}
+}
+
+// Items per section, as shown in the run history
+func newsletterCounts(data NewsletterData) map[string]int {
+	episodes := func(groups []SeriesGroup) int {
+		n := 0
+		for _, g := range groups {
+			n += len(g.Episodes)
+		}
+		return n
+	}
+	return map[string]int{
+		SectionUpcomingSeries:   episodes(data.UpcomingSeriesGroups),
+		SectionUpcomingMovies:   len(data.UpcomingMovies),
+		SectionDownloadedSeries: episodes(data.DownloadedSeriesGroups),
+		SectionDownloadedMovies: len(data.DownloadedMovies),
+	}
+}
+
+// Web archive of sent issues (data/archive.json index, HTML in data/archive/)
+const (
+	ArchivePublic = "public"
+	ArchiveSigned = "signed"
+	ArchiveOff    = "off"
+)
+
+type Issue struct {
+	ID          string    `json:"id"` // same as the run ID
+	ProfileID   string    `json:"profile_id"`
+	ProfileName string    `json:"profile_name"`
+	Subject     string    `json:"subject"`
+	SentAt      time.Time `json:"sent_at"`
+	WindowStart time.Time `json:"window_start"`
+	WindowEnd   time.Time `json:"window_end"`
+	Readers     []string  `json:"readers"` // recipientKey of each recipient, for private archive indexes
+}
+
+// Issues kept in the archive, oldest dropped first (like the run history)
+const maxArchivedIssues = 200
+
+func loadIssues() []Issue {
+	archiveMu.Lock()
+	defer archiveMu.Unlock()
+	var issues []Issue
+	if err := readJSONFile("archive.json", &issues); err != nil {
+		log.Printf("⚠️  Failed to read archive: %v", err)
+	}
+	return issues
+}
+
+func findIssue(id string) (Issue, bool) {
+	for _, issue := range loadIssues() {
+		if issue.ID == id {
+			return issue, true
+		}
+	}
+	return Issue{}, false
+}
+
+// Store the shared rendering of a sent issue (without a self-referencing "view in browser" link)
+func archiveNewsletter(p Profile, run *Run, data NewsletterData, readers []string) {
+	data.ViewInBrowserURL = ""
+	html, err := generateNewsletterHTML(p, data)
+	if err == nil {
+		err = writeArchiveFile(run.ID+".html", html)
+	}
+	logger := run.logger()
+	if err != nil {
+		logger.Warn("⚠️  Failed to archive newsletter", "error", err)
+		return
+	}
+
+	issue := Issue{
+		ID:          run.ID,
+		ProfileID:   p.ID,
+		ProfileName: p.Name,
+		Subject:     run.Subject,
+		SentAt:      time.Now(),
+		WindowStart: run.WindowStart,
+		WindowEnd:   run.WindowEnd,
+		Readers:     readers,
+	}
+
+	archiveMu.Lock()
+	defer archiveMu.Unlock()
+	var issues []Issue
+	if err := readJSONFile("archive.json", &issues); err != nil {
+		logger.Warn("⚠️  Failed to read archive", "error", err)
+	}
+	issues = append([]Issue{issue}, issues...)
+	if len(issues) > maxArchivedIssues {
+		for _, old := range issues[maxArchivedIssues:] {
+			removeIssueHTML(old)
+		}
+		issues = issues[:maxArchivedIssues]
+	}
+	if err := writeJSONFile("archive.json", issues); err != nil {
+		logger.Warn("⚠️  Failed to save archive", "error", err)
+		return
+	}
+	logger.Info("🗄️  Archived issue", "issue", issue.ID)
+}
+
+// Delete the stored renderings of an issue dropped from the archive
+func removeIssueHTML(issue Issue) {
+	dir := dataPath("archive")
+	os.Remove(filepath.Join(dir, issue.ID+".html"))
+	for _, key := range issue.Readers {
+		os.Remove(filepath.Join(dir, issue.ID+"-"+key+".html"))
+	}
+}
+
+// A recipient's own filtered copy of an issue
+func saveArchiveCopy(issueID, email, html string) error {
+	return writeArchiveFile(issueID+"-"+recipientKey(email)+".html", html)
+}
+
+func writeArchiveFile(name, html string) error {
+	dir := dataPath("archive")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, name), []byte(html), 0600)
+}
+
+// Stable identifier for a recipient in archive links and indexes. Keyed with the
+// signing key, so it can't be matched against hashes of guessed addresses.
+func recipientKey(email string) string {
+	return signLink("recipient", strings.ToLower(strings.TrimSpace(email)))[:16]
+}
+
+// HMAC key for signed links, generated on first use (data/signing.key)
+var (
+	signingKeyOnce sync.Once
+	signingKeyData []byte
+)
+
+func signingKey() []byte {
+	signingKeyOnce.Do(func() {
+		path := dataPath("signing.key")
+		if data, err := os.ReadFile(path); err == nil {
+			if key, err := hex.DecodeString(strings.TrimSpace(string(data))); err == nil && len(key) >= 32 {
+				signingKeyData = key
+				return
+			}
+		}
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			log.Printf("⚠️  Failed to generate signing key: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(hex.EncodeToString(key)), 0600); err != nil {
+			log.Printf("⚠️  Failed to save signing key: %v", err)
+		}
+		signingKeyData = key
+	})
+	return signingKeyData
+}
+
+func signLink(parts ...string) string {
+	mac := hmac.New(sha256.New, signingKey())
+	mac.Write([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+func validLinkSignature(sig string, parts ...string) bool {
+	return sig != "" && hmac.Equal([]byte(sig), []byte(signLink(parts...)))
+}
+
+// Link to an issue; with private archives it is signed for one recipient
+func issueURL(cfg *Config, issueID, email string) string {
+	if cfg.PublicURL == "" || cfg.ArchiveAccess == ArchiveOff {
+		return ""
+	}
+	link := cfg.PublicURL + "/archive/" + issueID
+	if cfg.ArchiveAccess == ArchiveSigned {
+		key := recipientKey(email)
+		link += "?r=" + key + "&sig=" + signLink("issue", issueID, key)
+	}
+	return link
+}
+
+// Link to the list of past issues; with private archives it only lists the recipient's issues
+func archiveIndexURL(cfg *Config, email string) string {
+	if cfg.PublicURL == "" || cfg.ArchiveAccess == ArchiveOff {
+		return ""
+	}
+	link := cfg.PublicURL + "/archive"
+	if cfg.ArchiveAccess == ArchiveSigned {
+		key := recipientKey(email)
+		link += "?r=" + key + "&sig=" + signLink("index", key)
+	}
+	return link
+}
+
+// Longest period a send reports on: a profile idle for months starts from here
+// instead of fetching its whole history (unless its lookback is longer)
+const maxNewsletterWindow = 31 * 24 * time.Hour
+
+// Window for the downloaded sections: from the end of the last successful send
+// (or the profile's lookback on the first send) up to now
+func newsletterWindow(p Profile, now time.Time) (time.Time, time.Time) {
+	start := loadRunState()[p.ID].lastSendEnd()
+	if start.IsZero() || !start.Before(now) {
+		start = now.Add(-p.lookback())
+	}
+	return capWindowStart(p, start, now).In(now.Location()), now
+}
+
+func capWindowStart(p Profile, start, now time.Time) time.Time {
+	limit := maxNewsletterWindow
+	if p.lookback() > limit {
+		limit = p.lookback()
+	}
+	if earliest := now.Add(-limit); start.Before(earliest) {
+		return earliest
+	}
+	return start
+}
+
+// Start of each history section's fetch: the window start, or earlier for
+// sections that failed in the last completed run
+func historyWindow(p Profile, start, now time.Time) map[string]time.Time {
+	since := map[string]time.Time{
+		SectionDownloadedSeries: start,
+		SectionDownloadedMovies: start,
+	}
+	for section, owed := range loadRunState()[p.ID].OwedSince {
+		if _, ok := since[section]; ok && owed.Before(start) {
+			since[section] = capWindowStart(p, owed, now).In(start.Location())
+		}
+	}
+	return since
+}
+
+// Seed profiles.json with a weekly profile built from the legacy single-schedule settings
+func migrateProfiles(cfg *Config) {
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+
+	if _, err := os.Stat(dataPath("profiles.json")); err == nil {
+		return
+	}
+
+	sections := []string{SectionUpcomingSeries, SectionUpcomingMovies}
+	if cfg.ShowDownloaded {
+		sections = append(sections, SectionDownloadedSeries, SectionDownloadedMovies)
+	}
+
+	profile := Profile{
+		ID:             "weekly",
+		Name:           "Weekly Newsletter",
+		Enabled:        true,
+		Schedules:      []string{convertToCronExpression(cfg.ScheduleDay, cfg.ScheduleTime)},
+		Lookback:       "7d",
+		Lookahead:      "7d",
+		Sections:       sections,
+		ShowPosters:    cfg.ShowPosters,
+		Template:       "email.html",
+		Recipients:     cfg.ToEmails,
+		AllSubscribers: true,
+	}
+
+	if err := saveProfilesLocked([]Profile{profile}); err != nil {
+		log.Printf("⚠️  Failed to create default profile: %v", err)
+		return
+	}
+	log.Printf("📰 Created default newsletter profile (%s)", describeSchedule(profile.Schedules[0]))
+}
+
+// Random hex token for confirmation links
+func generateToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Web server with gzip compression
+func startWebServer() {
+	cfg := getConfig()
+
+	// Setup internal scheduler
+	setupScheduler(cfg)
+	go catchUpMissedRuns(cfg)
+
+	buildInfo.set(1, version)
+	seedRunMetrics()
+	announceSetupCode()
+	if cfg.SignupEnabled && cfg.PublicURL == "" {
+		log.Printf("⚠️  Sign-up is enabled but PUBLIC_URL is not set; the sign-up page stays off until it is")
+	}
+
+	port := getEnvFromFile(readEnvFile(), "WEBUI_PORT", "8080")
+
+	// Serve static files with gzip
+	http.HandleFunc("/", withGzip(uiHandler))
+	http.HandleFunc("/api/config", configHandler)
+	http.HandleFunc("/api/test-sonarr", testSonarrHandler)
+	http.HandleFunc("/api/test-radarr", testRadarrHandler)
+	http.HandleFunc("/api/test-email", testEmailHandler)
+	http.HandleFunc("/api/send", sendHandler)
+	http.HandleFunc("/api/logs", logsHandler)
+	http.HandleFunc("/api/events", eventsHandler)
+	http.HandleFunc("/metrics", metricsHandler)
+	http.HandleFunc("/healthz", healthzHandler)
+	http.HandleFunc("/readyz", readyzHandler)
+	http.HandleFunc("/api/status", statusHandler)
+	http.HandleFunc("/api/version", versionHandler)
+	http.HandleFunc("/api/update", updateHandler)
+	http.HandleFunc("/api/preview", previewHandler)
+	http.HandleFunc("/api/timezone-info", timezoneInfoHandler)
+	http.HandleFunc("/api/schedule-info", scheduleInfoHandler)
+	http.HandleFunc("/api/subscribers", subscribersHandler)
+	http.HandleFunc("/api/groups", groupsHandler)
+	http.HandleFunc("/api/profiles", profilesHandler)
+	http.HandleFunc("/api/runs", runsHandler)
+	http.HandleFunc("/api/runs/{id}", runHandler)
+	http.HandleFunc("/api/runs/{id}/html", runHandler)
+	http.HandleFunc("/api/jobs", jobsHandler)
+	http.HandleFunc("/api/jobs/{id}", jobHandler)
+	http.HandleFunc("/api/jobs/{id}/cancel", jobHandler)
+	http.HandleFunc("/api/tokens", tokensHandler)
+	http.HandleFunc("/api/tokens/{id}", tokensHandler)
+
+	// Public sign-up (only active when SIGNUP_ENABLED=true)
+	http.HandleFunc("/subscribe", subscribePageHandler)
+	http.HandleFunc("/subscribe/confirm", confirmSubscriptionHandler)
+	http.HandleFunc("/api/subscribe", subscribeHandler)
+
+	// Subscriber preferences (follow/mute titles, keyed by subscriber token)
+	http.HandleFunc("/preferences", preferencesPageHandler)
+
+	// Web archive of sent issues (ARCHIVE_ACCESS=public|signed|off)
+	http.HandleFunc("/archive", archiveIndexHandler)
+	http.HandleFunc("/archive/{id}", archiveIssueHandler)
+
+	// Atom feed and iCalendar of upcoming releases (require FEED_TOKEN)
+	http.HandleFunc("/feed.xml", feedHandler)
+	http.HandleFunc("/calendar.ics", calendarHandler)
+	http.HandleFunc("/api/preferences", preferencesHandler)
+
+	// Sign-in (everything else except the public pages requires it)
+	http.HandleFunc("/login", loginHandler)
+	http.HandleFunc("/logout", logoutHandler)
+
+	// Graceful shutdown
+	server := &http.Server{
+		Addr:    ":" + port,
+		Handler: withAuth(http.DefaultServeMux),
+	}
+	server.RegisterOnShutdown(events.close)
+
+	go func() {
+		log.Printf("🌐 Web UI started on port %s", port)
+		log.Printf("📅 Scheduler timezone: %s", cfg.Timezone)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("❌ Server error: %v", err)
+		}
+	}()
+
+	// Wait for interrupt signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Println("🛑 Shutting down server...")
+	shuttingDown.Store(true)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	schedulerMu.Lock()
+	if scheduler != nil {
+		scheduler.Stop()
+	}
+	schedulerMu.Unlock()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Fatal("Server forced to shutdown:", err)
+	}
+	flushTokenUsage()
+
+	log.Println("✅ Server stopped")
+}
+
+// Setup internal cron scheduler (replaces systemd timer)
+func setupScheduler(cfg *Config) {
+	scheduler = cron.New(cron.WithLocation(getTimezone(cfg.Timezone)))
+
+	// One cron entry per schedule of every enabled profile
+	for _, p := range loadProfiles() {
+		if !p.Enabled {
+			continue
+		}
+
+		id := p.ID
+		job := cron.FuncJob(func() {
+			// Look the profile up again so edits made since scheduling are honoured
+			profile, ok := findProfile(id)
+			if !ok {
+				log.Printf("⚠️  Scheduled profile %s no longer exists", id)
+				return
+			}
+			log.Printf("⏰ Scheduled newsletter triggered: %s", profile.Name)
+			if _, err := startJob(profile, TriggerScheduled); err != nil {
+				log.Printf("⏭️  Skipping scheduled run of %s: %v", profile.Name, err)
+			}
+		})
+
+		for _, spec := range p.Schedules {
+			sched, err := parseSchedule(spec)
+			if err != nil {
+				log.Printf("⚠️  Failed to schedule %s (%s): %v", p.Name, spec, err)
+				continue
+			}
+			log.Printf("📅 Scheduling %s: %s (cron: %s)", p.Name, describeSchedule(spec), spec)
+			scheduler.Schedule(sched, job)
+		}
+	}
+
+	scheduler.Start()
+	log.Printf("✅ Internal scheduler started (%d job(s))", len(scheduler.Entries()))
+}
+
+// Convert the legacy SCHEDULE_DAY/SCHEDULE_TIME pair to a cron expression
+func convertToCronExpression(day, timeStr string) string {
+	// Parse time (HH:MM)
+	parts := strings.Split(timeStr, ":")
+	hour, minute := 9, 0
+	if len(parts) == 2 {
+		fmt.Sscanf(parts[0], "%d", &hour)
+		fmt.Sscanf(parts[1], "%d", &minute)
+	}
+
+	// Convert day to cron weekday (0 = Sunday, 6 = Saturday)
+	dayMap := map[string]string{
+		"Sun": "0",
+		"Mon": "1",
+		"Tue": "2",
+		"Wed": "3",
+		"Thu": "4",
+		"Fri": "5",
+		"Sat": "6",
+	}
+
+	cronDay := dayMap[day]
+	if cronDay == "" {
+		cronDay = "0" // Default to Sunday
+	}
+
+	// Cron format: minute hour day month weekday
+	return fmt.Sprintf("%d %d * * %s", minute, hour, cronDay)
+}
+
+// Schedule presets offered in the UI (expanded to plain cron expressions)
+var schedulePresets = []struct {
+	Name string `json:"name"`
+	Expr string `json:"expr"`
+}{
+	{"Daily at 09:00", "0 9 * * *"},
+	{"Weekly on Sunday at 09:00", "0 9 * * 0"},
+	{"Weekdays at 08:00", "0 8 * * 1-5"},
+	{"Monthly on the 1st at 09:00", "0 9 1 * *"},
+	{"First Monday of the month at 09:00", "0 9 * * 1#1"},
+}
+
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// nthWeekdaySchedule fires only on the nth occurrence of a weekday in the month ("1#1" = first Monday)
+type nthWeekdaySchedule struct {
+	cron.Schedule
+	nth int
+}
+
+func (s nthWeekdaySchedule) Next(t time.Time) time.Time {
+	// A matching weekday comes round at least every 5 weeks; bail out well after that
+	for i := 0; i < 40; i++ {
+		t = s.Schedule.Next(t)
+		if t.IsZero() || (t.Day()-1)/7+1 == s.nth {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// Shortest gap allowed between two sends of one schedule (@hourly is the most frequent)
+const minScheduleInterval = time.Hour
+
+// Parse a schedule: standard 5-field cron, @descriptors, or a weekday field of the form "day#n".
+// Schedules without CRON_TZ= follow the scheduler timezone. Schedules that would send more
+// often than minScheduleInterval are rejected.
+func parseSchedule(spec string) (cron.Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("empty schedule")
+	}
+
+	fields := strings.Fields(spec)
+	last := len(fields) - 1
+	if !strings.HasPrefix(fields[last], "@") && strings.Contains(fields[last], "#") {
+		parts := strings.SplitN(fields[last], "#", 2)
+		nth, err := strconv.Atoi(parts[1])
+		if err != nil || nth < 1 || nth > 5 {
+			return nil, fmt.Errorf("invalid weekday occurrence %q (use 1-5)", fields[last])
+		}
+		if dom := len(fields) - 3; dom < 0 || fields[dom] != "*" {
+			return nil, fmt.Errorf("day-of-month must be * when using %q", fields[last])
+		}
+		fields[last] = parts[0]
+		sched, err := cronParser.Parse(strings.Join(fields, " "))
+		if err != nil {
+			return nil, err
+		}
+		return checkedSchedule(nthWeekdaySchedule{Schedule: sched, nth: nth})
+	}
+
+	sched, err := cronParser.Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	return checkedSchedule(sched)
+}
+
+func checkedSchedule(sched cron.Schedule) (cron.Schedule, error) {
+	if err := checkScheduleInterval(time.UTC, sched); err != nil {
+		return nil, err
+	}
+	return sched, nil
+}
+
+// Reject schedules whose fire times, taken together, come closer than minScheduleInterval.
+// Cron fields repeat, so the closest pair shows up among the first few dozen fire times;
+// instants several schedules share count once, like in nextRunTimes.
+func checkScheduleInterval(loc *time.Location, scheds ...cron.Schedule) error {
+	const samples = 50
+	var times []time.Time
+	var horizon time.Time // every schedule's fire times are known up to here
+	for _, sched := range scheds {
+		t := time.Date(2000, time.January, 1, 0, 0, 0, 0, loc)
+		n := 0
+		for ; n < samples; n++ {
+			if t = sched.Next(t); t.IsZero() {
+				break
+			}
+			times = append(times, t)
+		}
+		if n == samples && (horizon.IsZero() || t.Before(horizon)) {
+			horizon = t
+		}
+	}
+
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	for i := 1; i < len(times); i++ {
+		if !horizon.IsZero() && times[i].After(horizon) {
+			break
+		}
+		if gap := times[i].Sub(times[i-1]); gap > 0 && gap < minScheduleInterval {
+			return fmt.Errorf("would send more than once an hour (%s apart)", gap)
+		}
+	}
+	return nil
+}
+
+// Next n fire times across all schedules, soonest first
+func nextRunTimes(specs []string, loc *time.Location, n int) []time.Time {
+	now := time.Now().In(loc)
+	var times []time.Time
+	for _, spec := range specs {
+		sched, err := parseSchedule(spec)
+		if err != nil {
+			continue
+		}
+		t := now
+		for i := 0; i < n; i++ {
+			t = sched.Next(t)
+			if t.IsZero() {
+				break
+			}
+			times = append(times, t.In(loc))
+		}
+	}
+
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	// Schedules can coincide; keep each instant once
+	unique := times[:0]
+	for _, t := range times {
+		if len(unique) == 0 || !t.Equal(unique[len(unique)-1]) {
+			unique = append(unique, t)
+		}
+	}
+	if len(unique) > n {
+		unique = unique[:n]
+	}
+	return unique
+}
+
+var weekdayNames = []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+var ordinalNames = []string{"", "first", "second", "third", "fourth", "fifth"}
+
+// Weekday name for a cron day-of-week value (0-7 or SUN-SAT)
+func cronWeekdayName(value string) (string, bool) {
+	if d, err := strconv.Atoi(value); err == nil {
+		if d < 0 || d > 7 {
+			return "", false
+		}
+		return weekdayNames[d%7], true
+	}
+	for _, name := range weekdayNames {
+		if strings.EqualFold(value, name[:3]) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Human-readable description of a schedule; falls back to the expression for anything unusual
+func describeSchedule(spec string) string {
+	spec = strings.TrimSpace(spec)
+	if _, err := parseSchedule(spec); err != nil {
+		return "Invalid schedule: " + err.Error()
+	}
+
+	tz := ""
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		i := strings.Index(spec, " ")
+		tz = " (" + spec[strings.Index(spec, "=")+1:i] + ")"
+		spec = strings.TrimSpace(spec[i:])
+	}
+
+	switch spec {
+	case "@yearly", "@annually":
+		return "Every year on January 1 at 00:00" + tz
+	case "@monthly":
+		return "On the 1st of every month at 00:00" + tz
+	case "@weekly":
+		return "Every Sunday at 00:00" + tz
+	case "@daily", "@midnight":
+		return "Every day at 00:00" + tz
+	case "@hourly":
+		return "Every hour" + tz
+	}
+	if strings.HasPrefix(spec, "@every ") {
+		return "Every " + strings.TrimPrefix(spec, "@every ")
+	}
+
+	f := strings.Fields(spec)
+	minute, hour, dom, month, dow := f[0], f[1], f[2], f[3], f[4]
+
+	m, errM := strconv.Atoi(minute)
+	h, errH := strconv.Atoi(hour)
+	var at string
+	switch {
+	case errM == nil && errH == nil:
+		at = fmt.Sprintf("at %02d:%02d", h, m)
+	case errM == nil && hour == "*":
+		at = fmt.Sprintf("every hour at minute %d", m)
+	default:
+		return "Custom schedule: " + spec + tz
+	}
+
+	var days string
+	switch {
+	case strings.Contains(dow, "#"):
+		parts := strings.SplitN(dow, "#", 2)
+		nth, _ := strconv.Atoi(parts[1])
+		name, ok := cronWeekdayName(parts[0])
+		if !ok {
+			return "Custom schedule: " + spec + tz
+		}
+		days = fmt.Sprintf("On the %s %s of the month", ordinalNames[nth], name)
+	case dom == "*" && dow == "*":
+		days = "Every day"
+	case dom == "*" && (dow == "1-5" || strings.EqualFold(dow, "MON-FRI")):
+		days = "Every weekday"
+	case dom == "*":
+		var names []string
+		for _, part := range strings.Split(dow, ",") {
+			name, ok := cronWeekdayName(part)
+			if !ok {
+				return "Custom schedule: " + spec + tz
+			}
+			names = append(names, name)
+		}
+		days = "Every " + strings.Join(names, ", ")
+	case dow == "*":
+		if _, err := strconv.Atoi(dom); err != nil {
+			return "Custom schedule: " + spec + tz
+		}
+		days = "On day " + dom + " of the month"
+	default:
+		return "Custom schedule: " + spec + tz
+	}
+
+	if month != "*" {
+		days += " in month " + month
+	}
+	return days + " " + at + tz
+}
+
+// Most recent fire time of any schedule in (after, before], or zero if none
+func lastScheduledRun(specs []string, after, before time.Time) time.Time {
+	var last time.Time
+	for _, spec := range specs {
+		sched, err := parseSchedule(spec)
+		if err != nil {
+			continue
+		}
+		for t := sched.Next(after); !t.IsZero() && !t.After(before); t = sched.Next(t) {
+			if t.After(last) {
+				last = t
+			}
+		}
+	}
+	return last
+}
+
+// Run each profile once if a scheduled send was missed while we were down.
+// Profiles that never completed a run are skipped, so fresh installs don't send on boot.
+func catchUpMissedRuns(cfg *Config) {
+	if cfg.CatchUpGrace <= 0 {
+		return
+	}
+
+	loc := getTimezone(cfg.Timezone)
+	now := time.Now().In(loc)
+	state := loadRunState()
+
+	for _, p := range loadProfiles() {
+		last := state[p.ID].LastSuccess
+		if !p.Enabled || last.IsZero() {
+			continue
+		}
+
+		// Only look as far back as the grace period; older misses are not worth sending late
+		from := last.In(loc)
+		if limit := now.Add(-cfg.CatchUpGrace); from.Before(limit) {
+			from = limit
+		}
+		missed := lastScheduledRun(p.Schedules, from, now)
+		if missed.IsZero() {
+			if lastScheduledRun(p.Schedules, last.In(loc), now).IsZero() {
+				continue
+			}
+			log.Printf("⏭️  Missed run of %s is older than %s, not catching up", p.Name, cfg.CatchUpGrace)
+			continue
+		}
+
+		// The window starts where the last successful send ended, so the catch-up covers the gap
+		log.Printf("⏪ Catching up missed run of %s (was due %s, last success %s)",
+			p.Name, missed.Format("2006-01-02 15:04"), last.In(loc).Format("2006-01-02 15:04"))
+		job, err := startJob(p, TriggerCatchUp)
+		if err != nil {
+			log.Printf("⏭️  Skipping catch-up of %s: %v", p.Name, err)
+			continue
+		}
+		<-job.done
+	}
+}
+
+// Restart scheduler when config changes
+func restartScheduler() {
+	schedulerMu.Lock()
+	defer schedulerMu.Unlock()
+	if scheduler != nil {
+		scheduler.Stop()
+	}
+	cfg := getConfig()
+	setupScheduler(cfg)
+	log.Println("🔄 Scheduler restarted")
+}
+
+// Gzip compression middleware
+func withGzip(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			handler(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+
+		gzw := &gzipResponseWriter{Writer: gz, ResponseWriter: w}
+		handler(gzw, r)
+	}
+}
+
+type gzipResponseWriter struct {
+	io.Writer
+	http.ResponseWriter
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	return w.Writer.Write(b)
+}
+
+// Handlers
+
+func uiHandler(w http.ResponseWriter, r *http.Request) {
+	cfg := getConfig()
+	account := ""
+	if user, ok := currentUser(r); ok {
+		account = "Signed in as " + template.HTMLEscapeString(user.Name)
+		if !user.Proxy {
+			account += ` • <form method="POST" action="/logout" style="display: inline;"><button type="submit" class="link-button">Sign out</button></form>`
+		}
+	}
+	loc := getTimezone(cfg.Timezone)
+	nextRuns := []string{}
+	for _, p := range loadProfiles() {
+		if !p.Enabled {
+			continue
+		}
+		if times := nextRunTimes(p.Schedules, loc, 1); len(times) > 0 {
+			nextRuns = append(nextRuns, template.HTMLEscapeString(p.Name)+": "+times[0].Format("Monday, January 2, 2006 at 3:04 PM MST"))
+		}
+	}
+	nextRun := "No newsletter scheduled"
+	if len(nextRuns) > 0 {
+		nextRun = strings.Join(nextRuns, " • ")
+	}
+
+	html := `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Newslettar v` + version + `</title>
+    <style>
+        * { margin: 0; padding: 0; box-sizing: border-box; }
         body {
             font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif;
             background: #0f1419;
@@ -1102,1223 +4787,5059 @@ func uiHandler(w http.ResponseWriter, r *http.Request) {
             .action-buttons { flex-direction: column; }
             .action-buttons .btn { margin-bottom: 10px; }
         }
-        
-        .header {
+        
+        .header {
+            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
+            padding: 30px;
+            border-radius: 12px;
+            margin-bottom: 30px;
+            text-align: center;
+        }
+        .header h1 {
+            font-size: 2.5em;
+            margin-bottom: 10px;
+        }
+        .version {
+            opacity: 0.9;
+            font-size: 0.9em;
+        }
+        .link-button {
+            background: none;
+            border: none;
+            color: inherit;
+            font: inherit;
+            text-decoration: underline;
+            cursor: pointer;
+        }
+        .tabs {
+            display: flex;
+            gap: 10px;
+            margin-bottom: 20px;
+            background: #1a2332;
+            padding: 10px;
+            border-radius: 10px;
+        }
+        .tab {
+            flex: 1;
+            padding: 12px 20px;
+            background: transparent;
+            border: none;
+            color: #8899aa;
+            cursor: pointer;
+            border-radius: 8px;
+            font-size: 14px;
+            font-weight: 500;
+            transition: all 0.3s;
+        }
+        .tab:hover { background: #252f3f; color: #fff; }
+        .tab:focus { outline: 2px solid #667eea; outline-offset: 2px; }
+        .tab.active {
+            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
+            color: #fff;
+        }
+        .tab-content {
+            display: none;
+            background: #1a2332;
+            padding: 30px;
+            border-radius: 12px;
+            min-height: 400px;
+        }
+        .tab-content.active { display: block; }
+        .form-group {
+            margin-bottom: 20px;
+        }
+        .form-group label {
+            display: block;
+            margin-bottom: 8px;
+            color: #a0b0c0;
+            font-weight: 500;
+        }
+        .form-group input, .form-group select {
+            width: 100%;
+            padding: 12px;
+            background: #0f1419;
+            border: 2px solid #2a3444;
+            border-radius: 8px;
+            color: #e8e8e8;
+            font-size: 14px;
+            transition: border-color 0.3s;
+        }
+        .form-group input:focus, .form-group select:focus {
+            outline: none;
+            border-color: #667eea;
+        }
+        .form-group input.error, .form-group select.error {
+            border-color: #eb3349;
+        }
+        .form-group input.success, .form-group select.success {
+            border-color: #38ef7d;
+        }
+        .error-message {
+            color: #eb3349;
+            font-size: 0.85em;
+            margin-top: 5px;
+            display: none;
+        }
+        .error-message.show { display: block; }
+        .setting-source {
+            font-size: 0.8em;
+            margin-top: 5px;
+            opacity: 0.6;
+        }
+        .setting-source.overridden {
+            color: #f2c94c;
+            opacity: 1;
+        }
+        .btn {
+            padding: 12px 24px;
+            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
+            color: white;
+            border: none;
+            border-radius: 8px;
+            cursor: pointer;
+            font-size: 14px;
+            font-weight: 600;
+            transition: transform 0.2s, opacity 0.3s;
+            position: relative;
+        }
+        .btn:hover { transform: translateY(-2px); opacity: 0.9; }
+        .btn:active { transform: translateY(0); }
+        .btn:disabled {
+            opacity: 0.5;
+            cursor: not-allowed;
+            transform: none;
+        }
+        .btn:focus { outline: 2px solid #667eea; outline-offset: 2px; }
+        .btn-secondary {
+            background: #2a3444;
+        }
+        .btn-success {
+            background: linear-gradient(135deg, #11998e 0%, #38ef7d 100%);
+        }
+        .btn-danger {
+            background: linear-gradient(135deg, #eb3349 0%, #f45c43 100%);
+        }
+        .btn.loading::after {
+            content: "";
+            position: absolute;
+            width: 16px;
+            height: 16px;
+            top: 50%;
+            left: 50%;
+            margin-left: -8px;
+            margin-top: -8px;
+            border: 2px solid #ffffff40;
+            border-top-color: #fff;
+            border-radius: 50%;
+            animation: spin 0.6s linear infinite;
+        }
+        .btn.loading span { opacity: 0; }
+        @keyframes spin {
+            to { transform: rotate(360deg); }
+        }
+        .notification {
+            position: fixed;
+            top: 20px;
+            right: 20px;
+            padding: 16px 24px;
+            border-radius: 10px;
+            color: white;
+            font-weight: 500;
+            animation: slideIn 0.3s;
+            z-index: 1000;
+            max-width: 400px;
+        }
+        .notification.success {
+            background: linear-gradient(135deg, #11998e 0%, #38ef7d 100%);
+        }
+        .notification.error {
+            background: linear-gradient(135deg, #eb3349 0%, #f45c43 100%);
+        }
+        .notification.warning {
+            background: linear-gradient(135deg, #f2994a 0%, #f2c94c 100%);
+        }
+        @keyframes slideIn {
+            from { transform: translateX(400px); opacity: 0; }
+            to { transform: translateX(0); opacity: 1; }
+        }
+        .job-progress {
+            margin-top: 15px;
+            padding: 15px;
+            background: #252f3f;
+            border-radius: 8px;
+            color: #a0b0c0;
+            font-size: 0.9em;
+        }
+        .job-progress p { margin-top: 5px; }
+        .progress-bar {
+            height: 8px;
+            margin-top: 10px;
+            background: #0f1419;
+            border-radius: 4px;
+            overflow: hidden;
+        }
+        .progress-bar div {
+            height: 100%;
+            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
+            transition: width 0.3s;
+        }
+        .logs-container {
+            background: #0f1419;
+            padding: 20px;
+            border-radius: 8px;
+            font-family: 'Courier New', monospace;
+            font-size: 13px;
+            max-height: 500px;
+            overflow-y: auto;
+            white-space: pre-wrap;
+            border: 2px solid #2a3444;
+        }
+        .logs-filters {
+            display: flex;
+            flex-wrap: wrap;
+            gap: 10px;
+            margin-bottom: 15px;
+        }
+        .logs-filters select, .logs-filters input {
+            width: auto;
+            flex: 1 1 140px;
+        }
+        .log-debug { color: #6b7b8c; }
+        .log-warn { color: #f0c674; }
+        .log-error { color: #ff6b6b; }
+        .schedule-info {
+            background: #252f3f;
+            padding: 20px;
+            border-radius: 10px;
+            margin-bottom: 20px;
+            border-left: 4px solid #667eea;
+        }
+        .schedule-info h3 {
+            margin-bottom: 10px;
+            color: #667eea;
+        }
+        .toggle-switch {
+            position: relative;
+            display: inline-block;
+            width: 50px;
+            height: 26px;
+            margin-left: 10px;
+        }
+        .toggle-switch input {
+            opacity: 0;
+            width: 0;
+            height: 0;
+        }
+        .toggle-slider {
+            position: absolute;
+            cursor: pointer;
+            top: 0;
+            left: 0;
+            right: 0;
+            bottom: 0;
+            background-color: #2a3444;
+            transition: 0.3s;
+            border-radius: 26px;
+        }
+        .toggle-slider:before {
+            position: absolute;
+            content: "";
+            height: 20px;
+            width: 20px;
+            left: 3px;
+            bottom: 3px;
+            background-color: white;
+            transition: 0.3s;
+            border-radius: 50%;
+        }
+        input:checked + .toggle-slider {
             background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
-            padding: 30px;
-            border-radius: 12px;
-            margin-bottom: 30px;
-            text-align: center;
         }
-        .header h1 {
-            font-size: 2.5em;
-            margin-bottom: 10px;
+        input:checked + .toggle-slider:before {
+            transform: translateX(24px);
         }
-        .version {
-            opacity: 0.9;
+        .template-option {
+            display: flex;
+            align-items: center;
+            justify-content: space-between;
+            padding: 15px;
+            background: #252f3f;
+            border-radius: 8px;
+            margin-bottom: 12px;
+        }
+        .timezone-info {
+            background: #252f3f;
+            padding: 15px;
+            border-radius: 8px;
+            margin-top: 10px;
             font-size: 0.9em;
         }
-        .tabs {
-            display: flex;
-            gap: 10px;
+        .timezone-info strong {
+            color: #667eea;
+        }
+        .info-banner {
+            background: #252f3f;
+            padding: 15px 20px;
+            border-radius: 8px;
             margin-bottom: 20px;
+            border-left: 4px solid #11998e;
+        }
+        .info-banner p {
+            margin: 5px 0;
+            color: #a0b0c0;
+        }
+        .info-banner strong {
+            color: #e8e8e8;
+        }
+        
+        /* Preview Modal */
+        .modal {
+            display: none;
+            position: fixed;
+            z-index: 2000;
+            left: 0;
+            top: 0;
+            width: 100%;
+            height: 100%;
+            background-color: rgba(0,0,0,0.8);
+            animation: fadeIn 0.3s;
+        }
+        .modal.show { display: flex; align-items: center; justify-content: center; }
+        .modal-content {
             background: #1a2332;
-            padding: 10px;
-            border-radius: 10px;
+            width: 90%;
+            max-width: 900px;
+            max-height: 90vh;
+            border-radius: 12px;
+            overflow: hidden;
+            animation: slideUp 0.3s;
         }
-        .tab {
-            flex: 1;
-            padding: 12px 20px;
+        .modal-header {
+            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
+            padding: 20px;
+            display: flex;
+            justify-content: space-between;
+            align-items: center;
+        }
+        .modal-header h2 {
+            margin: 0;
+            color: white;
+        }
+        .modal-close {
             background: transparent;
             border: none;
-            color: #8899aa;
+            color: white;
+            font-size: 28px;
             cursor: pointer;
+            padding: 0;
+            width: 30px;
+            height: 30px;
+            line-height: 1;
+        }
+        .modal-close:hover { opacity: 0.7; }
+        .modal-close:focus { outline: 2px solid white; outline-offset: 2px; }
+        .modal-body {
+            padding: 20px;
+            max-height: calc(90vh - 140px);
+            overflow-y: auto;
+        }
+        .modal-body iframe {
+            width: 100%;
+            height: 600px;
+            border: 2px solid #2a3444;
             border-radius: 8px;
-            font-size: 14px;
-            font-weight: 500;
-            transition: all 0.3s;
+            background: white;
         }
-        .tab:hover { background: #252f3f; color: #fff; }
-        .tab:focus { outline: 2px solid #667eea; outline-offset: 2px; }
-        .tab.active {
-            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
-            color: #fff;
+        @keyframes fadeIn {
+            from { opacity: 0; }
+            to { opacity: 1; }
+        }
+        @keyframes slideUp {
+            from { transform: translateY(50px); opacity: 0; }
+            to { transform: translateY(0); opacity: 1; }
+        }
+        
+        .action-buttons {
+            display: flex;
+            gap: 10px;
+            margin-top: 20px;
+        }
+        .action-buttons .btn {
+            flex: 1;
+        }
+        
+        /* Loading overlay */
+        .loading-overlay {
+            display: none;
+            position: fixed;
+            top: 0;
+            left: 0;
+            width: 100%;
+            height: 100%;
+            background: rgba(0,0,0,0.7);
+            z-index: 1500;
+            align-items: center;
+            justify-content: center;
+        }
+        .loading-overlay.show { display: flex; }
+        .loading-spinner {
+            width: 60px;
+            height: 60px;
+            border: 5px solid rgba(255,255,255,0.3);
+            border-top-color: #667eea;
+            border-radius: 50%;
+            animation: spin 0.8s linear infinite;
+        }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>📺 Newslettar</h1>
+            <p class="version">Version ` + version + ` • Enhanced UI • Timezone-Aware</p>
+            <p class="version">` + account + `</p>
+        </div>
+
+        <div class="tabs" role="tablist">
+            <button class="tab active" role="tab" aria-selected="true" aria-controls="config-tab" onclick="showTab('config')">⚙️ Configuration</button>
+            <button class="tab" role="tab" aria-selected="false" aria-controls="newsletters-tab" onclick="showTab('newsletters')">📰 Newsletters</button>
+            <button class="tab" role="tab" aria-selected="false" aria-controls="subscribers-tab" onclick="showTab('subscribers')">👥 Subscribers</button>
+            <button class="tab" role="tab" aria-selected="false" aria-controls="history-tab" onclick="showTab('history')">🕘 History</button>
+            <button class="tab" role="tab" aria-selected="false" aria-controls="logs-tab" onclick="showTab('logs')">📋 Logs</button>
+            <button class="tab" role="tab" aria-selected="false" aria-controls="tokens-tab" onclick="showTab('tokens')">🔑 API Tokens</button>
+            <button class="tab" role="tab" aria-selected="false" aria-controls="update-tab" onclick="showTab('update')">🔄 Update</button>
+        </div>
+
+        <div id="config-tab" class="tab-content active" role="tabpanel">
+            <div class="info-banner">
+                <p><strong>⏰ Next Scheduled Send:</strong> ` + nextRun + `</p>
+                <p><strong>🌍 Timezone:</strong> <span id="current-timezone">` + cfg.Timezone + `</span></p>
+                <p style="margin-top: 10px; font-size: 0.9em; opacity: 0.8;">
+                    ℹ️ Scheduler runs internally (no systemd timer needed). Changes apply immediately.
+                </p>
+            </div>
+
+            <form id="config-form">
+                <h3 style="margin-bottom: 15px; color: #667eea;">General Settings</h3>
+                
+                <div class="form-group">
+                    <label for="timezone">Timezone</label>
+                    <select name="timezone" id="timezone" aria-label="Select timezone">
+                        <option value="UTC">UTC (GMT+0)</option>
+                        <option value="America/New_York">Eastern Time (GMT-5/-4)</option>
+                        <option value="America/Chicago">Central Time (GMT-6/-5)</option>
+                        <option value="America/Denver">Mountain Time (GMT-7/-6)</option>
+                        <option value="America/Los_Angeles">Pacific Time (GMT-8/-7)</option>
+                        <option value="America/Toronto">Toronto (GMT-5/-4)</option>
+                        <option value="America/Vancouver">Vancouver (GMT-8/-7)</option>
+                        <option value="America/Montreal">Montreal (GMT-5/-4)</option>
+                        <option value="Europe/London">London (GMT+0/+1)</option>
+                        <option value="Europe/Paris">Paris (GMT+1/+2)</option>
+                        <option value="Europe/Berlin">Berlin (GMT+1/+2)</option>
+                        <option value="Asia/Tokyo">Tokyo (GMT+9)</option>
+                        <option value="Asia/Shanghai">Shanghai (GMT+8)</option>
+                        <option value="Australia/Sydney">Sydney (GMT+10/+11)</option>
+                    </select>
+                    <div class="timezone-info" id="timezone-info"></div>
+                </div>
+
+                <div class="form-group">
+                    <label for="catchup_grace">Missed-run catch-up window (e.g. 24h, 90m; 0 = off)</label>
+                    <input type="text" name="catchup_grace" id="catchup_grace" placeholder="24h" aria-label="Missed-run catch-up window">
+                </div>
+
+
+                <hr style="margin: 30px 0; border: none; border-top: 2px solid #2a3444;">
+
+                <h3 style="margin-bottom: 15px; color: #667eea;">Sonarr Settings</h3>
+                <div class="form-group">
+                    <label for="sonarr_url">Sonarr URL</label>
+                    <input type="url" name="sonarr_url" id="sonarr_url" placeholder="http://localhost:8989" aria-label="Sonarr URL">
+                    <div class="error-message" id="sonarr-url-error">Please enter a valid URL</div>
+                </div>
+                <div class="form-group">
+                    <label for="sonarr_api_key">Sonarr API Key</label>
+                    <input type="password" name="sonarr_api_key" id="sonarr_api_key" placeholder="Your Sonarr API key" aria-label="Sonarr API Key" autocomplete="off">
+                </div>
+                <button type="button" class="btn btn-secondary" onclick="testConnection('sonarr')" aria-label="Test Sonarr connection">
+                    <span>Test Sonarr</span>
+                </button>
+
+                <hr style="margin: 30px 0; border: none; border-top: 2px solid #2a3444;">
+
+                <h3 style="margin-bottom: 15px; color: #667eea;">Radarr Settings</h3>
+                <div class="form-group">
+                    <label for="radarr_url">Radarr URL</label>
+                    <input type="url" name="radarr_url" id="radarr_url" placeholder="http://localhost:7878" aria-label="Radarr URL">
+                    <div class="error-message" id="radarr-url-error">Please enter a valid URL</div>
+                </div>
+                <div class="form-group">
+                    <label for="radarr_api_key">Radarr API Key</label>
+                    <input type="password" name="radarr_api_key" id="radarr_api_key" placeholder="Your Radarr API key" aria-label="Radarr API Key" autocomplete="off">
+                </div>
+                <button type="button" class="btn btn-secondary" onclick="testConnection('radarr')" aria-label="Test Radarr connection">
+                    <span>Test Radarr</span>
+                </button>
+
+                <hr style="margin: 30px 0; border: none; border-top: 2px solid #2a3444;">
+
+                <h3 style="margin-bottom: 15px; color: #667eea;">Email Settings</h3>
+                <div class="form-group">
+                    <label for="mailgun_smtp">SMTP Server</label>
+                    <input type="text" name="mailgun_smtp" id="mailgun_smtp" placeholder="smtp.mailgun.org" aria-label="SMTP Server">
+                </div>
+                <div class="form-group">
+                    <label for="mailgun_port">SMTP Port</label>
+                    <input type="number" name="mailgun_port" id="mailgun_port" placeholder="587" aria-label="SMTP Port">
+                </div>
+                <div class="form-group">
+                    <label for="mailgun_user">SMTP Username</label>
+                    <input type="text" name="mailgun_user" id="mailgun_user" placeholder="postmaster@yourdomain.com" aria-label="SMTP Username">
+                </div>
+                <div class="form-group">
+                    <label for="mailgun_pass">SMTP Password</label>
+                    <input type="password" name="mailgun_pass" id="mailgun_pass" placeholder="Your SMTP password" aria-label="SMTP Password">
+                </div>
+                <div class="form-group">
+                    <label for="from_name">From Name</label>
+                    <input type="text" name="from_name" id="from_name" placeholder="Newslettar" aria-label="From Name">
+                </div>
+                <div class="form-group">
+                    <label for="from_email">From Email</label>
+                    <input type="email" name="from_email" id="from_email" placeholder="newsletter@yourdomain.com" aria-label="From Email">
+                    <div class="error-message" id="from-email-error">Please enter a valid email address</div>
+                </div>
+                <div class="form-group">
+                    <label for="admin_email">Admin Email (alerted when a newsletter fails to send, optional)</label>
+                    <input type="email" name="admin_email" id="admin_email" placeholder="you@yourdomain.com" aria-label="Admin Email">
+                </div>
+                <button type="button" class="btn btn-secondary" onclick="testConnection('email')" aria-label="Test email authentication">
+                    <span>Test Email Auth</span>
+                </button>
+
+                <hr style="margin: 30px 0; border: none; border-top: 2px solid #2a3444;">
+
+                <h3 style="margin-bottom: 15px; color: #667eea;">Public Sign-up</h3>
+                <div class="form-group">
+                    <label for="signup_enabled">Sign-up Page (/subscribe)</label>
+                    <select name="signup_enabled" id="signup_enabled" aria-label="Enable public sign-up">
+                        <option value="false">Disabled</option>
+                        <option value="true">Enabled</option>
+                    </select>
+                </div>
+                <div class="form-group">
+                    <label for="invite_codes">Invite Codes (comma-separated, leave empty for open sign-up)</label>
+                    <input type="text" name="invite_codes" id="invite_codes" placeholder="family2025, friends" aria-label="Invite Codes">
+                </div>
+                <div class="form-group">
+                    <label for="public_url">Public URL (used in confirmation, preferences and archive links; required for sign-up)</label>
+                    <input type="url" name="public_url" id="public_url" placeholder="https://newsletter.example.com" aria-label="Public URL">
+                </div>
+                <div class="form-group">
+                    <label for="archive_access">Web Archive (/archive)</label>
+                    <select name="archive_access" id="archive_access" aria-label="Web archive access">
+                        <option value="signed">Private links (each recipient gets a signed link)</option>
+                        <option value="public">Public (anyone with the URL)</option>
+                        <option value="off">Disabled</option>
+                    </select>
+                </div>
+                <div class="form-group">
+                    <label for="feed_token">Feed Token (required by /feed.xml and /calendar.ics, leave empty to disable both)</label>
+                    <div style="display: flex; gap: 10px;">
+                        <input type="password" name="feed_token" id="feed_token" aria-label="Feed token" style="flex: 1;" oninput="updateFeedURLs()" autocomplete="off">
+                        <button type="button" class="btn btn-secondary" onclick="generateFeedToken()" aria-label="Generate feed token">
+                            <span>🎲 Generate</span>
+                        </button>
+                    </div>
+                    <div class="timezone-info" id="feed-urls"></div>
+                </div>
+
+                <hr style="margin: 30px 0; border: none; border-top: 2px solid #2a3444;">
+
+                <button type="submit" class="btn" aria-label="Save configuration">
+                    <span>💾 Save Configuration</span>
+                </button>
+            </form>
+        </div>
+
+        <div id="newsletters-tab" class="tab-content" role="tabpanel">
+            <h3 style="margin-bottom: 15px;">📰 Newsletters</h3>
+            <p style="margin-bottom: 20px; color: #8899aa; font-size: 0.9em;">
+                Each newsletter has its own schedule, time window, sections, template, recipients and (optionally) SMTP settings.
+                Empty SMTP fields use the Email Settings from the Configuration tab.
+            </p>
+
+            <div id="profiles-list"></div>
+
+            <button class="btn btn-secondary" onclick="addProfile()" style="margin-top: 10px;" aria-label="Add newsletter">
+                <span>➕ Add Newsletter</span>
+            </button>
+        </div>
+
+        <div id="subscribers-tab" class="tab-content" role="tabpanel">
+            <h3 style="margin-bottom: 15px;">👥 Subscribers</h3>
+            <p style="margin-bottom: 15px; color: #8899aa; font-size: 0.9em;">
+                People who signed up through the public /subscribe page or were added here. Pending subscribers have not clicked their confirmation link yet.
+            </p>
+            <div class="action-buttons" style="margin-top: 0; margin-bottom: 15px;">
+                <input type="email" id="new-subscriber" placeholder="kid@example.com" aria-label="New subscriber email"
+                    style="flex: 2; padding: 12px; background: #0f1419; border: 2px solid #2a3444; border-radius: 8px; color: #e8e8e8;">
+                <button class="btn" onclick="addSubscriber()" aria-label="Add subscriber">
+                    <span>➕ Add Subscriber</span>
+                </button>
+                <button class="btn btn-secondary" onclick="loadSubscribers()" aria-label="Refresh subscribers">
+                    <span>🔄 Refresh</span>
+                </button>
+            </div>
+            <p style="margin-bottom: 15px; color: #8899aa; font-size: 0.9em;">
+                ℹ️ Max rating hides titles certified above the limit (e.g. PG-13 also allows TV-14). Titles without a certification are hidden unless "unrated" is allowed.
+            </p>
+            <div id="subscribers-list" aria-live="polite"></div>
+
+            <hr style="margin: 30px 0; border: none; border-top: 2px solid #2a3444;">
+
+            <h3 style="margin-bottom: 15px;">🏷️ Recipient Groups</h3>
+            <p style="margin-bottom: 15px; color: #8899aa; font-size: 0.9em;">
+                Groups map Sonarr/Radarr tags to subscribers: members only get series and movies carrying one of the group's tags
+                (plus untagged items if enabled). Subscribers without a group get everything.
+            </p>
+            <p style="margin-bottom: 15px; color: #8899aa; font-size: 0.9em;">
+                <strong>Available tags:</strong> <span id="available-tags">loading...</span>
+            </p>
+            <div id="groups-list"></div>
+            <div class="action-buttons">
+                <button class="btn btn-secondary" onclick="addGroup()" aria-label="Add group">
+                    <span>➕ Add Group</span>
+                </button>
+                <button class="btn" onclick="saveGroups()" aria-label="Save groups">
+                    <span>💾 Save Groups</span>
+                </button>
+            </div>
+        </div>
+
+        <div id="history-tab" class="tab-content" role="tabpanel">
+            <h3 style="margin-bottom: 15px;">🕘 Run History</h3>
+            <p style="margin-bottom: 15px; color: #8899aa; font-size: 0.9em;">
+                Every newsletter run with its window, item counts, source timings and per-recipient delivery results.
+            </p>
+            <button class="btn btn-secondary" onclick="loadRuns()" style="margin-bottom: 15px;" aria-label="Refresh history">
+                <span>🔄 Refresh</span>
+            </button>
+            <div id="runs-list" aria-live="polite"></div>
+        </div>
+
+        <div id="logs-tab" class="tab-content" role="tabpanel">
+            <h3 style="margin-bottom: 15px;">📋 Newsletter Logs</h3>
+            <div class="logs-filters">
+                <select id="log-level" onchange="loadLogs()" aria-label="Minimum level">
+                    <option value="debug">All levels</option>
+                    <option value="info" selected>Info and above</option>
+                    <option value="warn">Warnings and errors</option>
+                    <option value="error">Errors only</option>
+                </select>
+                <select id="log-since" onchange="loadLogs()" aria-label="Time range">
+                    <option value="">Any time</option>
+                    <option value="1h">Last hour</option>
+                    <option value="24h">Last 24 hours</option>
+                    <option value="7d">Last 7 days</option>
+                </select>
+                <input type="text" id="log-run" placeholder="Run ID" onchange="loadLogs()" aria-label="Run ID">
+                <input type="text" id="log-search" placeholder="Search" onchange="loadLogs()" aria-label="Search logs">
+            </div>
+            <button class="btn btn-secondary" onclick="loadLogs()" style="margin-bottom: 15px;" aria-label="Refresh logs">
+                <span>🔄 Refresh Logs</span>
+            </button>
+            <div class="logs-container" id="logs" role="log" aria-live="polite"></div>
+        </div>
+
+        <div id="tokens-tab" class="tab-content" role="tabpanel">
+            <h3 style="margin-bottom: 15px;">🔑 API Tokens</h3>
+            <p style="margin-bottom: 15px; color: #8899aa; font-size: 0.9em;">
+                Tokens let Home Assistant and scripts call the API with <code>Authorization: Bearer &lt;token&gt;</code>.
+                <strong>read</strong> covers status, history and logs; <strong>send</strong> starts and cancels sends; <strong>admin</strong> allows everything, including settings.
+            </p>
+            <div class="action-buttons" style="margin-top: 0; margin-bottom: 15px; align-items: center;">
+                <input type="text" id="new-token-name" placeholder="Home Assistant" aria-label="Token name"
+                    style="flex: 2; padding: 12px; background: #0f1419; border: 2px solid #2a3444; border-radius: 8px; color: #e8e8e8;">
+                <label style="color: #e8e8e8;"><input type="checkbox" data-token-scope="read" checked> read</label>
+                <label style="color: #e8e8e8;"><input type="checkbox" data-token-scope="send"> send</label>
+                <label style="color: #e8e8e8;"><input type="checkbox" data-token-scope="admin"> admin</label>
+                <button class="btn" onclick="createToken()" aria-label="Create token">
+                    <span>➕ Create Token</span>
+                </button>
+            </div>
+            <div id="new-token" class="info-banner" style="display: none;" aria-live="polite"></div>
+            <div id="tokens-list" aria-live="polite"></div>
+        </div>
+
+        <div id="update-tab" class="tab-content" role="tabpanel">
+            <h3 style="margin-bottom: 20px;">🔄 Update Newslettar</h3>
+            
+            <div id="version-info" aria-live="polite">
+                <p>Checking for updates...</p>
+            </div>
+
+            <button class="btn" onclick="checkUpdates()" style="margin-right: 10px;" aria-label="Check for updates">
+                <span>🔍 Check for Updates</span>
+            </button>
+            <button class="btn btn-success" id="update-btn" onclick="performUpdate()" style="display: none;" aria-label="Update now">
+                <span>⬇️ Update Now</span>
+            </button>
+        </div>
+    </div>
+
+    <!-- Preview Modal -->
+    <div id="preview-modal" class="modal" role="dialog" aria-labelledby="preview-title" aria-modal="true">
+        <div class="modal-content">
+            <div class="modal-header">
+                <h2 id="preview-title">Email Preview</h2>
+                <button class="modal-close" onclick="closePreview()" aria-label="Close preview">&times;</button>
+            </div>
+            <div class="modal-body">
+                <iframe id="preview-frame" title="Email preview"></iframe>
+            </div>
+        </div>
+    </div>
+
+    <!-- Loading Overlay -->
+    <div id="loading-overlay" class="loading-overlay" role="status" aria-live="polite">
+        <div class="loading-spinner"></div>
+    </div>
+
+    <script>
+        let logsInterval;
+
+        // Session expired or signed out elsewhere: back to the login page
+        const apiFetch = window.fetch.bind(window);
+        window.fetch = async (...args) => {
+            const resp = await apiFetch(...args);
+            if (resp.status === 401) window.location.href = '/login?next=' + encodeURIComponent(window.location.pathname);
+            return resp;
+        };
+
+        // Keyboard navigation
+        document.addEventListener('keydown', (e) => {
+            if (e.key === 'Escape') {
+                closePreview();
+            }
+        });
+
+        function showTab(tabName) {
+            document.querySelectorAll('.tab').forEach(t => {
+                t.classList.remove('active');
+                t.setAttribute('aria-selected', 'false');
+            });
+            document.querySelectorAll('.tab-content').forEach(t => t.classList.remove('active'));
+            
+            event.target.classList.add('active');
+            event.target.setAttribute('aria-selected', 'true');
+            document.getElementById(tabName + '-tab').classList.add('active');
+
+            if (tabName === 'newsletters') {
+                loadProfiles();
+            }
+
+            if (tabName === 'history') {
+                loadRuns();
+            }
+
+            if (tabName === 'subscribers') {
+                loadSubscribers();
+                loadGroups();
+            }
+
+            if (tabName === 'tokens') {
+                loadTokens();
+            }
+
+            if (tabName === 'logs') {
+                loadLogs();
+                // Streamed over /api/events where supported
+                if (!eventSource) logsInterval = setInterval(loadLogs, 5000);
+            } else {
+                if (logsInterval) {
+                    clearInterval(logsInterval);
+                }
+            }
         }
-        .tab-content {
-            display: none;
-            background: #1a2332;
-            padding: 30px;
-            border-radius: 12px;
-            min-height: 400px;
+
+        // Real-time validation
+        function validateURL(input) {
+            const value = input.value.trim();
+            if (!value) return true;
+            
+            try {
+                new URL(value);
+                return true;
+            } catch {
+                return false;
+            }
         }
-        .tab-content.active { display: block; }
-        .form-group {
-            margin-bottom: 20px;
+
+        function validateEmail(email) {
+            return /^[^\s@]+@[^\s@]+\.[^\s@]+$/.test(email);
         }
-        .form-group label {
-            display: block;
-            margin-bottom: 8px;
-            color: #a0b0c0;
-            font-weight: 500;
+
+        function validateEmails(input) {
+            const value = input.value.trim();
+            if (!value) return true;
+            
+            const emails = value.split(',').map(e => e.trim());
+            return emails.every(email => validateEmail(email));
         }
-        .form-group input, .form-group select {
-            width: 100%;
-            padding: 12px;
-            background: #0f1419;
-            border: 2px solid #2a3444;
-            border-radius: 8px;
-            color: #e8e8e8;
-            font-size: 14px;
-            transition: border-color 0.3s;
+
+        // Add validation listeners
+        document.addEventListener('DOMContentLoaded', () => {
+            const sonarrUrl = document.getElementById('sonarr_url');
+            const radarrUrl = document.getElementById('radarr_url');
+            const fromEmail = document.getElementById('from_email');
+
+            sonarrUrl.addEventListener('blur', function() {
+                if (this.value && !validateURL(this)) {
+                    this.classList.add('error');
+                    this.classList.remove('success');
+                    document.getElementById('sonarr-url-error').classList.add('show');
+                } else if (this.value) {
+                    this.classList.remove('error');
+                    this.classList.add('success');
+                    document.getElementById('sonarr-url-error').classList.remove('show');
+                }
+            });
+
+            radarrUrl.addEventListener('blur', function() {
+                if (this.value && !validateURL(this)) {
+                    this.classList.add('error');
+                    this.classList.remove('success');
+                    document.getElementById('radarr-url-error').classList.add('show');
+                } else if (this.value) {
+                    this.classList.remove('error');
+                    this.classList.add('success');
+                    document.getElementById('radarr-url-error').classList.remove('show');
+                }
+            });
+
+            fromEmail.addEventListener('blur', function() {
+                if (this.value && !validateEmail(this.value)) {
+                    this.classList.add('error');
+                    this.classList.remove('success');
+                    document.getElementById('from-email-error').classList.add('show');
+                } else if (this.value) {
+                    this.classList.remove('error');
+                    this.classList.add('success');
+                    document.getElementById('from-email-error').classList.remove('show');
+                }
+            });
+
+            // Update timezone info on change
+            document.getElementById('timezone').addEventListener('change', updateTimezoneInfo);
+        });
+
+        function generateFeedToken() {
+            const bytes = new Uint8Array(16);
+            crypto.getRandomValues(bytes);
+            document.getElementById('feed_token').value = Array.from(bytes, b => b.toString(16).padStart(2, '0')).join('');
+            updateFeedURLs();
         }
-        .form-group input:focus, .form-group select:focus {
-            outline: none;
-            border-color: #667eea;
+
+        function updateFeedURLs() {
+            const token = document.getElementById('feed_token').value.trim();
+            const info = document.getElementById('feed-urls');
+            if (!token) {
+                info.textContent = 'Feeds are disabled.';
+                return;
+            }
+            const base = (document.getElementById('public_url').value.trim() || window.location.origin).replace(/\/$/, '');
+            // The saved token is never sent back: only a newly entered one can be shown in full
+            const saved = token === loadedConfig.feed_token;
+            const param = saved ? 'YOUR_FEED_TOKEN' : encodeURIComponent(token);
+            info.innerHTML = '📰 Atom feed: <code>' + escapeHTML(base + '/feed.xml?token=' + param) + '</code><br>' +
+                '📅 Calendar: <code>' + escapeHTML(base + '/calendar.ics?token=' + param) + '</code>' +
+                (saved ? '<br>The saved token is hidden; generate a new one to get complete links.' : '');
         }
-        .form-group input.error, .form-group select.error {
-            border-color: #eb3349;
+
+        async function updateTimezoneInfo() {
+            const tz = document.getElementById('timezone').value;
+            try {
+                const resp = await fetch('/api/timezone-info?tz=' + encodeURIComponent(tz));
+                const data = await resp.json();
+                
+                document.getElementById('timezone-info').innerHTML = 
+                    '<strong>Current time:</strong> ' + data.current_time + 
+                    ' <strong>•</strong> Offset: ' + data.offset;
+            } catch (error) {
+                console.error('Failed to fetch timezone info:', error);
+            }
         }
-        .form-group input.success, .form-group select.success {
-            border-color: #38ef7d;
+
+        // Values as loaded, to tell which fields were emptied (and must be cleared) on save
+        let loadedConfig = {};
+
+        async function loadConfig() {
+            showLoading();
+            try {
+                const resp = await fetch('/api/config');
+                const data = await resp.json();
+                loadedConfig = data;
+                
+                document.querySelector('[name="sonarr_url"]').value = data.sonarr_url || '';
+                document.querySelector('[name="sonarr_api_key"]').value = data.sonarr_api_key || '';
+                document.querySelector('[name="radarr_url"]').value = data.radarr_url || '';
+                document.querySelector('[name="radarr_api_key"]').value = data.radarr_api_key || '';
+                document.querySelector('[name="mailgun_smtp"]').value = data.mailgun_smtp || 'smtp.mailgun.org';
+                document.querySelector('[name="mailgun_port"]').value = data.mailgun_port || '587';
+                document.querySelector('[name="mailgun_user"]').value = data.mailgun_user || '';
+                document.querySelector('[name="mailgun_pass"]').value = data.mailgun_pass || '';
+                document.querySelector('[name="from_email"]').value = data.from_email || '';
+                document.querySelector('[name="from_name"]').value = data.from_name || 'Newslettar';
+                document.querySelector('[name="admin_email"]').value = data.admin_email || '';
+                document.querySelector('[name="timezone"]').value = data.timezone || 'UTC';
+                document.querySelector('[name="catchup_grace"]').value = data.catchup_grace || '24h';
+                document.querySelector('[name="signup_enabled"]').value = data.signup_enabled || 'false';
+                document.querySelector('[name="invite_codes"]').value = data.invite_codes || '';
+                document.querySelector('[name="public_url"]').value = data.public_url || '';
+                document.querySelector('[name="archive_access"]').value = data.archive_access || 'signed';
+                document.querySelector('[name="feed_token"]').value = data.feed_token || '';
+                showSettingSources(data.sources || {});
+                updateFeedURLs();
+                
+                document.getElementById('current-timezone').textContent = data.timezone || 'UTC';
+                
+                await updateTimezoneInfo();
+            } catch (error) {
+                showNotification('Failed to load configuration: ' + error.message, 'error');
+            } finally {
+                hideLoading();
+            }
         }
-        .error-message {
-            color: #eb3349;
-            font-size: 0.85em;
-            margin-top: 5px;
-            display: none;
+
+        const settingSourceLabels = {
+            flag: 'command-line flag',
+            env: 'environment variable',
+            env_file: 'secret file (*_FILE)',
+            credential: 'systemd credential',
+            dotenv: '.env',
+            secrets: 'encrypted secrets store',
+            default: 'default'
+        };
+
+        // Note under each field where its value comes from; flags and the environment win over saved values
+        function showSettingSources(sources) {
+            Object.entries(sources).forEach(([field, source]) => {
+                const input = document.querySelector('[name="' + field + '"]');
+                if (!input) return;
+                const group = input.closest('.form-group');
+                let note = group.querySelector('.setting-source');
+                if (!note) {
+                    note = document.createElement('div');
+                    note.className = 'setting-source';
+                    group.appendChild(note);
+                }
+                const overridden = ['flag', 'env', 'env_file', 'credential'].includes(source);
+                note.textContent = 'Source: ' + (settingSourceLabels[source] || source) +
+                    (overridden ? ' (changes saved here have no effect while it is set)' : '');
+                note.classList.toggle('overridden', overridden);
+            });
         }
-        .error-message.show { display: block; }
-        .btn {
-            padding: 12px 24px;
-            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
-            color: white;
-            border: none;
-            border-radius: 8px;
-            cursor: pointer;
-            font-size: 14px;
-            font-weight: 600;
-            transition: transform 0.2s, opacity 0.3s;
-            position: relative;
+
+        document.getElementById('config-form').addEventListener('submit', async (e) => {
+            e.preventDefault();
+            
+            const formData = new FormData(e.target);
+            const data = Object.fromEntries(formData);
+            // Empty values are ignored by the server: emptied fields have to be cleared explicitly
+            data.clear = Object.keys(data).filter(key => data[key] === '' && loadedConfig[key]);
+            
+            const submitBtn = e.target.querySelector('button[type="submit"]');
+            submitBtn.classList.add('loading');
+            submitBtn.disabled = true;
+            
+            try {
+                const resp = await fetch('/api/config', {
+                    method: 'POST',
+                    headers: {'Content-Type': 'application/json'},
+                    body: JSON.stringify(data)
+                });
+
+                if (resp.ok) {
+                    const result = await resp.json();
+                    if (result.overridden && result.overridden.length) {
+                        showNotification('Saved, but overridden by flags or the environment: ' + result.overridden.join(', '), 'warning');
+                    } else {
+                        showNotification('Configuration saved successfully!', 'success');
+                    }
+                    setTimeout(() => location.reload(), 2000);
+                } else {
+                    showNotification('Failed to save configuration: ' + await resp.text(), 'error');
+                }
+            } catch (error) {
+                showNotification('Network error: ' + error.message, 'error');
+            } finally {
+                submitBtn.classList.remove('loading');
+                submitBtn.disabled = false;
+            }
+        });
+
+        async function testConnection(type) {
+            const form = document.getElementById('config-form');
+            const formData = new FormData(form);
+            const data = Object.fromEntries(formData);
+            
+            const button = event.target.closest('button');
+            button.classList.add('loading');
+            button.disabled = true;
+
+            let endpoint, payload;
+
+            if (type === 'sonarr') {
+                endpoint = '/api/test-sonarr';
+                payload = { url: data.sonarr_url, api_key: data.sonarr_api_key };
+            } else if (type === 'radarr') {
+                endpoint = '/api/test-radarr';
+                payload = { url: data.radarr_url, api_key: data.radarr_api_key };
+            } else {
+                endpoint = '/api/test-email';
+                payload = {
+                    smtp: data.mailgun_smtp,
+                    port: data.mailgun_port,
+                    user: data.mailgun_user,
+                    pass: data.mailgun_pass
+                };
+            }
+
+            try {
+                const resp = await fetch(endpoint, {
+                    method: 'POST',
+                    headers: {'Content-Type': 'application/json'},
+                    body: JSON.stringify(payload)
+                });
+
+                const result = await resp.json();
+                showNotification(result.message, result.success ? 'success' : 'error');
+            } catch (error) {
+                showNotification('Connection test failed: ' + error.message, 'error');
+            } finally {
+                button.classList.remove('loading');
+                button.disabled = false;
+            }
         }
-        .btn:hover { transform: translateY(-2px); opacity: 0.9; }
-        .btn:active { transform: translateY(0); }
-        .btn:disabled {
-            opacity: 0.5;
-            cursor: not-allowed;
-            transform: none;
+
+        async function previewNewsletter(profileId) {
+            const button = event.target.closest('button');
+            button.classList.add('loading');
+            button.disabled = true;
+            
+            showLoading();
+
+            try {
+                const resp = await fetch('/api/preview?profile=' + encodeURIComponent(profileId), { method: 'POST' });
+                const data = await resp.json();
+
+                if (data.success) {
+                    const iframe = document.getElementById('preview-frame');
+                    iframe.srcdoc = data.html;
+                    document.getElementById('preview-modal').classList.add('show');
+                } else {
+                    showNotification(data.error || 'Failed to generate preview', 'error');
+                }
+            } catch (error) {
+                showNotification('Preview failed: ' + error.message, 'error');
+            } finally {
+                button.classList.remove('loading');
+                button.disabled = false;
+                hideLoading();
+            }
         }
-        .btn:focus { outline: 2px solid #667eea; outline-offset: 2px; }
-        .btn-secondary {
-            background: #2a3444;
+
+        function closePreview() {
+            document.getElementById('preview-modal').classList.remove('show');
         }
-        .btn-success {
-            background: linear-gradient(135deg, #11998e 0%, #38ef7d 100%);
+
+        async function sendNow(profileId) {
+            if (!confirm('Send newsletter now?')) return;
+            
+            const button = event.target.closest('button');
+            button.classList.add('loading');
+            button.disabled = true;
+            
+            showNotification('Sending newsletter...', 'success');
+            
+            try {
+                const resp = await fetch('/api/send?profile=' + encodeURIComponent(profileId), { method: 'POST' });
+                const data = await resp.json();
+
+                if (!data.job_id) {
+                    showNotification(data.message || 'Failed to send newsletter', 'error');
+                    return;
+                }
+                if (!data.success) {
+                    showNotification(data.message + ', following the running send', 'error');
+                }
+                await watchJob(data.job_id, button.closest('.action-buttons'));
+            } catch (error) {
+                showNotification('Send failed: ' + error.message, 'error');
+            } finally {
+                button.classList.remove('loading');
+                button.disabled = false;
+            }
         }
-        .btn-danger {
-            background: linear-gradient(135deg, #eb3349 0%, #f45c43 100%);
+
+        // Follow a send job until it finishes: live progress from /api/events,
+        // with polling as a backup for missed events and browsers without EventSource
+        async function watchJob(jobId, container) {
+            const view = document.createElement('div');
+            view.className = 'job-progress';
+            container.after(view);
+
+            const state = { step: 'Starting', sources: {}, deliveries: [], processed: 0, recipients: 0, cancelling: false };
+            let finish;
+            const finished = new Promise(resolve => finish = resolve);
+
+            const render = () => {
+                let html = '<div style="display: flex; justify-content: space-between; align-items: center; gap: 10px;">';
+                html += '<strong>⏳ ' + escapeHTML(state.step) + '</strong>';
+                html += state.cancelling
+                    ? '<span>Cancelling...</span>'
+                    : '<button class="btn btn-danger" onclick="cancelJob(\'' + escapeHTML(jobId) + '\')"><span>⏹️ Cancel</span></button>';
+                html += '</div>';
+                Object.entries(state.sources).forEach(([source, result]) => {
+                    html += '<p>' + escapeHTML(source) + ': ' + escapeHTML(result) + '</p>';
+                });
+                if (state.recipients) {
+                    const percent = Math.round(100 * state.processed / state.recipients);
+                    html += '<div class="progress-bar"><div style="width: ' + percent + '%;"></div></div>';
+                    html += '<p>📧 ' + state.processed + ' / ' + state.recipients + ' recipients</p>';
+                    state.deliveries.slice(-5).forEach(d => {
+                        const icon = d.status === 'sent' ? '✅' : d.status === 'failed' ? '❌' : '⏭️';
+                        html += '<p>' + icon + ' ' + escapeHTML(d.email) + (d.error ? ' — ' + escapeHTML(d.error) : '') + '</p>';
+                    });
+                }
+                view.innerHTML = html;
+            };
+
+            const listener = event => {
+                if (event.job_id !== jobId) return;
+                switch (event.kind) {
+                    case 'step':
+                        state.step = event.step;
+                        break;
+                    case 'source_started':
+                        state.sources[event.source] = '⏳ fetching';
+                        break;
+                    case 'source_finished':
+                        state.sources[event.source] = event.error ? '❌ ' + event.error : '✓ ' + (event.items || 0) + ' item(s)';
+                        break;
+                    case 'delivery':
+                        state.deliveries.push(event);
+                        state.processed = event.processed;
+                        state.recipients = event.recipients;
+                        break;
+                    case 'finished':
+                        finish();
+                        return;
+                }
+                render();
+            };
+
+            const poll = async () => {
+                try {
+                    const resp = await fetch('/api/jobs/' + encodeURIComponent(jobId));
+                    if (!resp.ok) throw new Error(await resp.text());
+                    const job = await resp.json();
+                    if (job.status !== 'running') return finish();
+                    if (job.steps.length) state.step = job.steps[job.steps.length - 1].name;
+                    state.processed = job.processed;
+                    state.recipients = job.recipients;
+                    state.cancelling = !!job.cancel_requested;
+                    render();
+                } catch (error) {
+                    finish();
+                }
+            };
+
+            progressListeners.add(listener);
+            const timer = setInterval(poll, eventSource ? 5000 : 1000);
+            render();
+            poll();
+
+            try {
+                await finished;
+                const resp = await fetch('/api/jobs/' + encodeURIComponent(jobId));
+                if (!resp.ok) throw new Error(await resp.text());
+                const job = await resp.json();
+                const ok = job.status === 'success' || job.status === 'skipped';
+                const kind = job.error_kind ? ' (' + (ERROR_KIND_LABELS[job.error_kind] || job.error_kind) + ')' : '';
+                showNotification((RUN_STATUS_LABELS[job.status] || job.status) + kind + (job.message ? ': ' + job.message : ''), ok ? 'success' : 'error');
+            } finally {
+                clearInterval(timer);
+                progressListeners.delete(listener);
+                view.remove();
+            }
         }
-        .btn.loading::after {
-            content: "";
-            position: absolute;
-            width: 16px;
-            height: 16px;
-            top: 50%;
-            left: 50%;
-            margin-left: -8px;
-            margin-top: -8px;
-            border: 2px solid #ffffff40;
-            border-top-color: #fff;
-            border-radius: 50%;
-            animation: spin 0.6s linear infinite;
+
+        async function cancelJob(jobId) {
+            try {
+                const resp = await fetch('/api/jobs/' + encodeURIComponent(jobId) + '/cancel', { method: 'POST' });
+                if (!resp.ok) throw new Error(await resp.text());
+                showNotification('Cancelling after the current recipient...', 'success');
+            } catch (error) {
+                showNotification('Cancel failed: ' + error.message, 'error');
+            }
         }
-        .btn.loading span { opacity: 0; }
-        @keyframes spin {
-            to { transform: rotate(360deg); }
+
+        function escapeHTML(value) {
+            const div = document.createElement('div');
+            div.textContent = value == null ? '' : String(value);
+            return div.innerHTML;
         }
-        .notification {
-            position: fixed;
-            top: 20px;
-            right: 20px;
-            padding: 16px 24px;
-            border-radius: 10px;
-            color: white;
-            font-weight: 500;
-            animation: slideIn 0.3s;
-            z-index: 1000;
-            max-width: 400px;
+
+        const RUN_STATUS_LABELS = {
+            running: '⏳ Running',
+            success: '✅ Sent',
+            partial: '⚠️ Partially sent',
+            skipped: 'ℹ️ Nothing to send',
+            failed: '❌ Failed',
+            cancelled: '⏹️ Cancelled'
+        };
+
+        const ERROR_KIND_LABELS = {
+            config: 'Configuration',
+            upstream: 'Sonarr/Radarr',
+            render: 'Template',
+            delivery: 'Delivery',
+            internal: 'Internal error'
+        };
+
+        async function loadRuns() {
+            try {
+                const resp = await fetch('/api/runs');
+                const data = await resp.json();
+                const list = document.getElementById('runs-list');
+
+                if (!data.runs.length) {
+                    list.innerHTML = '<p style="color: #8899aa;">No runs recorded yet.</p>';
+                    return;
+                }
+
+                let html = '';
+                data.runs.forEach(run => {
+                    const sent = (run.deliveries || []).filter(d => d.status === 'sent').length;
+                    html += '<div class="schedule-info">';
+                    html += '<h3>' + escapeHTML(run.profile_name) + ' — ' + (RUN_STATUS_LABELS[run.status] || escapeHTML(run.status)) + '</h3>';
+                    html += '<p>🕘 ' + new Date(run.started_at).toLocaleString() + ' • ' + escapeHTML(run.trigger);
+                    if (run.finished_at) html += ' • ' + ((new Date(run.finished_at) - new Date(run.started_at)) / 1000).toFixed(1) + 's';
+                    html += '</p>';
+                    if (run.message) html += '<p>' + (run.error_kind ? '<strong>' + escapeHTML(ERROR_KIND_LABELS[run.error_kind] || run.error_kind) + ':</strong> ' : '') + escapeHTML(run.message) + '</p>';
+                    if (run.window_start && !run.window_start.startsWith('0001')) {
+                        html += '<p>🪟 ' + new Date(run.window_start).toLocaleString() + ' → ' + new Date(run.window_end).toLocaleString() + '</p>';
+                    }
+                    const counts = run.counts || {};
+                    html += '<p>📊 ' + Object.entries(SECTION_LABELS).map(([key, label]) => label + ': ' + (counts[key] || 0)).join(' • ') + '</p>';
+                    html += '<p>📧 ' + sent + ' of ' + (run.deliveries || []).length + ' recipient(s) sent</p>';
+
+                    html += '<details style="margin-top: 10px;"><summary style="cursor: pointer; color: #a0b0c0;">Details</summary><div style="margin-top: 10px; font-size: 0.9em;">';
+                    (run.sources || []).forEach(src => {
+                        html += '<p>' + (src.error ? '❌ ' : '✓ ') + escapeHTML(src.name) + ': ' + src.items + ' item(s) in ' + src.duration_ms + 'ms';
+                        if (src.error) html += ' — ' + escapeHTML(src.error);
+                        html += '</p>';
+                    });
+                    (run.deliveries || []).forEach((d, i) => {
+                        html += '<p>' + (d.status === 'sent' ? '✅ ' : d.status === 'failed' ? '❌ ' : '⏭️ ') + escapeHTML(d.email);
+                        if (d.error) html += ' — ' + escapeHTML(d.error);
+                        if (d.custom_html) html += ' • <a href="/api/runs/' + encodeURIComponent(run.id) + '/html?delivery=' + i + '" target="_blank" style="color: #667eea;">view their email</a>';
+                        html += '</p>';
+                    });
+                    html += '<p><a href="/api/logs?level=debug&run_id=' + encodeURIComponent(run.id) + '&format=text" target="_blank" style="color: #667eea;">📋 Logs of this run</a></p>';
+                    html += '</div></details>';
+
+                    if (run.has_html) {
+                        html += '<div class="action-buttons"><a class="btn btn-secondary" style="text-decoration: none;" target="_blank" href="/api/runs/' + encodeURIComponent(run.id) + '/html"><span>👁️ View Email</span></a></div>';
+                    }
+                    html += '</div>';
+                });
+                list.innerHTML = html;
+            } catch (error) {
+                showNotification('Failed to load history: ' + error.message, 'error');
+            }
         }
-        .notification.success {
-            background: linear-gradient(135deg, #11998e 0%, #38ef7d 100%);
+
+        async function loadSubscribers() {
+            try {
+                const resp = await fetch('/api/subscribers');
+                const subs = await resp.json();
+                const list = document.getElementById('subscribers-list');
+
+                if (!subs.length) {
+                    list.innerHTML = '<p style="color: #8899aa;">No subscribers yet.</p>';
+                    return;
+                }
+
+                let html = '';
+                subs.forEach(sub => {
+                    html += '<div class="template-option">';
+                    html += '<div><strong>' + escapeHTML(sub.email) + '</strong>';
+                    html += '<p style="font-size: 0.9em; color: #8899aa; margin-top: 5px;">';
+                    html += (sub.status === 'active' ? '✅ Active' : '⏳ Pending confirmation');
+                    html += ' • Joined ' + new Date(sub.created_at).toLocaleDateString();
+                    if (sub.invite_code) html += ' • Invite: ' + escapeHTML(sub.invite_code);
+                    html += '</p></div>';
+                    html += '<div style="display: flex; gap: 10px; align-items: center;">';
+                    html += '<select data-email="' + escapeHTML(sub.email) + '" class="sub-rating" onchange="saveSubscriber(this.dataset.email)" aria-label="Max rating"';
+                    html += ' style="padding: 8px; background: #0f1419; border: 2px solid #2a3444; border-radius: 8px; color: #e8e8e8;">';
+                    RATING_CHOICES.forEach(([value, label]) => {
+                        html += '<option value="' + value + '"' + (sub.max_rating === value ? ' selected' : '') + '>' + label + '</option>';
+                    });
+                    html += '</select>';
+                    html += '<input type="text" class="sub-groups" data-email="' + escapeHTML(sub.email) + '" value="' + escapeHTML((sub.groups || []).join(', ')) + '"';
+                    html += ' placeholder="groups" onchange="saveSubscriber(this.dataset.email)" aria-label="Groups"';
+                    html += ' style="width: 140px; padding: 8px; background: #0f1419; border: 2px solid #2a3444; border-radius: 8px; color: #e8e8e8;">';
+                    html += '<input type="text" class="sub-timezone" data-email="' + escapeHTML(sub.email) + '" value="' + escapeHTML(sub.timezone || '') + '"';
+                    html += ' placeholder="timezone" onchange="saveSubscriber(this.dataset.email)" aria-label="Timezone"';
+                    html += ' style="width: 140px; padding: 8px; background: #0f1419; border: 2px solid #2a3444; border-radius: 8px; color: #e8e8e8;">';
+                    html += '<label style="font-size: 0.85em; color: #8899aa; white-space: nowrap;"><input type="checkbox" class="sub-unrated" data-email="' + escapeHTML(sub.email) + '"';
+                    html += (sub.allow_unrated ? ' checked' : '') + ' onchange="saveSubscriber(this.dataset.email)"> unrated</label>';
+                    if (sub.preferences_url) {
+                        html += '<a class="btn btn-secondary" style="text-decoration: none;" target="_blank" href="' + escapeHTML(sub.preferences_url) + '"><span>⭐ Follows</span></a>';
+                    }
+                    if (sub.status === 'active') {
+                        html += '<button class="btn btn-secondary" data-email="' + escapeHTML(sub.email) + '" onclick="rotatePreferencesLink(this.dataset.email)" title="Issue a new preferences link; links already sent stop working"><span>🔄 New link</span></button>';
+                    }
+                    html += '<button class="btn btn-danger" data-email="' + escapeHTML(sub.email) + '" onclick="removeSubscriber(this.dataset.email)"><span>Remove</span></button>';
+                    html += '</div></div>';
+                });
+                list.innerHTML = html;
+            } catch (error) {
+                showNotification('Failed to load subscribers: ' + error.message, 'error');
+            }
         }
-        .notification.error {
-            background: linear-gradient(135deg, #eb3349 0%, #f45c43 100%);
+
+        const RATING_CHOICES = [
+            ['', 'No limit'],
+            ['G', 'G / TV-G'],
+            ['TV-Y7', 'TV-Y7'],
+            ['PG', 'PG / TV-PG'],
+            ['PG-13', 'PG-13 / TV-14'],
+            ['R', 'R / TV-MA']
+        ];
+
+        async function postSubscriber(payload) {
+            const resp = await fetch('/api/subscribers', {
+                method: 'POST',
+                headers: {'Content-Type': 'application/json'},
+                body: JSON.stringify(payload)
+            });
+            if (!resp.ok) throw new Error(await resp.text());
         }
-        @keyframes slideIn {
-            from { transform: translateX(400px); opacity: 0; }
-            to { transform: translateX(0); opacity: 1; }
+
+        async function addSubscriber() {
+            const input = document.getElementById('new-subscriber');
+            if (!validateEmail(input.value.trim())) {
+                showNotification('Please enter a valid email address', 'error');
+                return;
+            }
+
+            try {
+                await postSubscriber({ email: input.value.trim() });
+                input.value = '';
+                showNotification('Subscriber added', 'success');
+                loadSubscribers();
+            } catch (error) {
+                showNotification('Failed to add subscriber: ' + error.message, 'error');
+            }
         }
-        .logs-container {
-            background: #0f1419;
-            padding: 20px;
-            border-radius: 8px;
-            font-family: 'Courier New', monospace;
-            font-size: 13px;
-            max-height: 500px;
-            overflow-y: auto;
-            white-space: pre-wrap;
-            border: 2px solid #2a3444;
+
+        async function rotatePreferencesLink(email) {
+            if (!confirm('Issue a new preferences link for ' + email + '? Links in newsletters already sent will stop working.')) return;
+            try {
+                await postSubscriber({email: email, rotate_preferences: true});
+                showNotification('New preferences link issued', 'success');
+                loadSubscribers();
+            } catch (error) {
+                showNotification('Failed to issue a new link: ' + error.message, 'error');
+            }
         }
-        .schedule-info {
-            background: #252f3f;
-            padding: 20px;
-            border-radius: 10px;
-            margin-bottom: 20px;
-            border-left: 4px solid #667eea;
+
+        async function saveSubscriber(email) {
+            const rating = [...document.querySelectorAll('.sub-rating')].find(el => el.dataset.email === email);
+            const unrated = [...document.querySelectorAll('.sub-unrated')].find(el => el.dataset.email === email);
+            const groups = [...document.querySelectorAll('.sub-groups')].find(el => el.dataset.email === email);
+            const timezone = [...document.querySelectorAll('.sub-timezone')].find(el => el.dataset.email === email);
+
+            try {
+                await postSubscriber({
+                    email: email,
+                    max_rating: rating.value,
+                    allow_unrated: unrated.checked,
+                    groups: groups.value.split(',').map(g => g.trim()).filter(g => g),
+                    timezone: timezone.value.trim()
+                });
+                showNotification('Subscriber updated', 'success');
+            } catch (error) {
+                showNotification('Failed to update subscriber: ' + error.message, 'error');
+            }
         }
-        .schedule-info h3 {
-            margin-bottom: 10px;
-            color: #667eea;
+
+        const RELEASE_TYPE_LABELS = {
+            cinema: '🎟️ In cinemas',
+            digital: '💻 Digital',
+            physical: '📀 Physical'
+        };
+        const DEFAULT_RELEASE_TYPES = ['digital', 'physical'];
+
+        const SECTION_LABELS = {
+            upcoming_series: 'Upcoming TV',
+            upcoming_movies: 'Upcoming Movies',
+            downloaded_series: 'Downloaded TV',
+            downloaded_movies: 'Downloaded Movies'
+        };
+
+        let profilesState = [];
+        let templateNames = [];
+        let schedulePresets = [];
+
+        async function loadProfiles() {
+            try {
+                const resp = await fetch('/api/profiles');
+                const data = await resp.json();
+                profilesState = data.profiles;
+                templateNames = data.templates;
+                schedulePresets = data.presets;
+                renderProfiles();
+            } catch (error) {
+                showNotification('Failed to load newsletters: ' + error.message, 'error');
+            }
         }
-        .toggle-switch {
-            position: relative;
-            display: inline-block;
-            width: 50px;
-            height: 26px;
-            margin-left: 10px;
+
+        function profileField(label, html) {
+            return '<div class="form-group"><label>' + label + '</label>' + html + '</div>';
         }
-        .toggle-switch input {
-            opacity: 0;
-            width: 0;
-            height: 0;
+
+        function renderProfiles() {
+            let html = '';
+            profilesState.forEach((p, i) => {
+                const t = p.transport || {};
+                html += '<div class="schedule-info" data-index="' + i + '">';
+                html += '<h3>' + escapeHTML(p.name || 'New Newsletter') + '</h3>';
+                if (p.next_runs && p.next_runs.length) {
+                    html += '<p style="margin-bottom: 15px; color: #8899aa;">⏰ Next runs: ' + p.next_runs.map(escapeHTML).join(' • ') + '</p>';
+                } else if (p.next_run) {
+                    html += '<p style="margin-bottom: 15px; color: #8899aa;">⏰ ' + escapeHTML(p.next_run) + '</p>';
+                }
+
+                html += profileField('Name', '<input type="text" data-field="name" value="' + escapeHTML(p.name) + '">');
+                html += '<div class="template-option"><strong>Enabled</strong><label class="toggle-switch"><input type="checkbox" data-field="enabled"' + (p.enabled ? ' checked' : '') + '><span class="toggle-slider"></span></label></div>';
+
+                let schedules = '';
+                (p.schedules || []).forEach((spec, j) => {
+                    const description = (p.schedule_descriptions || [])[j] || '';
+                    schedules += scheduleRow(spec, description);
+                });
+                let presets = '<option value="">➕ Add schedule…</option><option value="custom">Custom cron expression</option>';
+                schedulePresets.forEach(preset => {
+                    presets += '<option value="' + escapeHTML(preset.expr) + '">' + escapeHTML(preset.name) + '</option>';
+                });
+                html += profileField('Schedules (cron: minute hour day-of-month month day-of-week)',
+                    '<div data-schedules>' + schedules + '</div>' +
+                    '<select onchange="addSchedule(this)">' + presets + '</select>' +
+                    '<p style="margin-top: 8px; color: #8899aa; font-size: 0.85em;">Examples: <code>0 9 * * 0</code> (Sundays 09:00), <code>30 7 * * 1-5</code>, <code>0 9 * * 1#1</code> (first Monday), <code>@daily</code>.</p>');
+                if (p.window_start) html += '<p style="margin-bottom: 15px; color: #8899aa;">🪟 Next send covers downloads since ' + escapeHTML(p.window_start) + '</p>';
+                html += profileField('Lookback for the first send (e.g. 7d, 36h; later sends start where the last one ended)', '<input type="text" data-field="lookback" value="' + escapeHTML(p.lookback || '7d') + '">');
+                html += profileField('Lookahead for upcoming releases (e.g. 7d, 14d)', '<input type="text" data-field="lookahead" value="' + escapeHTML(p.lookahead || '7d') + '">');
+
+                let sections = '';
+                Object.entries(SECTION_LABELS).forEach(([value, label]) => {
+                    const checked = (p.sections || []).includes(value) ? ' checked' : '';
+                    sections += '<label style="display: inline-block; margin-right: 15px; color: #e8e8e8;"><input type="checkbox" data-section="' + value + '"' + checked + '> ' + label + '</label>';
+                });
+                html += profileField('Sections', '<div>' + sections + '</div>');
+
+                const releaseTypes = (p.release_types && p.release_types.length) ? p.release_types : DEFAULT_RELEASE_TYPES;
+                let releases = '';
+                Object.entries(RELEASE_TYPE_LABELS).forEach(([value, label]) => {
+                    const checked = releaseTypes.includes(value) ? ' checked' : '';
+                    releases += '<label style="display: inline-block; margin-right: 15px; color: #e8e8e8;"><input type="checkbox" data-release-type="' + value + '"' + checked + '> ' + label + '</label>';
+                });
+                html += profileField('Movie releases counted as upcoming', '<div>' + releases + '</div>');
+                html += '<div class="template-option"><strong>Show Movie/Series Posters</strong><label class="toggle-switch"><input type="checkbox" data-field="show_posters"' + (p.show_posters ? ' checked' : '') + '><span class="toggle-slider"></span></label></div>';
+                html += '<div class="template-option"><strong>Attach calendar (.ics) of upcoming releases</strong><label class="toggle-switch"><input type="checkbox" data-field="attach_calendar"' + (p.attach_calendar ? ' checked' : '') + '><span class="toggle-slider"></span></label></div>';
+
+                let templates = '';
+                templateNames.forEach(name => {
+                    templates += '<option value="' + escapeHTML(name) + '"' + (p.template === name ? ' selected' : '') + '>' + escapeHTML(name) + '</option>';
+                });
+                html += profileField('Template', '<select data-field="template">' + templates + '</select>');
+
+                html += profileField('Recipients (comma-separated)', '<input type="text" data-field="recipients" value="' + escapeHTML((p.recipients || []).join(', ')) + '" placeholder="user@example.com, user2@example.com">');
+                html += '<div class="template-option"><strong>Send to all subscribers</strong><label class="toggle-switch"><input type="checkbox" data-field="all_subscribers"' + (p.all_subscribers ? ' checked' : '') + '><span class="toggle-slider"></span></label></div>';
+                html += profileField('Subscriber groups (comma-separated, used when not sending to all subscribers)', '<input type="text" data-field="groups" value="' + escapeHTML((p.groups || []).join(', ')) + '">');
+
+                html += '<details style="margin-bottom: 20px;"><summary style="cursor: pointer; color: #a0b0c0;">SMTP overrides</summary><div style="margin-top: 15px;">';
+                html += profileField('SMTP Server', '<input type="text" data-transport="host" value="' + escapeHTML(t.host || '') + '">');
+                html += profileField('SMTP Port', '<input type="number" data-transport="port" value="' + escapeHTML(t.port || '') + '">');
+                html += profileField('SMTP Username', '<input type="text" data-transport="user" value="' + escapeHTML(t.user || '') + '">');
+                html += profileField('SMTP Password', '<input type="password" data-transport="pass" value="' + escapeHTML(t.pass || '') + '">');
+                html += profileField('From Name', '<input type="text" data-transport="from_name" value="' + escapeHTML(t.from_name || '') + '">');
+                html += profileField('From Email', '<input type="email" data-transport="from_email" value="' + escapeHTML(t.from_email || '') + '">');
+                html += '</div></details>';
+
+                html += '<div class="action-buttons">';
+                html += '<button class="btn" onclick="saveProfile(' + i + ')"><span>💾 Save</span></button>';
+                if (p.id) {
+                    html += '<button class="btn btn-secondary" onclick="previewNewsletter(\'' + p.id + '\')"><span>👁️ Preview</span></button>';
+                    html += '<button class="btn btn-success" onclick="sendNow(\'' + p.id + '\')"><span>📧 Send Now</span></button>';
+                }
+                html += '<button class="btn btn-danger" onclick="deleteProfile(' + i + ')"><span>🗑️ Delete</span></button>';
+                html += '</div></div>';
+            });
+            document.getElementById('profiles-list').innerHTML = html || '<p style="color: #8899aa;">No newsletters yet.</p>';
         }
-        .toggle-slider {
-            position: absolute;
-            cursor: pointer;
-            top: 0;
-            left: 0;
-            right: 0;
-            bottom: 0;
-            background-color: #2a3444;
-            transition: 0.3s;
-            border-radius: 26px;
+
+        function scheduleRow(spec, description) {
+            return '<div class="schedule-row" style="display: flex; gap: 10px; align-items: center; margin-bottom: 8px;">' +
+                '<input type="text" data-schedule value="' + escapeHTML(spec) + '" onchange="describeSchedule(this)" style="flex: 1; font-family: monospace;">' +
+                '<span class="schedule-description" style="flex: 1; color: #8899aa; font-size: 0.9em;">' + escapeHTML(description) + '</span>' +
+                '<button class="btn btn-danger" style="padding: 8px 12px;" onclick="this.parentElement.remove()" aria-label="Remove schedule">✕</button>' +
+                '</div>';
         }
-        .toggle-slider:before {
-            position: absolute;
-            content: "";
-            height: 20px;
-            width: 20px;
-            left: 3px;
-            bottom: 3px;
-            background-color: white;
-            transition: 0.3s;
-            border-radius: 50%;
+
+        function addSchedule(select) {
+            const value = select.value;
+            select.value = '';
+            if (!value) return;
+
+            const container = select.parentElement.querySelector('[data-schedules]');
+            container.insertAdjacentHTML('beforeend', scheduleRow(value === 'custom' ? '' : value, ''));
+            const input = container.lastElementChild.querySelector('input');
+            if (value === 'custom') {
+                input.focus();
+            } else {
+                describeSchedule(input);
+            }
         }
-        input:checked + .toggle-slider {
-            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
+
+        async function describeSchedule(input) {
+            const label = input.parentElement.querySelector('.schedule-description');
+            if (!input.value.trim()) {
+                label.textContent = '';
+                return;
+            }
+            try {
+                const resp = await fetch('/api/schedule-info?expr=' + encodeURIComponent(input.value));
+                const data = await resp.json();
+                if (data.valid) {
+                    input.classList.remove('error');
+                    label.style.color = '#8899aa';
+                    label.textContent = data.description + (data.next_runs.length ? ' — next: ' + data.next_runs[0] : '');
+                } else {
+                    input.classList.add('error');
+                    label.style.color = '#eb3349';
+                    label.textContent = '❌ ' + data.error;
+                }
+            } catch (error) {
+                label.textContent = '';
+            }
         }
-        input:checked + .toggle-slider:before {
-            transform: translateX(24px);
+
+        function addProfile() {
+            profilesState.push({
+                name: '', enabled: true, schedules: ['0 9 * * 0'], schedule_descriptions: ['Every Sunday at 09:00'],
+                lookback: '7d', lookahead: '7d', sections: Object.keys(SECTION_LABELS), release_types: DEFAULT_RELEASE_TYPES,
+                show_posters: true, template: 'email.html', recipients: [], all_subscribers: true, groups: [], transport: {}
+            });
+            renderProfiles();
         }
-        .template-option {
-            display: flex;
-            align-items: center;
-            justify-content: space-between;
-            padding: 15px;
-            background: #252f3f;
-            border-radius: 8px;
-            margin-bottom: 12px;
+
+        async function saveProfile(index) {
+            const card = document.querySelector('#profiles-list [data-index="' + index + '"]');
+            const field = name => card.querySelector('[data-field="' + name + '"]');
+            const list = value => value.split(',').map(v => v.trim()).filter(v => v);
+
+            const recipients = field('recipients');
+            if (!validateEmails(recipients)) {
+                showNotification('Please enter valid recipient email addresses', 'error');
+                return;
+            }
+
+            const transport = {};
+            card.querySelectorAll('[data-transport]').forEach(el => {
+                if (el.value.trim()) transport[el.dataset.transport] = el.value.trim();
+            });
+
+            const profile = {
+                id: profilesState[index].id || '',
+                name: field('name').value.trim(),
+                enabled: field('enabled').checked,
+                schedules: [...card.querySelectorAll('[data-schedule]')].map(el => el.value.trim()).filter(v => v),
+                lookback: field('lookback').value.trim(),
+                lookahead: field('lookahead').value.trim(),
+                sections: [...card.querySelectorAll('[data-section]')].filter(el => el.checked).map(el => el.dataset.section),
+                release_types: [...card.querySelectorAll('[data-release-type]')].filter(el => el.checked).map(el => el.dataset.releaseType),
+                show_posters: field('show_posters').checked,
+                attach_calendar: field('attach_calendar').checked,
+                template: field('template').value,
+                recipients: list(recipients.value),
+                all_subscribers: field('all_subscribers').checked,
+                groups: list(field('groups').value),
+                transport: transport
+            };
+
+            try {
+                const resp = await fetch('/api/profiles', {
+                    method: 'POST',
+                    headers: {'Content-Type': 'application/json'},
+                    body: JSON.stringify(profile)
+                });
+                if (!resp.ok) throw new Error(await resp.text());
+                showNotification('Newsletter saved', 'success');
+                loadProfiles();
+            } catch (error) {
+                showNotification('Failed to save newsletter: ' + error.message, 'error');
+            }
         }
-        .timezone-info {
-            background: #252f3f;
-            padding: 15px;
-            border-radius: 8px;
-            margin-top: 10px;
-            font-size: 0.9em;
+
+        async function deleteProfile(index) {
+            const p = profilesState[index];
+            if (!p.id) {
+                profilesState.splice(index, 1);
+                renderProfiles();
+                return;
+            }
+            if (!confirm('Delete newsletter "' + p.name + '"?')) return;
+
+            try {
+                const resp = await fetch('/api/profiles?id=' + encodeURIComponent(p.id), { method: 'DELETE' });
+                if (!resp.ok) throw new Error(await resp.text());
+                showNotification('Newsletter deleted', 'success');
+                loadProfiles();
+            } catch (error) {
+                showNotification('Failed to delete newsletter: ' + error.message, 'error');
+            }
         }
-        .timezone-info strong {
-            color: #667eea;
+
+        let groupsState = [];
+
+        async function loadGroups() {
+            try {
+                const resp = await fetch('/api/groups');
+                const data = await resp.json();
+                groupsState = data.groups;
+                document.getElementById('available-tags').textContent =
+                    data.available_tags.length ? data.available_tags.join(', ') : 'none found in Sonarr/Radarr';
+                renderGroups();
+            } catch (error) {
+                showNotification('Failed to load groups: ' + error.message, 'error');
+            }
         }
-        .info-banner {
-            background: #252f3f;
-            padding: 15px 20px;
-            border-radius: 8px;
-            margin-bottom: 20px;
-            border-left: 4px solid #11998e;
+
+        function renderGroups() {
+            const inputStyle = 'padding: 8px; background: #0f1419; border: 2px solid #2a3444; border-radius: 8px; color: #e8e8e8;';
+            let html = '';
+            groupsState.forEach((group, i) => {
+                html += '<div class="template-option" style="gap: 10px;">';
+                html += '<input type="text" value="' + escapeHTML(group.name) + '" placeholder="Group name" aria-label="Group name"';
+                html += ' onchange="groupsState[' + i + '].name = this.value" style="flex: 1; ' + inputStyle + '">';
+                html += '<input type="text" value="' + escapeHTML((group.tags || []).join(', ')) + '" placeholder="tags (comma-separated)" aria-label="Group tags"';
+                html += ' onchange="groupsState[' + i + '].tags = this.value.split(\',\').map(t => t.trim()).filter(t => t)" style="flex: 2; ' + inputStyle + '">';
+                html += '<select aria-label="Group max rating" onchange="groupsState[' + i + '].max_rating = this.value" style="' + inputStyle + '">';
+                RATING_CHOICES.forEach(([value, label]) => {
+                    html += '<option value="' + value + '"' + ((group.max_rating || '') === value ? ' selected' : '') + '>' + label + '</option>';
+                });
+                html += '</select>';
+                html += '<label style="font-size: 0.85em; color: #8899aa; white-space: nowrap;"><input type="checkbox"' + (group.include_untagged ? ' checked' : '');
+                html += ' onchange="groupsState[' + i + '].include_untagged = this.checked"> untagged</label>';
+                html += '<button class="btn btn-danger" onclick="groupsState.splice(' + i + ', 1); renderGroups()"><span>Remove</span></button>';
+                html += '</div>';
+            });
+            document.getElementById('groups-list').innerHTML = html || '<p style="color: #8899aa;">No groups yet.</p>';
         }
-        .info-banner p {
-            margin: 5px 0;
-            color: #a0b0c0;
+
+        function addGroup() {
+            groupsState.push({ name: '', tags: [], include_untagged: false, max_rating: '' });
+            renderGroups();
         }
-        .info-banner strong {
-            color: #e8e8e8;
+
+        async function saveGroups() {
+            try {
+                const resp = await fetch('/api/groups', {
+                    method: 'POST',
+                    headers: {'Content-Type': 'application/json'},
+                    body: JSON.stringify(groupsState)
+                });
+                if (!resp.ok) throw new Error(await resp.text());
+                showNotification('Groups saved', 'success');
+            } catch (error) {
+                showNotification('Failed to save groups: ' + error.message, 'error');
+            }
         }
-        
-        /* Preview Modal */
-        .modal {
-            display: none;
-            position: fixed;
-            z-index: 2000;
-            left: 0;
-            top: 0;
-            width: 100%;
-            height: 100%;
-            background-color: rgba(0,0,0,0.8);
-            animation: fadeIn 0.3s;
+
+        async function removeSubscriber(email) {
+            if (!confirm('Remove ' + email + ' from the newsletter?')) return;
+
+            try {
+                const resp = await fetch('/api/subscribers?email=' + encodeURIComponent(email), { method: 'DELETE' });
+                if (resp.ok) {
+                    showNotification('Subscriber removed', 'success');
+                    loadSubscribers();
+                } else {
+                    showNotification('Failed to remove subscriber', 'error');
+                }
+            } catch (error) {
+                showNotification('Failed to remove subscriber: ' + error.message, 'error');
+            }
         }
-        .modal.show { display: flex; align-items: center; justify-content: center; }
-        .modal-content {
-            background: #1a2332;
-            width: 90%;
-            max-width: 900px;
-            max-height: 90vh;
-            border-radius: 12px;
-            overflow: hidden;
-            animation: slideUp 0.3s;
+
+        async function loadTokens() {
+            try {
+                const resp = await fetch('/api/tokens');
+                const tokens = await resp.json();
+                const list = document.getElementById('tokens-list');
+
+                if (!tokens.length) {
+                    list.innerHTML = '<p style="color: #8899aa;">No API tokens yet.</p>';
+                    return;
+                }
+
+                let html = '';
+                tokens.forEach(token => {
+                    html += '<div class="template-option">';
+                    html += '<div><strong>' + escapeHTML(token.name) + '</strong> <code>' + escapeHTML(token.prefix) + '…</code>';
+                    html += '<p style="font-size: 0.9em; color: #8899aa; margin-top: 5px;">';
+                    html += 'Scopes: ' + escapeHTML(token.scopes.join(', '));
+                    html += ' • Created ' + new Date(token.created_at).toLocaleDateString();
+                    if (token.created_by) html += ' by ' + escapeHTML(token.created_by);
+                    html += ' • ' + (token.last_used_at ? 'Last used ' + new Date(token.last_used_at).toLocaleString() : 'Never used');
+                    html += '</p></div>';
+                    html += '<button class="btn btn-danger" data-id="' + escapeHTML(token.id) + '" data-name="' + escapeHTML(token.name) + '" onclick="revokeToken(this.dataset.id, this.dataset.name)"><span>Revoke</span></button>';
+                    html += '</div>';
+                });
+                list.innerHTML = html;
+            } catch (error) {
+                showNotification('Failed to load API tokens: ' + error.message, 'error');
+            }
         }
-        .modal-header {
-            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
-            padding: 20px;
-            display: flex;
-            justify-content: space-between;
-            align-items: center;
+
+        async function createToken() {
+            const name = document.getElementById('new-token-name').value.trim();
+            const scopes = Array.from(document.querySelectorAll('[data-token-scope]:checked')).map(el => el.dataset.tokenScope);
+            if (!name) {
+                showNotification('Give the token a name', 'error');
+                return;
+            }
+
+            try {
+                const resp = await fetch('/api/tokens', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ name, scopes })
+                });
+                const data = await resp.json();
+                if (!data.success) {
+                    showNotification(data.error || 'Failed to create token', 'error');
+                    return;
+                }
+                const banner = document.getElementById('new-token');
+                banner.innerHTML = '🔑 Copy the token for <strong>' + escapeHTML(name) + '</strong> now, it won\'t be shown again:<br><code>' + escapeHTML(data.token) + '</code>';
+                banner.style.display = 'block';
+                document.getElementById('new-token-name').value = '';
+                loadTokens();
+            } catch (error) {
+                showNotification('Failed to create token: ' + error.message, 'error');
+            }
         }
-        .modal-header h2 {
-            margin: 0;
-            color: white;
+
+        async function revokeToken(id, name) {
+            if (!confirm('Revoke the API token "' + name + '"? Clients using it will stop working.')) return;
+
+            try {
+                const resp = await fetch('/api/tokens/' + encodeURIComponent(id), { method: 'DELETE' });
+                if (resp.ok) {
+                    showNotification('Token revoked', 'success');
+                    document.getElementById('new-token').style.display = 'none';
+                    loadTokens();
+                } else {
+                    showNotification('Failed to revoke token', 'error');
+                }
+            } catch (error) {
+                showNotification('Failed to revoke token: ' + error.message, 'error');
+            }
         }
-        .modal-close {
-            background: transparent;
-            border: none;
-            color: white;
-            font-size: 28px;
-            cursor: pointer;
-            padding: 0;
-            width: 30px;
-            height: 30px;
-            line-height: 1;
+
+        // Live events: log lines for the Logs tab and progress for running sends
+        let eventSource = null;
+        const progressListeners = new Set();
+
+        function connectEvents() {
+            if (!window.EventSource) return;
+            eventSource = new EventSource('/api/events');
+            // (Re)connected: reload so nothing logged while disconnected is missing
+            eventSource.addEventListener('logs', () => loadLogs());
+            eventSource.addEventListener('log', e => {
+                const entry = JSON.parse(e.data);
+                if (!logMatches(entry)) return;
+                const logs = document.getElementById('logs');
+                const atBottom = logs.scrollTop + logs.clientHeight >= logs.scrollHeight - 20;
+                logs.appendChild(logLine(entry));
+                while (logs.childNodes.length > 500) logs.removeChild(logs.firstChild);
+                if (atBottom) logs.scrollTop = logs.scrollHeight;
+            });
+            eventSource.addEventListener('progress', e => {
+                const progress = JSON.parse(e.data);
+                progressListeners.forEach(listener => listener(progress));
+            });
         }
-        .modal-close:hover { opacity: 0.7; }
-        .modal-close:focus { outline: 2px solid white; outline-offset: 2px; }
-        .modal-body {
-            padding: 20px;
-            max-height: calc(90vh - 140px);
-            overflow-y: auto;
+
+        const LOG_LEVELS = { debug: 0, info: 1, warn: 2, error: 3 };
+
+        function logFilters() {
+            return {
+                level: document.getElementById('log-level').value,
+                since: document.getElementById('log-since').value,
+                run_id: document.getElementById('log-run').value.trim(),
+                q: document.getElementById('log-search').value.trim(),
+            };
         }
-        .modal-body iframe {
-            width: 100%;
-            height: 600px;
-            border: 2px solid #2a3444;
-            border-radius: 8px;
-            background: white;
+
+        // Same filters as the server applies, for entries arriving live
+        // (the time range always includes new entries)
+        function logMatches(entry) {
+            const f = logFilters();
+            if ((LOG_LEVELS[entry.level] || 0) < LOG_LEVELS[f.level]) return false;
+            const attrs = entry.attrs || {};
+            if (f.run_id && attrs.run_id !== f.run_id) return false;
+            if (!f.q) return true;
+            const q = f.q.toLowerCase();
+            return [entry.msg, ...Object.values(attrs)].some(v => v.toLowerCase().includes(q));
         }
-        @keyframes fadeIn {
-            from { opacity: 0; }
-            to { opacity: 1; }
+
+        function logLine(entry) {
+            const line = document.createElement('div');
+            line.className = 'log-' + entry.level;
+            let text = new Date(entry.time).toLocaleString() + ' ';
+            if (entry.level !== 'info') text += entry.level.toUpperCase() + ' ';
+            text += entry.msg;
+            Object.keys(entry.attrs || {}).sort().forEach(key => {
+                text += ' ' + key + '=' + entry.attrs[key];
+            });
+            line.textContent = text;
+            return line;
         }
-        @keyframes slideUp {
-            from { transform: translateY(50px); opacity: 0; }
-            to { transform: translateY(0); opacity: 1; }
+
+        async function loadLogs() {
+            const params = new URLSearchParams();
+            Object.entries(logFilters()).forEach(([key, value]) => {
+                if (value) params.set(key, value);
+            });
+            try {
+                const resp = await fetch('/api/logs?' + params);
+                if (!resp.ok) throw new Error(await resp.text());
+                const data = await resp.json();
+                const logs = document.getElementById('logs');
+                logs.replaceChildren(...data.entries.map(logLine));
+                logs.scrollTop = logs.scrollHeight;
+            } catch (error) {
+                console.error('Failed to load logs:', error);
+            }
         }
-        
-        .action-buttons {
-            display: flex;
-            gap: 10px;
-            margin-top: 20px;
+
+        async function checkUpdates() {
+            const button = event.target;
+            button.classList.add('loading');
+            button.disabled = true;
+
+            try {
+                const resp = await fetch('/api/version');
+                const data = await resp.json();
+                
+                let html = '<div style="background: #252f3f; padding: 20px; border-radius: 10px; margin-bottom: 20px;">';
+                html += '<p><strong>Current Version:</strong> ' + data.current_version + '</p>';
+                html += '<p><strong>Latest Version:</strong> ' + data.latest_version + '</p>';
+
+                if (data.update_available) {
+                    html += '<p style="color: #38ef7d; margin-top: 15px;"><strong>Update Available!</strong></p>';
+                    html += '<h4 style="margin-top: 15px;">What\'s New:</h4>';
+                    html += '<ul style="margin-left: 20px; margin-top: 10px;">';
+                    data.changelog.forEach(item => {
+                        html += '<li style="margin: 5px 0;">' + item + '</li>';
+                    });
+                    html += '</ul>';
+                    document.getElementById('update-btn').style.display = 'inline-block';
+                } else {
+                    html += '<p style="color: #8899aa; margin-top: 15px;">You are running the latest version!</p>';
+                    document.getElementById('update-btn').style.display = 'none';
+                }
+
+                html += '</div>';
+                document.getElementById('version-info').innerHTML = html;
+            } catch (error) {
+                showNotification('Failed to check updates: ' + error.message, 'error');
+            } finally {
+                button.classList.remove('loading');
+                button.disabled = false;
+            }
         }
-        .action-buttons .btn {
-            flex: 1;
+
+        async function performUpdate() {
+            if (!confirm('Update Newslettar? The page will reload in 20 seconds.')) return;
+
+            const button = document.getElementById('update-btn');
+            button.classList.add('loading');
+            button.disabled = true;
+
+            showNotification('Starting update... Page will reload in 20 seconds', 'success');
+            
+            try {
+                await fetch('/api/update', { method: 'POST' });
+
+                setTimeout(() => {
+                    location.reload();
+                }, 20000);
+            } catch (error) {
+                showNotification('Update failed: ' + error.message, 'error');
+                button.classList.remove('loading');
+                button.disabled = false;
+            }
         }
-        
-        /* Loading overlay */
-        .loading-overlay {
-            display: none;
-            position: fixed;
-            top: 0;
-            left: 0;
-            width: 100%;
-            height: 100%;
-            background: rgba(0,0,0,0.7);
-            z-index: 1500;
-            align-items: center;
-            justify-content: center;
+
+        function showNotification(message, type) {
+            const notification = document.createElement('div');
+            notification.className = 'notification ' + type;
+            notification.textContent = message;
+            notification.setAttribute('role', 'alert');
+            document.body.appendChild(notification);
+
+            setTimeout(() => {
+                notification.remove();
+            }, 10000);
         }
-        .loading-overlay.show { display: flex; }
-        .loading-spinner {
-            width: 60px;
-            height: 60px;
-            border: 5px solid rgba(255,255,255,0.3);
-            border-top-color: #667eea;
-            border-radius: 50%;
-            animation: spin 0.8s linear infinite;
+
+        function showLoading() {
+            document.getElementById('loading-overlay').classList.add('show');
+        }
+
+        function hideLoading() {
+            document.getElementById('loading-overlay').classList.remove('show');
         }
+
+        // Load config on page load
+        loadConfig();
+        connectEvents();
+        checkUpdates();
+    </script>
+</body>
+</html>`
+
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprint(w, html)
+}
+
+func timezoneInfoHandler(w http.ResponseWriter, r *http.Request) {
+	tz := r.URL.Query().Get("tz")
+	if tz == "" {
+		tz = "UTC"
+	}
+
+	loc := getTimezone(tz)
+	now := time.Now().In(loc)
+
+	_, offset := now.Zone()
+	hours := offset / 3600
+	minutes := (offset % 3600) / 60
+
+	offsetStr := fmt.Sprintf("GMT%+d", hours)
+	if minutes != 0 {
+		offsetStr = fmt.Sprintf("GMT%+d:%02d", hours, minutes)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"current_time": now.Format("Monday, January 2, 2006 3:04 PM"),
+		"offset":       offsetStr,
+	})
+}
+
+// Validate a schedule expression and describe it (?expr=...)
+func scheduleInfoHandler(w http.ResponseWriter, r *http.Request) {
+	expr := strings.TrimSpace(r.URL.Query().Get("expr"))
+	w.Header().Set("Content-Type", "application/json")
+
+	if _, err := parseSchedule(expr); err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"valid": false,
+			"error": err.Error(),
+		})
+		return
+	}
+
+	loc := getTimezone(getConfig().Timezone)
+	nextRuns := []string{}
+	for _, t := range nextRunTimes([]string{expr}, loc, 5) {
+		nextRuns = append(nextRuns, t.Format("Monday, January 2, 2006 at 3:04 PM MST"))
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"valid":       true,
+		"description": describeSchedule(expr),
+		"next_runs":   nextRuns,
+	})
+}
+
+// Preview handler for UI (?profile=<id>, default: first profile)
+func previewHandler(w http.ResponseWriter, r *http.Request) {
+	cfg := getConfig()
+	p, ok := findProfile(r.URL.Query().Get("profile"))
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Unknown newsletter profile",
+		})
+		return
+	}
+
+	loc := getTimezone(cfg.Timezone)
+	now := time.Now().In(loc)
+
+	// Same window the next send would use (the preview does not advance it)
+	weekStart, weekEnd := newsletterWindow(p, now)
+
+	// Parallel API calls with context
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	data, _ := fetchNewsletterData(ctx, cfg, p, weekStart, weekEnd, historyWindow(p, weekStart, now), 2)
+
+	html, err := generateNewsletterHTML(p, data)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Failed to generate preview: %v", err),
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"html":    html,
+	})
+}
+
+func configHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "POST" {
+		var webCfg WebConfig
+		if err := json.NewDecoder(r.Body).Decode(&webCfg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		envMap := readEnvFile()
+
+		// Explicitly cleared fields first, so a value sent alongside still wins
+		for _, field := range webCfg.Clear {
+			key, ok := configEnvKeys[field]
+			if !ok {
+				http.Error(w, "unknown field: "+field, http.StatusBadRequest)
+				return
+			}
+			delete(envMap, key)
+		}
+
+		// Only update fields that were provided (secrets only when a new value is sent)
+		if webCfg.SonarrURL != "" {
+			envMap["SONARR_URL"] = webCfg.SonarrURL
+		}
+		if isNewSecret(webCfg.SonarrAPIKey) {
+			envMap["SONARR_API_KEY"] = webCfg.SonarrAPIKey
+		}
+		if webCfg.RadarrURL != "" {
+			envMap["RADARR_URL"] = webCfg.RadarrURL
+		}
+		if isNewSecret(webCfg.RadarrAPIKey) {
+			envMap["RADARR_API_KEY"] = webCfg.RadarrAPIKey
+		}
+		if webCfg.MailgunSMTP != "" {
+			envMap["MAILGUN_SMTP"] = webCfg.MailgunSMTP
+		}
+		if webCfg.MailgunPort != "" {
+			envMap["MAILGUN_PORT"] = webCfg.MailgunPort
+		}
+		if webCfg.MailgunUser != "" {
+			envMap["MAILGUN_USER"] = webCfg.MailgunUser
+		}
+		if isNewSecret(webCfg.MailgunPass) {
+			envMap["MAILGUN_PASS"] = webCfg.MailgunPass
+		}
+		if webCfg.FromEmail != "" {
+			envMap["FROM_EMAIL"] = webCfg.FromEmail
+		}
+		if webCfg.FromName != "" {
+			envMap["FROM_NAME"] = webCfg.FromName
+		}
+		if webCfg.Timezone != "" {
+			envMap["TIMEZONE"] = webCfg.Timezone
+		}
+		if webCfg.CatchUpGrace != "" {
+			if webCfg.CatchUpGrace != "0" {
+				if _, err := time.ParseDuration(webCfg.CatchUpGrace); err != nil {
+					http.Error(w, "invalid catch-up grace period: "+err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+			envMap["CATCHUP_GRACE"] = webCfg.CatchUpGrace
+		}
+		if webCfg.SignupEnabled != "" {
+			envMap["SIGNUP_ENABLED"] = webCfg.SignupEnabled
+		}
+		if webCfg.InviteCodes != "" {
+			envMap["INVITE_CODES"] = webCfg.InviteCodes
+		}
+		if webCfg.PublicURL != "" {
+			envMap["PUBLIC_URL"] = webCfg.PublicURL
+		}
+		if isNewSecret(webCfg.FeedToken) {
+			envMap["FEED_TOKEN"] = webCfg.FeedToken
+		}
+		if webCfg.AdminEmail != "" {
+			if addr, err := mail.ParseAddress(webCfg.AdminEmail); err != nil || addr.Address != webCfg.AdminEmail {
+				http.Error(w, "invalid admin email: "+webCfg.AdminEmail, http.StatusBadRequest)
+				return
+			}
+			envMap["ADMIN_EMAIL"] = webCfg.AdminEmail
+		}
+		if webCfg.ArchiveAccess != "" {
+			switch webCfg.ArchiveAccess {
+			case ArchivePublic, ArchiveSigned, ArchiveOff:
+				envMap["ARCHIVE_ACCESS"] = webCfg.ArchiveAccess
+			default:
+				http.Error(w, "invalid archive access: "+webCfg.ArchiveAccess, http.StatusBadRequest)
+				return
+			}
+		}
+
+		if getEnvFromFile(envMap, "SIGNUP_ENABLED", "false") == "true" && getEnvFromFile(envMap, "PUBLIC_URL", "") == "" {
+			http.Error(w, "sign-up needs a public URL for its confirmation links", http.StatusBadRequest)
+			return
+		}
+
+		if err := writeEnvFile(envMap); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Reload config and restart scheduler
+		reloadConfig()
+		restartScheduler()
+
+		// Saved, but a flag or the environment still decides these
+		overridden := []string{}
+		for field, source := range configSources(readEnvFile()) {
+			if overridesSavedSettings(source) {
+				overridden = append(overridden, field)
+			}
+		}
+		sort.Strings(overridden)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":    true,
+			"overridden": overridden,
+		})
+		return
+	}
+
+	// GET request - return current config, with secrets masked
+	envMap := readEnvFile()
+	values := map[string]string{
+		"sonarr_url":     getEnvFromFile(envMap, "SONARR_URL", ""),
+		"sonarr_api_key": getEnvFromFile(envMap, "SONARR_API_KEY", ""),
+		"radarr_url":     getEnvFromFile(envMap, "RADARR_URL", ""),
+		"radarr_api_key": getEnvFromFile(envMap, "RADARR_API_KEY", ""),
+		"mailgun_smtp":   getEnvFromFile(envMap, "MAILGUN_SMTP", "smtp.mailgun.org"),
+		"mailgun_port":   getEnvFromFile(envMap, "MAILGUN_PORT", "587"),
+		"mailgun_user":   getEnvFromFile(envMap, "MAILGUN_USER", ""),
+		"mailgun_pass":   getEnvFromFile(envMap, "MAILGUN_PASS", ""),
+		"from_email":     getEnvFromFile(envMap, "FROM_EMAIL", ""),
+		"from_name":      getEnvFromFile(envMap, "FROM_NAME", "Newslettar"),
+		"admin_email":    getEnvFromFile(envMap, "ADMIN_EMAIL", ""),
+		"timezone":       getEnvFromFile(envMap, "TIMEZONE", "UTC"),
+		"catchup_grace":  getEnvFromFile(envMap, "CATCHUP_GRACE", "24h"),
+		"signup_enabled": getEnvFromFile(envMap, "SIGNUP_ENABLED", "false"),
+		"invite_codes":   getEnvFromFile(envMap, "INVITE_CODES", ""),
+		"public_url":     getEnvFromFile(envMap, "PUBLIC_URL", ""),
+		"archive_access": getEnvFromFile(envMap, "ARCHIVE_ACCESS", ArchiveSigned),
+		"feed_token":     getEnvFromFile(envMap, "FEED_TOKEN", ""),
+	}
+	for _, field := range secretConfigFields {
+		if values[field] != "" {
+			values[field] = secretMask
+		}
+	}
+
+	response := make(map[string]interface{}, len(values)+1)
+	for field, value := range values {
+		response[field] = value
+	}
+	response["sources"] = configSources(envMap)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func testSonarrHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		URL    string `json:"url"`
+		APIKey string `json:"api_key"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The form holds the mask while the saved key is unchanged
+	cfg := getConfig()
+	apiKey, ok := testSecret(req.APIKey, req.URL, cfg.SonarrURL, cfg.SonarrAPIKey)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Enter the API key to test a different server",
+		})
+		return
+	}
+	req.APIKey = apiKey
+
+	success := false
+	message := "Missing URL or API key"
+
+	if req.URL != "" && req.APIKey != "" {
+		httpReq, err := http.NewRequest("GET", req.URL+"/api/v3/system/status", nil)
+		if err == nil {
+			httpReq.Header.Set("X-Api-Key", req.APIKey)
+			resp, err := httpClient.Do(httpReq)
+			if err != nil {
+				message = fmt.Sprintf("Connection failed: %v", err)
+			} else if resp.StatusCode == 200 {
+				success = true
+				message = "Sonarr connection successful!"
+				resp.Body.Close()
+			} else {
+				message = fmt.Sprintf("Connection failed: HTTP %d", resp.StatusCode)
+				resp.Body.Close()
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": success,
+		"message": message,
+	})
+}
+
+func testRadarrHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		URL    string `json:"url"`
+		APIKey string `json:"api_key"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The form holds the mask while the saved key is unchanged
+	cfg := getConfig()
+	apiKey, ok := testSecret(req.APIKey, req.URL, cfg.RadarrURL, cfg.RadarrAPIKey)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Enter the API key to test a different server",
+		})
+		return
+	}
+	req.APIKey = apiKey
+
+	success := false
+	message := "Missing URL or API key"
+
+	if req.URL != "" && req.APIKey != "" {
+		httpReq, err := http.NewRequest("GET", req.URL+"/api/v3/system/status", nil)
+		if err == nil {
+			httpReq.Header.Set("X-Api-Key", req.APIKey)
+			resp, err := httpClient.Do(httpReq)
+			if err != nil {
+				message = fmt.Sprintf("Connection failed: %v", err)
+			} else if resp.StatusCode == 200 {
+				success = true
+				message = "Radarr connection successful!"
+				resp.Body.Close()
+			} else {
+				message = fmt.Sprintf("Connection failed: HTTP %d", resp.StatusCode)
+				resp.Body.Close()
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": success,
+		"message": message,
+	})
+}
+
+func testEmailHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		SMTP string `json:"smtp"`
+		Port string `json:"port"`
+		User string `json:"user"`
+		Pass string `json:"pass"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The form holds the mask while the saved password is unchanged
+	cfg := getConfig()
+	pass, ok := testSecret(req.Pass, req.SMTP+":"+req.Port, cfg.MailgunSMTP+":"+cfg.MailgunPort, cfg.MailgunPass)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Enter the password to test a different server",
+		})
+		return
+	}
+	req.Pass = pass
+
+	success := false
+	message := "SMTP credentials missing"
+
+	if req.User != "" && req.Pass != "" {
+		addr := fmt.Sprintf("%s:%s", req.SMTP, req.Port)
+
+		client, err := smtp.Dial(addr)
+		if err != nil {
+			message = fmt.Sprintf("Connection failed: %v", err)
+		} else {
+			defer client.Close()
+
+			if err = client.Hello("localhost"); err != nil {
+				message = fmt.Sprintf("EHLO failed: %v", err)
+			} else if ok, _ := client.Extension("STARTTLS"); ok {
+				config := &tls.Config{ServerName: req.SMTP}
+				if err = client.StartTLS(config); err != nil {
+					message = fmt.Sprintf("STARTTLS failed: %v", err)
+				} else {
+					auth := smtp.PlainAuth("", req.User, req.Pass, req.SMTP)
+					if err = client.Auth(auth); err != nil {
+						message = fmt.Sprintf("Authentication failed: %v", err)
+					} else {
+						success = true
+						message = "SMTP authentication successful (with STARTTLS)"
+					}
+				}
+			} else {
+				auth := smtp.PlainAuth("", req.User, req.Pass, req.SMTP)
+				if err = client.Auth(auth); err != nil {
+					message = fmt.Sprintf("Authentication failed: %v", err)
+				} else {
+					success = true
+					message = "SMTP authentication successful"
+				}
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": success,
+		"message": message,
+	})
+}
+
+func sendHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	p, ok := findProfile(r.URL.Query().Get("profile"))
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Unknown newsletter profile",
+		})
+		return
+	}
+
+	// Runs in the background; the client polls /api/jobs/{id}
+	job, err := startJob(p, TriggerManual)
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": err.Error(),
+			"job_id":  job.snapshot().ID,
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Newsletter generation started",
+		"job_id":  job.snapshot().ID,
+	})
+}
+
+// Recent jobs, newest first
+func jobsHandler(w http.ResponseWriter, r *http.Request) {
+	jobsMu.Lock()
+	list := append([]*Job{}, jobs...)
+	jobsMu.Unlock()
+
+	statuses := []JobStatus{}
+	for _, job := range list {
+		statuses = append(statuses, job.snapshot())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statuses)
+}
+
+// Job status (GET /api/jobs/{id}) and cancellation (POST /api/jobs/{id}/cancel)
+func jobHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	job, ok := findJob(id)
+	cancel := strings.HasSuffix(r.URL.Path, "/cancel")
+
+	if cancel {
+		if r.Method != "POST" {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !ok || job.snapshot().FinishedAt != nil {
+			http.Error(w, "job is not running", http.StatusConflict)
+			return
+		}
+		job.stop()
+		log.Printf("⏹️  Cancel requested for %s", job.snapshot().ProfileName)
+	}
+
+	if !ok {
+		// No longer in memory: report the final result from the run history
+		run, found := findRun(id)
+		if !found {
+			http.Error(w, "job not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(JobStatus{
+			ID:          run.ID,
+			ProfileID:   run.ProfileID,
+			ProfileName: run.ProfileName,
+			Trigger:     run.Trigger,
+			Status:      run.Status,
+			Message:     run.Message,
+			ErrorKind:   run.ErrorKind,
+			Steps:       []JobStep{},
+			Processed:   len(run.Deliveries),
+			Recipients:  len(run.Deliveries),
+			StartedAt:   run.StartedAt,
+			FinishedAt:  run.FinishedAt,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job.snapshot())
+}
+
+// Prometheus scrape endpoint
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	for _, m := range allMetrics {
+		m.write(w)
+	}
+}
+
+// Liveness: the process is up and serving (use this for Docker HEALTHCHECK)
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprintln(w, "ok")
+}
+
+// Readiness for orchestrators. Only process state counts: a fresh install has to stay
+// reachable so it can be configured. Anonymous callers get just the verdict.
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	problems := readinessProblems()
+	resp := map[string]interface{}{"ready": len(problems) == 0}
+	if _, ok := authenticate(r); ok {
+		resp["problems"] = problems
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if len(problems) > 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func readinessProblems() []string {
+	problems := []string{}
+	if shuttingDown.Load() {
+		problems = append(problems, "shutting down")
+	}
+	schedulerMu.Lock()
+	running := scheduler != nil
+	schedulerMu.Unlock()
+	if !running {
+		problems = append(problems, "scheduler not running")
+	}
+	return problems
+}
+
+// Settings the enabled newsletters need but don't have
+func missingSettings(cfg *Config) []string {
+	missing := []string{}
+	add := func(setting string) {
+		for _, m := range missing {
+			if m == setting {
+				return
+			}
+		}
+		missing = append(missing, setting)
+	}
+
+	for _, p := range loadProfiles() {
+		if !p.Enabled {
+			continue
+		}
+		if (p.hasSection(SectionDownloadedSeries) || p.hasSection(SectionUpcomingSeries)) && (cfg.SonarrURL == "" || cfg.SonarrAPIKey == "") {
+			add("Sonarr URL/API key")
+		}
+		if (p.hasSection(SectionDownloadedMovies) || p.hasSection(SectionUpcomingMovies)) && (cfg.RadarrURL == "" || cfg.RadarrAPIKey == "") {
+			add("Radarr URL/API key")
+		}
+		t := p.transport(cfg)
+		if t.Host == "" || t.Port == "" || t.FromEmail == "" {
+			add("SMTP server, port or from address for " + p.Name)
+		}
+		if len(getRecipients(cfg, p)) == 0 {
+			add("recipients for " + p.Name)
+		}
+	}
+	return missing
+}
+
+// Live reachability of one configured Sonarr/Radarr/SMTP endpoint
+type EndpointCheck struct {
+	Name      string `json:"name"`
+	Target    string `json:"target"`
+	OK        bool   `json:"ok"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+const endpointCheckTimeout = 5 * time.Second
+
+// Check every configured endpoint in parallel: Sonarr/Radarr system status (with the API key)
+// and each distinct SMTP server (connect and EHLO, without logging in)
+func checkEndpoints(ctx context.Context, cfg *Config) []EndpointCheck {
+	type check struct {
+		name, target string
+		run          func(ctx context.Context) error
+	}
+	var checks []check
+	if cfg.SonarrURL != "" {
+		checks = append(checks, check{"sonarr", cfg.SonarrURL, func(ctx context.Context) error {
+			return checkArr(ctx, cfg.SonarrURL, cfg.SonarrAPIKey)
+		}})
+	}
+	if cfg.RadarrURL != "" {
+		checks = append(checks, check{"radarr", cfg.RadarrURL, func(ctx context.Context) error {
+			return checkArr(ctx, cfg.RadarrURL, cfg.RadarrAPIKey)
+		}})
+	}
+	seen := make(map[string]bool)
+	transports := []SMTPTransport{cfg.emailTransport()}
+	for _, p := range loadProfiles() {
+		if p.Enabled {
+			transports = append(transports, p.transport(cfg))
+		}
+	}
+	for _, t := range transports {
+		addr := net.JoinHostPort(t.Host, t.Port)
+		if t.Host == "" || t.Port == "" || seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		checks = append(checks, check{"smtp", addr, func(ctx context.Context) error {
+			return checkSMTP(ctx, addr)
+		}})
+	}
+
+	results := make([]EndpointCheck, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, endpointCheckTimeout)
+			defer cancel()
+			start := time.Now()
+			err := c.run(ctx)
+			results[i] = EndpointCheck{Name: c.name, Target: c.target, OK: err == nil, LatencyMs: time.Since(start).Milliseconds()}
+			if err != nil {
+				results[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func checkArr(ctx context.Context, baseURL, apiKey string) error {
+	if apiKey == "" {
+		return fmt.Errorf("API key not configured")
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/api/v3/system/status", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Api-Key", apiKey)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func checkSMTP(ctx context.Context, addr string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	host, _, _ := net.SplitHostPort(addr)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+	if err := client.Hello("localhost"); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// Overall state for dashboards and monitors: "ok", or "degraded" when not ready, settings
+// are missing, an endpoint is unreachable or a newsletter's last run failed. ?checks=false skips
+// the live endpoint checks.
+func statusHandler(w http.ResponseWriter, r *http.Request) {
+	cfg := getConfig()
+	loc := getTimezone(cfg.Timezone)
+	missing := missingSettings(cfg)
+	problems := readinessProblems()
+	degraded := len(problems) > 0 || len(missing) > 0
+
+	// Scheduler entries and the next time any of them fires
+	schedulerMu.Lock()
+	running := scheduler != nil && !shuttingDown.Load()
+	var entries []cron.Entry
+	if scheduler != nil {
+		entries = scheduler.Entries()
+	}
+	schedulerMu.Unlock()
+	var nextRun *time.Time
+	for _, e := range entries {
+		if next := e.Next.In(loc); !e.Next.IsZero() && (nextRun == nil || next.Before(*nextRun)) {
+			nextRun = &next
+		}
+	}
+
+	// Latest run of each newsletter (the history is newest first)
+	lastRuns := make(map[string]Run)
+	for _, run := range loadRuns() {
+		if _, ok := lastRuns[run.ProfileID]; !ok && run.Status != RunRunning {
+			lastRuns[run.ProfileID] = run
+		}
+	}
+	jobsMu.Lock()
+	active := make(map[string]string)
+	for id, job := range activeJobs {
+		active[id] = job.status.ID
+	}
+	jobsMu.Unlock()
+
+	newsletters := []map[string]interface{}{}
+	for _, p := range loadProfiles() {
+		entry := map[string]interface{}{
+			"id":        p.ID,
+			"name":      p.Name,
+			"enabled":   p.Enabled,
+			"schedules": p.Schedules,
+			"next_run":  nil,
+			"last_run":  nil,
+		}
+		if next := nextRunTimes(p.Schedules, loc, 1); p.Enabled && len(next) > 0 {
+			entry["next_run"] = next[0]
+		}
+		if run, ok := lastRuns[p.ID]; ok {
+			entry["last_run"] = map[string]interface{}{
+				"id":          run.ID,
+				"status":      run.Status,
+				"message":     run.Message,
+				"error_kind":  run.ErrorKind,
+				"trigger":     run.Trigger,
+				"finished_at": run.FinishedAt,
+			}
+			if p.Enabled && run.Status == RunFailed {
+				degraded = true
+			}
+		}
+		if id, ok := active[p.ID]; ok {
+			entry["running_job"] = id
+		}
+		newsletters = append(newsletters, entry)
+	}
+
+	checks := []EndpointCheck{}
+	if r.URL.Query().Get("checks") != "false" {
+		checks = checkEndpoints(r.Context(), cfg)
+	}
+	for _, c := range checks {
+		if !c.OK {
+			degraded = true
+		}
+	}
+
+	status := "ok"
+	if degraded {
+		status = "degraded"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":         status,
+		"version":        version,
+		"started_at":     startedAt,
+		"uptime_seconds": int64(time.Since(startedAt).Seconds()),
+		"ready":          len(problems) == 0,
+		"problems":       problems,
+		"config": map[string]interface{}{
+			"complete": len(missing) == 0,
+			"missing":  missing,
+		},
+		"scheduler": map[string]interface{}{
+			"running":  running,
+			"entries":  len(entries),
+			"next_run": nextRun,
+			"timezone": cfg.Timezone,
+		},
+		"newsletters": newsletters,
+		"checks":      checks,
+	})
+}
+
+// Most entries a single log query returns
+const maxLogQuery = 5000
+
+// Log query: ?level=warn&since=2h&until=<RFC 3339>&run_id=...&q=text&limit=500
+// (since/until take RFC 3339 times or durations ago); format=text returns plain lines
+func logsHandler(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	q := logQuery{minLevel: slog.LevelDebug, runID: params.Get("run_id"), text: strings.ToLower(params.Get("q"))}
+	if value := params.Get("level"); value != "" {
+		level, ok := parseLogLevel(value)
+		if !ok {
+			http.Error(w, "unknown level: "+value, http.StatusBadRequest)
+			return
+		}
+		q.minLevel = level
+	}
+
+	now := time.Now()
+	for name, bound := range map[string]*time.Time{"since": &q.since, "until": &q.until} {
+		value := params.Get(name)
+		if value == "" {
+			continue
+		}
+		t, err := parseLogTime(value, now)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		*bound = t
+	}
+
+	limit := 500
+	if value := params.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit: "+value, http.StatusBadRequest)
+			return
+		}
+		if n > maxLogQuery {
+			n = maxLogQuery
+		}
+		limit = n
+	}
+
+	entries, truncated := queryLogs(q, limit)
+	if entries == nil {
+		entries = []LogEntry{}
+	}
+
+	if params.Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, e := range entries {
+			fmt.Fprintln(w, e.text())
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"entries":   entries,
+		"truncated": truncated,
+		"from_file": logOutput != nil,
+	})
+}
+
+// Absolute (RFC 3339) or relative to now ("90m", "2h", "7d" ago)
+func parseLogTime(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	d, err := parseWindowDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: use RFC 3339 or a duration such as 2h or 7d", value)
+	}
+	return now.Add(-d), nil
+}
+
+// Server-Sent Events: the log backlog first, then new log lines and run progress as they happen
+func eventsHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	// Subscribe before reading the backlog so no line falls in between
+	ch := events.subscribe()
+	defer events.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // keep nginx from buffering the stream
+
+	logBufferMu.Lock()
+	backlog := append([]LogEntry{}, logBuffer...)
+	logBufferMu.Unlock()
+	writeEvent(w, "logs", backlog)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(15 * time.Second)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			writeEvent(w, e.Type, e.Data)
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+// One SSE message with a JSON payload (always a single data line)
+func writeEvent(w io.Writer, name string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, payload)
+}
+
+// Public sign-up page (double opt-in)
+var subscribePageTemplate = template.Must(template.New("subscribe").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Title}}</title>
+    <style>
+        * { margin: 0; padding: 0; box-sizing: border-box; }
+        body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; background: #0f1419; color: #e8e8e8; line-height: 1.6; }
+        .container { max-width: 480px; margin: 60px auto; padding: 20px; }
+        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); padding: 30px; border-radius: 12px 12px 0 0; text-align: center; }
+        .card { background: #1a2332; padding: 30px; border-radius: 0 0 12px 12px; }
+        label { display: block; margin-bottom: 8px; color: #a0b0c0; font-weight: 500; }
+        input { width: 100%; padding: 12px; margin-bottom: 20px; background: #0f1419; border: 2px solid #2a3444; border-radius: 8px; color: #e8e8e8; font-size: 14px; }
+        input:focus { outline: none; border-color: #667eea; }
+        .btn { width: 100%; padding: 12px 24px; background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; border: none; border-radius: 8px; cursor: pointer; font-size: 14px; font-weight: 600; }
+        .btn:disabled { opacity: 0.5; cursor: not-allowed; }
+        .message { margin-top: 20px; color: #a0b0c0; }
+        .message.error { color: #eb3349; }
     </style>
 </head>
 <body>
     <div class="container">
-        <div class="header">
-            <h1>📺 Newslettar</h1>
-            <p class="version">Version ` + version + ` • Enhanced UI • Timezone-Aware</p>
+        <div class="header"><h1>📺 Newslettar</h1></div>
+        <div class="card">
+            {{if .Form}}
+            <p style="margin-bottom: 20px; color: #a0b0c0;">Get a weekly email with new and upcoming shows and movies.</p>
+            <form id="subscribe-form">
+                <label for="email">Email</label>
+                <input type="email" id="email" name="email" required placeholder="you@example.com">
+                {{if .InviteRequired}}
+                <label for="invite_code">Invite Code</label>
+                <input type="text" id="invite_code" name="invite_code" required>
+                {{end}}
+                <button type="submit" class="btn">Subscribe</button>
+            </form>
+            <p class="message" id="message" role="alert"></p>
+            <script>
+                document.getElementById('subscribe-form').addEventListener('submit', async (e) => {
+                    e.preventDefault();
+                    const button = e.target.querySelector('button');
+                    const message = document.getElementById('message');
+                    button.disabled = true;
+                    try {
+                        const resp = await fetch('/api/subscribe', {
+                            method: 'POST',
+                            headers: {'Content-Type': 'application/json'},
+                            body: JSON.stringify(Object.fromEntries(new FormData(e.target)))
+                        });
+                        const data = await resp.json();
+                        message.textContent = data.message;
+                        message.className = 'message' + (data.success ? '' : ' error');
+                        if (data.success) e.target.reset();
+                    } catch (error) {
+                        message.textContent = 'Network error: ' + error.message;
+                        message.className = 'message error';
+                    } finally {
+                        button.disabled = false;
+                    }
+                });
+            </script>
+            {{else}}
+            <h2 style="margin-bottom: 10px;">{{.Title}}</h2>
+            <p class="message">{{.Message}}</p>
+            {{end}}
+        </div>
+    </div>
+</body>
+</html>`))
+
+var loginPageTemplate = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Sign in - Newslettar</title>
+    <style>
+        * { margin: 0; padding: 0; box-sizing: border-box; }
+        body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; background: #0f1419; color: #e8e8e8; line-height: 1.6; }
+        .container { max-width: 420px; margin: 60px auto; padding: 20px; }
+        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); padding: 30px; border-radius: 12px 12px 0 0; text-align: center; }
+        .card { background: #1a2332; padding: 30px; border-radius: 0 0 12px 12px; }
+        label { display: block; margin-bottom: 8px; color: #a0b0c0; font-weight: 500; }
+        input { width: 100%; padding: 12px; margin-bottom: 20px; background: #0f1419; border: 2px solid #2a3444; border-radius: 8px; color: #e8e8e8; font-size: 14px; }
+        input:focus { outline: none; border-color: #667eea; }
+        .btn { width: 100%; padding: 12px 24px; background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; border: none; border-radius: 8px; cursor: pointer; font-size: 14px; font-weight: 600; }
+        .message { margin-bottom: 20px; color: #eb3349; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header"><h1>📺 Newslettar</h1></div>
+        <div class="card">
+            {{if .Setup}}
+            <p style="margin-bottom: 20px; color: #a0b0c0;">Welcome! Create the admin account that protects this Newslettar instance. The setup code is printed in the server log (<code>journalctl -u newslettar</code>).</p>
+            {{end}}
+            {{if .Message}}<p class="message" role="alert">{{.Message}}</p>{{end}}
+            <form method="POST" action="/login">
+                <input type="hidden" name="next" value="{{.Next}}">
+                {{if .Setup}}
+                <label for="setup_code">Setup code</label>
+                <input type="text" id="setup_code" name="setup_code" required autocomplete="off" autofocus>
+                {{end}}
+                <label for="username">Username</label>
+                <input type="text" id="username" name="username" value="{{.Username}}" required autocomplete="username" {{if not .Setup}}autofocus{{end}}>
+                <label for="password">Password</label>
+                <input type="password" id="password" name="password" required {{if .Setup}}minlength="8" autocomplete="new-password"{{else}}autocomplete="current-password"{{end}}>
+                {{if .Setup}}
+                <label for="confirm">Confirm password</label>
+                <input type="password" id="confirm" name="confirm" required minlength="8" autocomplete="new-password">
+                <button type="submit" class="btn">Create account</button>
+                {{else}}
+                <button type="submit" class="btn">Sign in</button>
+                {{end}}
+            </form>
+        </div>
+    </div>
+</body>
+</html>`))
+
+var archivePageTemplate = template.Must(template.New("archive").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Newslettar Archive</title>
+    <style>
+        * { margin: 0; padding: 0; box-sizing: border-box; }
+        body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; background: #0f1419; color: #e8e8e8; line-height: 1.6; }
+        .container { max-width: 640px; margin: 60px auto; padding: 20px; }
+        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); padding: 30px; border-radius: 12px 12px 0 0; text-align: center; }
+        .card { background: #1a2332; padding: 30px; border-radius: 0 0 12px 12px; }
+        .issue { display: block; padding: 15px; margin-bottom: 10px; background: #252f3f; border-left: 3px solid #667eea; border-radius: 6px; color: #e8e8e8; text-decoration: none; }
+        .issue:hover { background: #2a3444; }
+        .issue small { display: block; color: #8899aa; }
+        .empty { color: #8899aa; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header"><h1>🗄️ Past Issues</h1></div>
+        <div class="card">
+            {{range .Issues}}
+            <a class="issue" href="{{.URL}}">
+                <strong>{{.Subject}}</strong>
+                <small>{{.SentAt}} • {{.ProfileName}}</small>
+            </a>
+            {{else}}
+            <p class="empty">No issues have been sent yet.</p>
+            {{end}}
         </div>
+    </div>
+</body>
+</html>`))
+
+// Atom feed (RFC 4287)
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title    string     `xml:"title"`
+	ID       string     `xml:"id"`
+	Updated  string     `xml:"updated"`
+	Category []atomTerm `xml:"category"`
+	Links    []atomLink `xml:"link"`
+	Summary  string     `xml:"summary"`
+}
+
+type atomTerm struct {
+	Term string `xml:"term,attr"`
+}
+
+// Newsletter data per profile and section set, cached so feed and calendar
+// clients polling often don't hit Sonarr/Radarr on every request
+const feedCacheTTL = 10 * time.Minute
+
+var (
+	feedCacheMu sync.Mutex
+	feedCache   = make(map[string]*feedCacheEntry)
+)
+
+type feedCacheEntry struct {
+	data    NewsletterData
+	fetched time.Time
+	ready   chan struct{} // closed once data is fetched
+}
+
+// Fetch the given sections of a profile (those it has enabled) over a rolling window ending now
+func cachedSectionData(cfg *Config, p Profile, sections ...string) NewsletterData {
+	enabled := []string{}
+	for _, s := range sections {
+		if p.hasSection(s) {
+			enabled = append(enabled, s)
+		}
+	}
+	p.Sections = enabled
+	key := p.ID + "|" + strings.Join(enabled, ",")
+
+	// The fetch runs outside the lock; concurrent requests for the same key wait for it
+	feedCacheMu.Lock()
+	if entry, ok := feedCache[key]; ok {
+		select {
+		case <-entry.ready:
+			if time.Since(entry.fetched) < feedCacheTTL {
+				feedCacheMu.Unlock()
+				return entry.data
+			}
+		default:
+			feedCacheMu.Unlock()
+			<-entry.ready
+			return entry.data
+		}
+	}
+	entry := &feedCacheEntry{ready: make(chan struct{})}
+	feedCache[key] = entry
+	feedCacheMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	now := time.Now().In(getTimezone(cfg.Timezone))
+	entry.data, _ = fetchNewsletterData(ctx, cfg, p, now.Add(-p.lookback()), now, nil, 2)
+	entry.fetched = time.Now()
+	close(entry.ready)
+	return entry.data
+}
+
+// Feed and calendar URLs carry FEED_TOKEN
+func validFeedToken(cfg *Config, token string) bool {
+	return cfg.FeedToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(cfg.FeedToken)) == 1
+}
+
+// Atom feed of downloaded episode groups, movies and sent issues (/feed.xml?token=...&profile=<id>)
+func feedHandler(w http.ResponseWriter, r *http.Request) {
+	cfg := getConfig()
+	if !validFeedToken(cfg, r.URL.Query().Get("token")) {
+		http.Error(w, "invalid feed token", http.StatusForbidden)
+		return
+	}
+
+	p, ok := findProfile(r.URL.Query().Get("profile"))
+	if !ok {
+		http.Error(w, "unknown newsletter profile", http.StatusNotFound)
+		return
+	}
+
+	base := publicBaseURL(cfg, r)
+	feed := atomFeed{
+		Title:  p.Name,
+		ID:     "urn:newslettar:feed:" + p.ID,
+		Author: atomAuthor{Name: "Newslettar"},
+		Links: []atomLink{
+			{Href: base + r.URL.RequestURI(), Rel: "self", Type: "application/atom+xml"},
+		},
+	}
+	var updated time.Time
+	addEntry := func(entry atomEntry, at time.Time) {
+		entry.Updated = at.UTC().Format(time.RFC3339)
+		if at.After(updated) {
+			updated = at
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	imdbLink := func(id string) []atomLink {
+		if id == "" {
+			return nil
+		}
+		return []atomLink{{Href: "https://www.imdb.com/title/" + id + "/", Rel: "alternate", Type: "text/html"}}
+	}
+
+	// Only the downloaded sections: the feed is about what became available
+	data := cachedSectionData(cfg, p, SectionDownloadedSeries, SectionDownloadedMovies)
+
+	for _, g := range data.DownloadedSeriesGroups {
+		var latest time.Time
+		var codes []string
+		for _, ep := range g.Episodes {
+			if ep.ImportedAt.After(latest) {
+				latest = ep.ImportedAt
+			}
+			codes = append(codes, fmt.Sprintf("S%02dE%02d %s", ep.SeasonNum, ep.EpisodeNum, ep.Title))
+		}
+		title := fmt.Sprintf("%s: %d new episode", g.SeriesTitle, len(g.Episodes))
+		if len(g.Episodes) != 1 {
+			title += "s"
+		}
+		// Stable per series and day so repeated polls don't duplicate entries
+		addEntry(atomEntry{
+			Title:    title,
+			ID:       fmt.Sprintf("urn:newslettar:series:%d:%s", g.TvdbID, latest.UTC().Format("2006-01-02")),
+			Category: []atomTerm{{Term: "tv"}},
+			Links:    imdbLink(g.IMDBID),
+			Summary:  strings.Join(codes, "\n"),
+		}, latest)
+	}
+
+	for _, m := range data.DownloadedMovies {
+		title := m.Title
+		if m.Year > 0 {
+			title = fmt.Sprintf("%s (%d)", m.Title, m.Year)
+		}
+		addEntry(atomEntry{
+			Title:    title + " is now available",
+			ID:       fmt.Sprintf("urn:newslettar:movie:%d", m.TmdbID),
+			Category: []atomTerm{{Term: "movie"}},
+			Links:    imdbLink(m.IMDBID),
+			Summary:  title,
+		}, m.ImportedAt)
+	}
+
+	for _, issue := range loadIssues() {
+		if issue.ProfileID != p.ID {
+			continue
+		}
+		entry := atomEntry{
+			Title:    issue.Subject,
+			ID:       "urn:newslettar:issue:" + issue.ID,
+			Category: []atomTerm{{Term: "issue"}},
+			Summary:  fmt.Sprintf("Newsletter covering %s to %s", issue.WindowStart.Format("January 2, 2006"), issue.WindowEnd.Format("January 2, 2006")),
+		}
+		// Feed readers act on behalf of the token holder, not a recipient
+		if link := issueURL(cfg, issue.ID, "feed"); link != "" {
+			entry.Links = []atomLink{{Href: link, Rel: "alternate", Type: "text/html"}}
+		}
+		addEntry(entry, issue.SentAt)
+	}
+
+	sort.SliceStable(feed.Entries, func(i, j int) bool { return feed.Entries[i].Updated > feed.Entries[j].Updated })
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	feed.Updated = updated.UTC().Format(time.RFC3339)
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		log.Printf("⚠️  Failed to write feed: %v", err)
+	}
+}
 
-        <div class="tabs" role="tablist">
-            <button class="tab active" role="tab" aria-selected="true" aria-controls="config-tab" onclick="showTab('config')">⚙️ Configuration</button>
-            <button class="tab" role="tab" aria-selected="false" aria-controls="template-tab" onclick="showTab('template')">📝 Email Template</button>
-            <button class="tab" role="tab" aria-selected="false" aria-controls="logs-tab" onclick="showTab('logs')">📋 Logs</button>
-            <button class="tab" role="tab" aria-selected="false" aria-controls="update-tab" onclick="showTab('update')">🔄 Update</button>
-        </div>
+// iCalendar (RFC 5545) of the upcoming episodes and movies in data
+func buildCalendar(name string, data NewsletterData) []byte {
+	var b strings.Builder
+	line := func(s string) {
+		// Fold long lines at 75 octets without splitting UTF-8 sequences;
+		// continuation lines get 74 since their leading space counts
+		limit := 75
+		for len(s) > limit {
+			cut := limit
+			for cut > 0 && !utf8.RuneStart(s[cut]) {
+				cut--
+			}
+			b.WriteString(s[:cut] + "\r\n ")
+			s = s[cut:]
+			limit = 74
+		}
+		b.WriteString(s + "\r\n")
+	}
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//Newslettar//" + version + "//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + icsEscape(name))
+
+	groups := append(append([]SeriesGroup{}, data.UpcomingSeriesGroups...), data.FollowedSeriesGroups...)
+	for _, g := range groups {
+		for _, ep := range g.Episodes {
+			if ep.Downloaded {
+				continue
+			}
+			// Exact air time, else an all-day event; without either there is no event
+			var dates []string
+			if !ep.AirTime.IsZero() {
+				runtime := ep.Runtime
+				if runtime <= 0 {
+					runtime = 30
+				}
+				dates = []string{
+					"DTSTART:" + ep.AirTime.UTC().Format("20060102T150405Z"),
+					"DTEND:" + ep.AirTime.Add(time.Duration(runtime)*time.Minute).UTC().Format("20060102T150405Z"),
+				}
+			} else if day, err := time.Parse("2006-01-02", ep.AirDate); err == nil {
+				dates = []string{
+					"DTSTART;VALUE=DATE:" + day.Format("20060102"),
+					"DTEND;VALUE=DATE:" + day.AddDate(0, 0, 1).Format("20060102"),
+				}
+			} else {
+				continue
+			}
+			line("BEGIN:VEVENT")
+			line(fmt.Sprintf("UID:episode-%d-s%02de%02d@newslettar", ep.TvdbID, ep.SeasonNum, ep.EpisodeNum))
+			line("DTSTAMP:" + stamp)
+			for _, d := range dates {
+				line(d)
+			}
+			line("SUMMARY:" + icsEscape(fmt.Sprintf("%s S%02dE%02d - %s", ep.SeriesTitle, ep.SeasonNum, ep.EpisodeNum, ep.Title)))
+			if ep.IMDBID != "" {
+				line("URL:https://www.imdb.com/title/" + ep.IMDBID + "/")
+			}
+			line("END:VEVENT")
+		}
+	}
 
-        <div id="config-tab" class="tab-content active" role="tabpanel">
-            <div class="info-banner">
-                <p><strong>⏰ Next Scheduled Send:</strong> ` + nextRun + `</p>
-                <p><strong>🌍 Timezone:</strong> <span id="current-timezone">` + cfg.Timezone + `</span></p>
-                <p style="margin-top: 10px; font-size: 0.9em; opacity: 0.8;">
-                    ℹ️ Scheduler runs internally (no systemd timer needed). Changes apply immediately.
-                </p>
-            </div>
+	movies := append(append([]Movie{}, data.UpcomingMovies...), data.FollowedMovies...)
+	for _, m := range movies {
+		if m.Downloaded {
+			continue
+		}
+		day, err := time.Parse("2006-01-02", m.ReleaseDate)
+		if err != nil {
+			continue
+		}
+		title := m.Title
+		if m.Year > 0 {
+			title = fmt.Sprintf("%s (%d)", m.Title, m.Year)
+		}
+		line("BEGIN:VEVENT")
+		line(fmt.Sprintf("UID:movie-%d@newslettar", m.TmdbID))
+		line("DTSTAMP:" + stamp)
+		line("DTSTART;VALUE=DATE:" + day.Format("20060102"))
+		line("DTEND;VALUE=DATE:" + day.AddDate(0, 0, 1).Format("20060102"))
+		if label := releaseLabel(m.ReleaseType); label != "" {
+			title += " - " + label
+		}
+		line("SUMMARY:" + icsEscape("🎬 "+title))
+		if m.IMDBID != "" {
+			line("URL:https://www.imdb.com/title/" + m.IMDBID + "/")
+		}
+		line("END:VEVENT")
+	}
 
-            <form id="config-form">
-                <h3 style="margin-bottom: 15px; color: #667eea;">Schedule Settings</h3>
-                
-                <div class="form-group">
-                    <label for="timezone">Timezone</label>
-                    <select name="timezone" id="timezone" aria-label="Select timezone">
-                        <option value="UTC">UTC (GMT+0)</option>
-                        <option value="America/New_York">Eastern Time (GMT-5/-4)</option>
-                        <option value="America/Chicago">Central Time (GMT-6/-5)</option>
-                        <option value="America/Denver">Mountain Time (GMT-7/-6)</option>
-                        <option value="America/Los_Angeles">Pacific Time (GMT-8/-7)</option>
-                        <option value="America/Toronto">Toronto (GMT-5/-4)</option>
-                        <option value="America/Vancouver">Vancouver (GMT-8/-7)</option>
-                        <option value="America/Montreal">Montreal (GMT-5/-4)</option>
-                        <option value="Europe/London">London (GMT+0/+1)</option>
-                        <option value="Europe/Paris">Paris (GMT+1/+2)</option>
-                        <option value="Europe/Berlin">Berlin (GMT+1/+2)</option>
-                        <option value="Asia/Tokyo">Tokyo (GMT+9)</option>
-                        <option value="Asia/Shanghai">Shanghai (GMT+8)</option>
-                        <option value="Australia/Sydney">Sydney (GMT+10/+11)</option>
-                    </select>
-                    <div class="timezone-info" id="timezone-info"></div>
-                </div>
+	line("END:VCALENDAR")
+	return []byte(b.String())
+}
 
-                <div class="form-group">
-                    <label for="schedule_day">Day of Week</label>
-                    <select name="schedule_day" id="schedule_day" aria-label="Select day of week">
-                        <option value="Sun">Sunday</option>
-                        <option value="Mon">Monday</option>
-                        <option value="Tue">Tuesday</option>
-                        <option value="Wed">Wednesday</option>
-                        <option value="Thu">Thursday</option>
-                        <option value="Fri">Friday</option>
-                        <option value="Sat">Saturday</option>
-                    </select>
-                </div>
+func icsEscape(s string) string {
+	return strings.NewReplacer("\\", "\\\\", ";", "\\;", ",", "\\,", "\r\n", "\\n", "\n", "\\n", "\r", "\\n").Replace(s)
+}
 
-                <div class="form-group">
-                    <label for="schedule_time">Time (24-hour format, HH:MM)</label>
-                    <input type="time" name="schedule_time" id="schedule_time" required aria-label="Select time">
-                    <div class="error-message" id="time-error">Please enter a valid time (HH:MM)</div>
-                </div>
+// .ics attachment for a newsletter (nothing when there are no upcoming releases)
+func calendarAttachment(p Profile, data NewsletterData) []Attachment {
+	if len(data.UpcomingSeriesGroups) == 0 && len(data.UpcomingMovies) == 0 &&
+		len(data.FollowedSeriesGroups) == 0 && len(data.FollowedMovies) == 0 {
+		return nil
+	}
+	return []Attachment{{
+		Filename:    "upcoming.ics",
+		ContentType: "text/calendar; charset=UTF-8; method=PUBLISH",
+		Data:        buildCalendar(p.Name, data),
+	}}
+}
 
-                <hr style="margin: 30px 0; border: none; border-top: 2px solid #2a3444;">
+// Subscribable calendar of upcoming releases (/calendar.ics?token=...&profile=<id>)
+func calendarHandler(w http.ResponseWriter, r *http.Request) {
+	cfg := getConfig()
+	if !validFeedToken(cfg, r.URL.Query().Get("token")) {
+		http.Error(w, "invalid feed token", http.StatusForbidden)
+		return
+	}
 
-                <h3 style="margin-bottom: 15px; color: #667eea;">Sonarr Settings</h3>
-                <div class="form-group">
-                    <label for="sonarr_url">Sonarr URL</label>
-                    <input type="url" name="sonarr_url" id="sonarr_url" placeholder="http://localhost:8989" aria-label="Sonarr URL">
-                    <div class="error-message" id="sonarr-url-error">Please enter a valid URL</div>
-                </div>
-                <div class="form-group">
-                    <label for="sonarr_api_key">Sonarr API Key</label>
-                    <input type="text" name="sonarr_api_key" id="sonarr_api_key" placeholder="Your Sonarr API key" aria-label="Sonarr API Key">
-                </div>
-                <button type="button" class="btn btn-secondary" onclick="testConnection('sonarr')" aria-label="Test Sonarr connection">
-                    <span>Test Sonarr</span>
-                </button>
+	p, ok := findProfile(r.URL.Query().Get("profile"))
+	if !ok {
+		http.Error(w, "unknown newsletter profile", http.StatusNotFound)
+		return
+	}
 
-                <hr style="margin: 30px 0; border: none; border-top: 2px solid #2a3444;">
+	data := cachedSectionData(cfg, p, SectionUpcomingSeries, SectionUpcomingMovies)
 
-                <h3 style="margin-bottom: 15px; color: #667eea;">Radarr Settings</h3>
-                <div class="form-group">
-                    <label for="radarr_url">Radarr URL</label>
-                    <input type="url" name="radarr_url" id="radarr_url" placeholder="http://localhost:7878" aria-label="Radarr URL">
-                    <div class="error-message" id="radarr-url-error">Please enter a valid URL</div>
-                </div>
-                <div class="form-group">
-                    <label for="radarr_api_key">Radarr API Key</label>
-                    <input type="text" name="radarr_api_key" id="radarr_api_key" placeholder="Your Radarr API key" aria-label="Radarr API Key">
-                </div>
-                <button type="button" class="btn btn-secondary" onclick="testConnection('radarr')" aria-label="Test Radarr connection">
-                    <span>Test Radarr</span>
-                </button>
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="newslettar.ics"`)
+	w.Write(buildCalendar(p.Name, data))
+}
 
-                <hr style="margin: 30px 0; border: none; border-top: 2px solid #2a3444;">
+// Archive index: every issue when public, or the issues one recipient received (signed link)
+func archiveIndexHandler(w http.ResponseWriter, r *http.Request) {
+	cfg := getConfig()
+	if cfg.ArchiveAccess == ArchiveOff {
+		http.NotFound(w, r)
+		return
+	}
 
-                <h3 style="margin-bottom: 15px; color: #667eea;">Email Settings</h3>
-                <div class="form-group">
-                    <label for="mailgun_smtp">SMTP Server</label>
-                    <input type="text" name="mailgun_smtp" id="mailgun_smtp" placeholder="smtp.mailgun.org" aria-label="SMTP Server">
-                </div>
-                <div class="form-group">
-                    <label for="mailgun_port">SMTP Port</label>
-                    <input type="number" name="mailgun_port" id="mailgun_port" placeholder="587" aria-label="SMTP Port">
-                </div>
-                <div class="form-group">
-                    <label for="mailgun_user">SMTP Username</label>
-                    <input type="text" name="mailgun_user" id="mailgun_user" placeholder="postmaster@yourdomain.com" aria-label="SMTP Username">
-                </div>
-                <div class="form-group">
-                    <label for="mailgun_pass">SMTP Password</label>
-                    <input type="password" name="mailgun_pass" id="mailgun_pass" placeholder="Your SMTP password" aria-label="SMTP Password">
-                </div>
-                <div class="form-group">
-                    <label for="from_name">From Name</label>
-                    <input type="text" name="from_name" id="from_name" placeholder="Newslettar" aria-label="From Name">
-                </div>
-                <div class="form-group">
-                    <label for="from_email">From Email</label>
-                    <input type="email" name="from_email" id="from_email" placeholder="newsletter@yourdomain.com" aria-label="From Email">
-                    <div class="error-message" id="from-email-error">Please enter a valid email address</div>
-                </div>
-                <div class="form-group">
-                    <label for="to_emails">To Emails (comma-separated)</label>
-                    <input type="text" name="to_emails" id="to_emails" placeholder="user@example.com, user2@example.com" aria-label="To Emails">
-                    <div class="error-message" id="to-emails-error">Please enter valid email addresses</div>
-                </div>
-                <button type="button" class="btn btn-secondary" onclick="testConnection('email')" aria-label="Test email authentication">
-                    <span>Test Email Auth</span>
-                </button>
+	key := r.URL.Query().Get("r")
+	if cfg.ArchiveAccess == ArchiveSigned && !validLinkSignature(r.URL.Query().Get("sig"), "index", key) {
+		renderPublicPage(w, http.StatusForbidden, "Private archive",
+			"This archive is private. Use the \"Past issues\" link at the bottom of any newsletter email.")
+		return
+	}
+
+	type issueLink struct {
+		Subject     string
+		ProfileName string
+		SentAt      string
+		URL         string
+	}
+	loc := getTimezone(cfg.Timezone)
+	links := []issueLink{}
+	for _, issue := range loadIssues() {
+		if cfg.ArchiveAccess == ArchiveSigned && !containsString(issue.Readers, key) {
+			continue
+		}
+		link := "/archive/" + issue.ID
+		if cfg.ArchiveAccess == ArchiveSigned {
+			link += "?r=" + key + "&sig=" + signLink("issue", issue.ID, key)
+		}
+		links = append(links, issueLink{
+			Subject:     issue.Subject,
+			ProfileName: issue.ProfileName,
+			SentAt:      issue.SentAt.In(loc).Format("Monday, January 2, 2006"),
+			URL:         link,
+		})
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	archivePageTemplate.Execute(w, map[string]interface{}{"Issues": links})
+}
+
+// A single archived issue (/archive/{id}); signed links show the recipient's own copy when one exists
+func archiveIssueHandler(w http.ResponseWriter, r *http.Request) {
+	cfg := getConfig()
+	if cfg.ArchiveAccess == ArchiveOff {
+		http.NotFound(w, r)
+		return
+	}
+
+	issue, ok := findIssue(r.PathValue("id"))
+	if !ok {
+		renderPublicPage(w, http.StatusNotFound, "Issue not found", "This newsletter issue does not exist or has been removed.")
+		return
+	}
+
+	key := r.URL.Query().Get("r")
+	signed := validLinkSignature(r.URL.Query().Get("sig"), "issue", issue.ID, key)
+	if cfg.ArchiveAccess == ArchiveSigned && !signed {
+		renderPublicPage(w, http.StatusForbidden, "Private archive",
+			"This link is not valid. Use the \"View in browser\" link from your newsletter email.")
+		return
+	}
+
+	dir := dataPath("archive")
+	html, err := os.ReadFile(filepath.Join(dir, issue.ID+".html"))
+	if signed {
+		if own, ownErr := os.ReadFile(filepath.Join(dir, issue.ID+"-"+key+".html")); ownErr == nil {
+			html, err = own, nil
+		}
+	}
+	if err != nil {
+		renderPublicPage(w, http.StatusNotFound, "Issue not found", "This newsletter issue is no longer available.")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(html)
+}
+
+func renderPublicPage(w http.ResponseWriter, status int, title, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	subscribePageTemplate.Execute(w, map[string]interface{}{
+		"Title":   title,
+		"Message": message,
+	})
+}
+
+func subscribePageHandler(w http.ResponseWriter, r *http.Request) {
+	cfg := getConfig()
+	if !cfg.signupOpen() {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	subscribePageTemplate.Execute(w, map[string]interface{}{
+		"Title":          "Subscribe to Newslettar",
+		"Form":           true,
+		"InviteRequired": len(cfg.InviteCodes) > 0,
+	})
+}
+
+func subscribeHandler(w http.ResponseWriter, r *http.Request) {
+	cfg := getConfig()
+	if !cfg.signupOpen() {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	respond := func(status int, success bool, message string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": success,
+			"message": message,
+		})
+	}
+
+	if !signupLimiter.allow(clientIP(r)) {
+		respond(http.StatusTooManyRequests, false, "Too many attempts, please try again later")
+		return
+	}
+
+	var req struct {
+		Email      string `json:"email"`
+		InviteCode string `json:"invite_code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond(http.StatusBadRequest, false, "Invalid request")
+		return
+	}
+
+	email := strings.TrimSpace(req.Email)
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		respond(http.StatusBadRequest, false, "Please enter a valid email address")
+		return
+	}
+
+	inviteCode := strings.TrimSpace(req.InviteCode)
+	if len(cfg.InviteCodes) > 0 && !isValidInviteCode(cfg, inviteCode) {
+		log.Printf("⚠️  Sign-up rejected for %s: invalid invite code", email)
+		respond(http.StatusForbidden, false, "Invalid invite code")
+		return
+	}
+
+	const pendingMessage = "Almost done! Check your inbox for a confirmation link."
+
+	subscribersMu.Lock()
+	subs := loadSubscribersLocked()
+	idx := -1
+	for i := range subs {
+		if strings.EqualFold(subs[i].Email, email) {
+			idx = i
+			break
+		}
+	}
+
+	// Already confirmed: answer the same way so the endpoint doesn't reveal who is subscribed
+	if idx >= 0 && subs[idx].Status == "active" {
+		subscribersMu.Unlock()
+		respond(http.StatusOK, true, pendingMessage)
+		return
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		subscribersMu.Unlock()
+		respond(http.StatusInternalServerError, false, "Failed to create subscription")
+		return
+	}
+
+	sub := Subscriber{
+		Email:      email,
+		Status:     "pending",
+		Token:      token,
+		InviteCode: inviteCode,
+		CreatedAt:  time.Now(),
+	}
+	if idx >= 0 {
+		subs[idx] = sub
+	} else {
+		subs = append(subs, sub)
+	}
+	err = saveSubscribersLocked(subs)
+	subscribersMu.Unlock()
+
+	if err != nil {
+		log.Printf("❌ Failed to save subscribers: %v", err)
+		respond(http.StatusInternalServerError, false, "Failed to create subscription")
+		return
+	}
+
+	confirmURL := cfg.PublicURL + "/subscribe/confirm?token=" + token
+	if err := sendConfirmationEmail(cfg, email, confirmURL); err != nil {
+		log.Printf("❌ Failed to send confirmation email to %s: %v", email, err)
+		respond(http.StatusBadGateway, false, "Could not send the confirmation email, please try again later")
+		return
+	}
+
+	log.Printf("📨 Confirmation email sent to %s", email)
+	respond(http.StatusOK, true, pendingMessage)
+}
+
+func confirmSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	cfg := getConfig()
+	if !cfg.signupOpen() {
+		http.NotFound(w, r)
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		renderPublicPage(w, http.StatusBadRequest, "Invalid link", "This confirmation link is not valid.")
+		return
+	}
+
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+
+	subs := loadSubscribersLocked()
+	for i := range subs {
+		if subs[i].Token != token {
+			continue
+		}
+
+		if subs[i].Status == "active" {
+			renderPublicPage(w, http.StatusOK, "Already confirmed", "Your subscription is already active.")
+			return
+		}
+
+		if time.Since(subs[i].CreatedAt) > confirmTokenTTL {
+			renderPublicPage(w, http.StatusGone, "Link expired", "This confirmation link has expired. Please sign up again.")
+			return
+		}
+
+		prefsToken, err := generateToken()
+		if err != nil {
+			log.Printf("❌ Failed to generate preferences token: %v", err)
+			renderPublicPage(w, http.StatusInternalServerError, "Something went wrong", "Please try again later.")
+			return
+		}
+		now := time.Now()
+		subs[i].Status = "active"
+		subs[i].ConfirmedAt = &now
+		subs[i].PrefsToken = prefsToken
+		if err := saveSubscribersLocked(subs); err != nil {
+			log.Printf("❌ Failed to save subscribers: %v", err)
+			renderPublicPage(w, http.StatusInternalServerError, "Something went wrong", "Please try again later.")
+			return
+		}
+
+		log.Printf("✅ Subscription confirmed: %s", subs[i].Email)
+		renderPublicPage(w, http.StatusOK, "Subscription confirmed", "You're on the list! The next newsletter will land in your inbox.")
+		return
+	}
+
+	renderPublicPage(w, http.StatusNotFound, "Invalid link", "This confirmation link is not valid.")
+}
+
+func sendConfirmationEmail(cfg *Config, email, confirmURL string) error {
+	var body bytes.Buffer
+	err := template.Must(template.New("confirm").Parse(`<!DOCTYPE html>
+<html><body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Arial, sans-serif; background-color: #0f1419; color: #e8e8e8; padding: 20px;">
+    <div style="max-width: 600px; margin: 0 auto; background-color: #1a2332; padding: 30px; border-radius: 12px;">
+        <h2 style="color: #667eea;">📺 Confirm your subscription</h2>
+        <p>Someone (hopefully you) asked to receive the Newslettar newsletter at {{.Email}}.</p>
+        <p style="margin: 30px 0;"><a href="{{.URL}}" style="background: #667eea; color: white; padding: 12px 24px; border-radius: 8px; text-decoration: none;">Confirm subscription</a></p>
+        <p style="color: #8899aa; font-size: 0.9em;">If you didn't sign up, just ignore this email.</p>
+    </div>
+</body></html>`)).Execute(&body, map[string]string{"Email": email, "URL": confirmURL})
+	if err != nil {
+		return err
+	}
 
-                <hr style="margin: 30px 0; border: none; border-top: 2px solid #2a3444;">
+	return sendEmail(cfg.emailTransport(), []string{email}, "Confirm your Newslettar subscription", body.String())
+}
 
-                <button type="submit" class="btn" aria-label="Save configuration">
-                    <span>💾 Save Configuration</span>
-                </button>
-            </form>
-        </div>
+func isValidInviteCode(cfg *Config, code string) bool {
+	for _, valid := range cfg.InviteCodes {
+		if subtle.ConstantTimeCompare([]byte(valid), []byte(code)) == 1 {
+			return true
+		}
+	}
+	return false
+}
 
-        <div id="template-tab" class="tab-content" role="tabpanel">
-            <h3 style="margin-bottom: 20px;">Email Template Options</h3>
-            
-            <div class="template-option">
-                <div>
-                    <strong>Show Movie/Series Posters</strong>
-                    <p style="font-size: 0.9em; color: #8899aa; margin-top: 5px;">
-                        Display poster images in the newsletter
-                    </p>
-                </div>
-                <label class="toggle-switch">
-                    <input type="checkbox" id="show-posters" onchange="saveTemplateSettings()" aria-label="Toggle poster display">
-                    <span class="toggle-slider"></span>
-                </label>
-            </div>
+// Sliding-window rate limit: at most limit attempts per key within window
+type rateLimiter struct {
+	mu        sync.Mutex
+	attempts  map[string][]time.Time
+	limit     int
+	window    time.Duration
+	lastSweep time.Time
+}
 
-            <div class="template-option">
-                <div>
-                    <strong>Show Downloaded Section</strong>
-                    <p style="font-size: 0.9em; color: #8899aa; margin-top: 5px;">
-                        Include "Downloaded This Week" section
-                    </p>
-                </div>
-                <label class="toggle-switch">
-                    <input type="checkbox" id="show-downloaded" onchange="saveTemplateSettings()" aria-label="Toggle downloaded section">
-                    <span class="toggle-slider"></span>
-                </label>
-            </div>
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{attempts: make(map[string][]time.Time), limit: limit, window: window}
+}
 
-            <p style="margin-top: 20px; color: #8899aa; font-size: 0.9em;">
-                ℹ️ Changes are saved automatically when you toggle switches.
-            </p>
+func (l *rateLimiter) allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 
-            <hr style="margin: 30px 0; border: none; border-top: 2px solid #2a3444;">
+	now := time.Now()
+	// Forget keys with no attempt left in the window, so the map doesn't grow with every IP seen
+	if now.Sub(l.lastSweep) > l.window {
+		for k, times := range l.attempts {
+			if len(times) == 0 || now.Sub(times[len(times)-1]) >= l.window {
+				delete(l.attempts, k)
+			}
+		}
+		l.lastSweep = now
+	}
 
-            <h3 style="margin-bottom: 20px;">Actions</h3>
-            
-            <div class="action-buttons">
-                <button class="btn btn-secondary" onclick="previewNewsletter()" aria-label="Preview newsletter">
-                    <span>👁️ Preview Newsletter</span>
-                </button>
-                <button class="btn btn-success" onclick="sendNow()" aria-label="Send newsletter now">
-                    <span>📧 Send Newsletter Now</span>
-                </button>
-            </div>
-            
-            <p style="margin-top: 15px; color: #8899aa; font-size: 0.9em;">
-                Preview generates the email based on current settings without sending. Send Now will generate and send immediately.
-            </p>
-        </div>
+	recent := l.attempts[key][:0]
+	for _, t := range l.attempts[key] {
+		if now.Sub(t) < l.window {
+			recent = append(recent, t)
+		}
+	}
 
-        <div id="logs-tab" class="tab-content" role="tabpanel">
-            <h3 style="margin-bottom: 15px;">📋 Newsletter Logs</h3>
-            <button class="btn btn-secondary" onclick="loadLogs()" style="margin-bottom: 15px;" aria-label="Refresh logs">
-                <span>🔄 Refresh Logs</span>
-            </button>
-            <div class="logs-container" id="logs" role="log" aria-live="polite"></div>
-        </div>
+	if len(recent) >= l.limit {
+		l.attempts[key] = recent
+		return false
+	}
 
-        <div id="update-tab" class="tab-content" role="tabpanel">
-            <h3 style="margin-bottom: 20px;">🔄 Update Newslettar</h3>
-            
-            <div id="version-info" aria-live="polite">
-                <p>Checking for updates...</p>
-            </div>
+	l.attempts[key] = append(recent, now)
+	return true
+}
 
-            <button class="btn" onclick="checkUpdates()" style="margin-right: 10px;" aria-label="Check for updates">
-                <span>🔍 Check for Updates</span>
-            </button>
-            <button class="btn btn-success" id="update-btn" onclick="performUpdate()" style="display: none;" aria-label="Update now">
-                <span>⬇️ Update Now</span>
-            </button>
-        </div>
-    </div>
+// Address of the peer the request came from (a reverse proxy, if there is one)
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
 
-    <!-- Preview Modal -->
-    <div id="preview-modal" class="modal" role="dialog" aria-labelledby="preview-title" aria-modal="true">
-        <div class="modal-content">
-            <div class="modal-header">
-                <h2 id="preview-title">Email Preview</h2>
-                <button class="modal-close" onclick="closePreview()" aria-label="Close preview">&times;</button>
-            </div>
-            <div class="modal-body">
-                <iframe id="preview-frame" title="Email preview"></iframe>
-            </div>
-        </div>
-    </div>
+// Address of the client: behind a trusted proxy, the last X-Forwarded-For entry
+// not added by a trusted proxy (earlier entries can be forged by the client)
+func clientIP(r *http.Request) string {
+	ip := remoteIP(r)
+	cfg := getConfig()
+	if !isTrustedProxy(cfg, ip) {
+		return ip
+	}
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(forwarded[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		ip = hop
+		if !isTrustedProxy(cfg, hop) {
+			break
+		}
+	}
+	return ip
+}
 
-    <!-- Loading Overlay -->
-    <div id="loading-overlay" class="loading-overlay" role="status" aria-live="polite">
-        <div class="loading-spinner"></div>
-    </div>
+// Base URL for links served back to the requester (PUBLIC_URL, or derived from the request).
+// Links sent by email always use PUBLIC_URL.
+func publicBaseURL(cfg *Config, r *http.Request) string {
+	if cfg.PublicURL != "" {
+		return cfg.PublicURL
+	}
+	scheme := "http"
+	if requestIsHTTPS(r) {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
 
-    <script>
-        let logsInterval;
+// Whether the client connected over HTTPS: directly, or through a trusted proxy that
+// says so (anyone else could set X-Forwarded-Proto)
+func requestIsHTTPS(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	return r.Header.Get("X-Forwarded-Proto") == "https" && isTrustedProxy(getConfig(), remoteIP(r))
+}
 
-        // Keyboard navigation
-        document.addEventListener('keydown', (e) => {
-            if (e.key === 'Escape') {
-                closePreview();
-            }
-        });
+// Local admin account (data/auth.json), created on the login page at first launch
+type AdminAccount struct {
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"password_hash"` // bcrypt
+	UpdatedAt    time.Time `json:"updated_at"`
+}
 
-        function showTab(tabName) {
-            document.querySelectorAll('.tab').forEach(t => {
-                t.classList.remove('active');
-                t.setAttribute('aria-selected', 'false');
-            });
-            document.querySelectorAll('.tab-content').forEach(t => t.classList.remove('active'));
-            
-            event.target.classList.add('active');
-            event.target.setAttribute('aria-selected', 'true');
-            document.getElementById(tabName + '-tab').classList.add('active');
+// Signed-in browser (data/sessions.json); only a hash of the cookie value is stored
+type Session struct {
+	TokenHash string    `json:"token_hash"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
 
-            if (tabName === 'logs') {
-                loadLogs();
-                logsInterval = setInterval(loadLogs, 5000);
-            } else {
-                if (logsInterval) {
-                    clearInterval(logsInterval);
-                }
-            }
-        }
+const (
+	sessionCookie     = "newslettar_session"
+	sessionTTL        = 30 * 24 * time.Hour
+	minPasswordLength = 8
+)
 
-        // Real-time validation
-        function validateURL(input) {
-            const value = input.value.trim();
-            if (!value) return true;
-            
-            try {
-                new URL(value);
-                return true;
-            } catch {
-                return false;
-            }
-        }
+var (
+	authMu    sync.Mutex
+	sessions  map[string]Session // by token hash, read from disk on first use
+	setupCode string             // one-time code for creating the account, printed to the log
+)
 
-        function validateEmail(email) {
-            return /^[^\s@]+@[^\s@]+\.[^\s@]+$/.test(email);
-        }
+var errAccountExists = errors.New("an admin account already exists")
 
-        function validateEmails(input) {
-            const value = input.value.trim();
-            if (!value) return true;
-            
-            const emails = value.split(',').map(e => e.trim());
-            return emails.every(email => validateEmail(email));
-        }
+func loadAccount() (AdminAccount, bool) {
+	authMu.Lock()
+	defer authMu.Unlock()
+	var account AdminAccount
+	if err := readJSONFile("auth.json", &account); err != nil {
+		log.Printf("⚠️  Failed to read admin account: %v", err)
+	}
+	return account, account.PasswordHash != ""
+}
 
-        // Add validation listeners
-        document.addEventListener('DOMContentLoaded', () => {
-            const sonarrUrl = document.getElementById('sonarr_url');
-            const radarrUrl = document.getElementById('radarr_url');
-            const fromEmail = document.getElementById('from_email');
-            const toEmails = document.getElementById('to_emails');
+func newAccount(username, password string) (AdminAccount, error) {
+	if strings.TrimSpace(username) == "" {
+		return AdminAccount{}, fmt.Errorf("username is required")
+	}
+	if len(password) < minPasswordLength {
+		return AdminAccount{}, fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return AdminAccount{}, err
+	}
+	return AdminAccount{Username: strings.TrimSpace(username), PasswordHash: string(hash), UpdatedAt: time.Now()}, nil
+}
 
-            sonarrUrl.addEventListener('blur', function() {
-                if (this.value && !validateURL(this)) {
-                    this.classList.add('error');
-                    this.classList.remove('success');
-                    document.getElementById('sonarr-url-error').classList.add('show');
-                } else if (this.value) {
-                    this.classList.remove('error');
-                    this.classList.add('success');
-                    document.getElementById('sonarr-url-error').classList.remove('show');
-                }
-            });
+// Create or replace the admin account (-set-password); every existing session is signed out
+func saveAccount(username, password string) error {
+	account, err := newAccount(username, password)
+	if err != nil {
+		return err
+	}
 
-            radarrUrl.addEventListener('blur', function() {
-                if (this.value && !validateURL(this)) {
-                    this.classList.add('error');
-                    this.classList.remove('success');
-                    document.getElementById('radarr-url-error').classList.add('show');
-                } else if (this.value) {
-                    this.classList.remove('error');
-                    this.classList.add('success');
-                    document.getElementById('radarr-url-error').classList.remove('show');
-                }
-            });
+	authMu.Lock()
+	defer authMu.Unlock()
+	if err := writeJSONFile("auth.json", account); err != nil {
+		return err
+	}
+	sessions = make(map[string]Session)
+	return saveSessionsLocked()
+}
 
-            fromEmail.addEventListener('blur', function() {
-                if (this.value && !validateEmail(this.value)) {
-                    this.classList.add('error');
-                    this.classList.remove('success');
-                    document.getElementById('from-email-error').classList.add('show');
-                } else if (this.value) {
-                    this.classList.remove('error');
-                    this.classList.add('success');
-                    document.getElementById('from-email-error').classList.remove('show');
-                }
-            });
+// First-run setup from the login page: only while no account exists, checked and
+// written under authMu so a second concurrent setup can't replace the first
+func createAccount(username, password string) error {
+	account, err := newAccount(username, password)
+	if err != nil {
+		return err
+	}
 
-            toEmails.addEventListener('blur', function() {
-                if (this.value && !validateEmails(this)) {
-                    this.classList.add('error');
-                    this.classList.remove('success');
-                    document.getElementById('to-emails-error').classList.add('show');
-                } else if (this.value) {
-                    this.classList.remove('error');
-                    this.classList.add('success');
-                    document.getElementById('to-emails-error').classList.remove('show');
-                }
-            });
+	authMu.Lock()
+	defer authMu.Unlock()
+	var existing AdminAccount
+	if err := readJSONFile("auth.json", &existing); err != nil {
+		return err
+	}
+	if existing.PasswordHash != "" {
+		return errAccountExists
+	}
+	if err := writeJSONFile("auth.json", account); err != nil {
+		return err
+	}
+	setupCode = ""
+	sessions = make(map[string]Session)
+	return saveSessionsLocked()
+}
 
-            // Update timezone info on change
-            document.getElementById('timezone').addEventListener('change', updateTimezoneInfo);
-        });
+// Caller holds authMu; the code is made (and logged) when first needed
+func setupCodeLocked() string {
+	if setupCode == "" {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return ""
+		}
+		setupCode = strings.ToUpper(hex.EncodeToString(b))
+		log.Printf("🔐 No admin account yet: open the web UI and enter setup code %s (or run newslettar -set-password)", setupCode)
+	}
+	return setupCode
+}
 
-        async function updateTimezoneInfo() {
-            const tz = document.getElementById('timezone').value;
-            try {
-                const resp = await fetch('/api/timezone-info?tz=' + encodeURIComponent(tz));
-                const data = await resp.json();
-                
-                document.getElementById('timezone-info').innerHTML = 
-                    '<strong>Current time:</strong> ' + data.current_time + 
-                    ' <strong>•</strong> Offset: ' + data.offset;
-            } catch (error) {
-                console.error('Failed to fetch timezone info:', error);
-            }
-        }
+// Log the setup code at startup while no account exists, so whoever installed it can find it
+func announceSetupCode() {
+	if _, exists := loadAccount(); exists {
+		return
+	}
+	authMu.Lock()
+	defer authMu.Unlock()
+	setupCodeLocked()
+}
+
+// Whether code is the setup code printed to the log (only the server's operator can see it)
+func checkSetupCode(code string) bool {
+	authMu.Lock()
+	defer authMu.Unlock()
+	var existing AdminAccount
+	if readJSONFile("auth.json", &existing); existing.PasswordHash != "" {
+		return false // created meanwhile; createAccount would refuse anyway
+	}
+	expected := setupCodeLocked()
+	code = strings.ToUpper(strings.TrimSpace(code))
+	return expected != "" && subtle.ConstantTimeCompare([]byte(code), []byte(expected)) == 1
+}
+
+// Username and password match the admin account (bcrypt runs even for a wrong username)
+func checkPassword(account AdminAccount, username, password string) bool {
+	passwordOK := bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(password)) == nil
+	return passwordOK && subtle.ConstantTimeCompare([]byte(username), []byte(account.Username)) == 1
+}
+
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Caller holds authMu
+func sessionsLocked() map[string]Session {
+	if sessions == nil {
+		var stored []Session
+		if err := readJSONFile("sessions.json", &stored); err != nil {
+			log.Printf("⚠️  Failed to read sessions: %v", err)
+		}
+		sessions = make(map[string]Session)
+		for _, s := range stored {
+			sessions[s.TokenHash] = s
+		}
+	}
+	return sessions
+}
+
+// Caller holds authMu; expired sessions are dropped on the way
+func saveSessionsLocked() error {
+	stored := []Session{}
+	for hash, s := range sessionsLocked() {
+		if time.Now().After(s.ExpiresAt) {
+			delete(sessions, hash)
+			continue
+		}
+		stored = append(stored, s)
+	}
+	return writeJSONFile("sessions.json", stored)
+}
+
+func createSession(username string) (string, Session, error) {
+	token, err := generateToken()
+	if err != nil {
+		return "", Session{}, err
+	}
+	now := time.Now()
+	s := Session{TokenHash: hashSessionToken(token), Username: username, CreatedAt: now, ExpiresAt: now.Add(sessionTTL)}
+
+	authMu.Lock()
+	defer authMu.Unlock()
+	sessionsLocked()[s.TokenHash] = s
+	return token, s, saveSessionsLocked()
+}
+
+func lookupSession(token string) (Session, bool) {
+	authMu.Lock()
+	defer authMu.Unlock()
+	s, ok := sessionsLocked()[hashSessionToken(token)]
+	if !ok || time.Now().After(s.ExpiresAt) {
+		return Session{}, false
+	}
+	return s, true
+}
+
+func deleteSession(token string) {
+	authMu.Lock()
+	defer authMu.Unlock()
+	delete(sessionsLocked(), hashSessionToken(token))
+	if err := saveSessionsLocked(); err != nil {
+		log.Printf("⚠️  Failed to save sessions: %v", err)
+	}
+}
+
+// Who a request is authenticated as
+type authUser struct {
+	Name    string
+	Proxy   bool     // signed in by the reverse proxy (logging out is up to the proxy)
+	TokenID string   // set for API token requests
+	Scopes  []string // API token scopes (nil = everything, for signed-in users)
+}
+
+func (u authUser) hasScope(scope string) bool {
+	if u.Scopes == nil {
+		return true
+	}
+	for _, s := range u.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
 
-        async function loadConfig() {
-            showLoading();
-            try {
-                const resp = await fetch('/api/config');
-                const data = await resp.json();
-                
-                document.querySelector('[name="sonarr_url"]').value = data.sonarr_url || '';
-                document.querySelector('[name="sonarr_api_key"]').value = data.sonarr_api_key || '';
-                document.querySelector('[name="radarr_url"]').value = data.radarr_url || '';
-                document.querySelector('[name="radarr_api_key"]').value = data.radarr_api_key || '';
-                document.querySelector('[name="mailgun_smtp"]').value = data.mailgun_smtp || 'smtp.mailgun.org';
-                document.querySelector('[name="mailgun_port"]').value = data.mailgun_port || '587';
-                document.querySelector('[name="mailgun_user"]').value = data.mailgun_user || '';
-                document.querySelector('[name="mailgun_pass"]').value = data.mailgun_pass || '';
-                document.querySelector('[name="from_email"]').value = data.from_email || '';
-                document.querySelector('[name="from_name"]').value = data.from_name || 'Newslettar';
-                document.querySelector('[name="to_emails"]').value = data.to_emails || '';
-                document.querySelector('[name="timezone"]').value = data.timezone || 'UTC';
-                document.querySelector('[name="schedule_day"]').value = data.schedule_day || 'Sun';
-                document.querySelector('[name="schedule_time"]').value = data.schedule_time || '09:00';
-                
-                document.getElementById('show-posters').checked = data.show_posters !== 'false';
-                document.getElementById('show-downloaded').checked = data.show_downloaded !== 'false';
-                
-                document.getElementById('current-timezone').textContent = data.timezone || 'UTC';
-                
-                await updateTimezoneInfo();
-            } catch (error) {
-                showNotification('Failed to load configuration: ' + error.message, 'error');
-            } finally {
-                hideLoading();
-            }
-        }
+// API token scopes; admin includes the others
+const (
+	ScopeRead  = "read"  // status, history, logs and other reads
+	ScopeSend  = "send"  // start and cancel sends
+	ScopeAdmin = "admin" // everything, including settings and tokens
+)
 
-        document.getElementById('config-form').addEventListener('submit', async (e) => {
-            e.preventDefault();
-            
-            const formData = new FormData(e.target);
-            const data = Object.fromEntries(formData);
-            
-            const submitBtn = e.target.querySelector('button[type="submit"]');
-            submitBtn.classList.add('loading');
-            submitBtn.disabled = true;
-            
-            try {
-                const resp = await fetch('/api/config', {
-                    method: 'POST',
-                    headers: {'Content-Type': 'application/json'},
-                    body: JSON.stringify(data)
-                });
+var allScopes = []string{ScopeRead, ScopeSend, ScopeAdmin}
+
+// Scope an API request needs, by route: settings, tokens and updates need admin
+// whatever the method, starting or cancelling a send needs send. On the other
+// routes reads need read and any change admin.
+func requiredScope(r *http.Request) string {
+	path := r.URL.Path
+	switch {
+	case path == "/api/config" || path == "/api/update" || path == "/api/tokens" || strings.HasPrefix(path, "/api/tokens/"):
+		return ScopeAdmin
+	case path == "/api/send" || (strings.HasPrefix(path, "/api/jobs/") && strings.HasSuffix(path, "/cancel")):
+		return ScopeSend
+	case r.Method == "GET" || r.Method == "HEAD":
+		return ScopeRead
+	}
+	return ScopeAdmin
+}
 
-                if (resp.ok) {
-                    showNotification('Configuration saved successfully!', 'success');
-                    setTimeout(() => location.reload(), 2000);
-                } else {
-                    showNotification('Failed to save configuration', 'error');
-                }
-            } catch (error) {
-                showNotification('Network error: ' + error.message, 'error');
-            } finally {
-                submitBtn.classList.remove('loading');
-                submitBtn.disabled = false;
-            }
-        });
+// Named, revocable API token (data/tokens.json); only a hash of the secret is stored
+type APIToken struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	Hash       string     `json:"hash,omitempty"` // blanked in API responses
+	Prefix     string     `json:"prefix"`         // first characters, to recognise the token
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
 
-        async function testConnection(type) {
-            const form = document.getElementById('config-form');
-            const formData = new FormData(form);
-            const data = Object.fromEntries(formData);
-            
-            const button = event.target.closest('button');
-            button.classList.add('loading');
-            button.disabled = true;
+const (
+	apiTokenPrefix = "nlt_"
+	// Last-used times are kept in memory and written out at most this often
+	tokenUsageSaveInterval = time.Minute
+)
 
-            let endpoint, payload;
+var (
+	tokensMu      sync.Mutex
+	apiTokens     []APIToken // read from disk on first use
+	tokensLoaded  bool
+	tokensSavedAt time.Time
+	tokensUnsaved bool
+)
 
-            if (type === 'sonarr') {
-                endpoint = '/api/test-sonarr';
-                payload = { url: data.sonarr_url, api_key: data.sonarr_api_key };
-            } else if (type === 'radarr') {
-                endpoint = '/api/test-radarr';
-                payload = { url: data.radarr_url, api_key: data.radarr_api_key };
-            } else {
-                endpoint = '/api/test-email';
-                payload = {
-                    smtp: data.mailgun_smtp,
-                    port: data.mailgun_port,
-                    user: data.mailgun_user,
-                    pass: data.mailgun_pass
-                };
-            }
+// Caller holds tokensMu
+func tokensLocked() []APIToken {
+	if !tokensLoaded {
+		if err := readJSONFile("tokens.json", &apiTokens); err != nil {
+			log.Printf("⚠️  Failed to read API tokens: %v", err)
+		}
+		tokensLoaded = true
+	}
+	return apiTokens
+}
 
-            try {
-                const resp = await fetch(endpoint, {
-                    method: 'POST',
-                    headers: {'Content-Type': 'application/json'},
-                    body: JSON.stringify(payload)
-                });
+// Caller holds tokensMu
+func saveTokensLocked() error {
+	tokens := tokensLocked()
+	if tokens == nil {
+		tokens = []APIToken{}
+	}
+	if err := writeJSONFile("tokens.json", tokens); err != nil {
+		return err
+	}
+	tokensSavedAt, tokensUnsaved = time.Now(), false
+	return nil
+}
 
-                const result = await resp.json();
-                showNotification(result.message, result.success ? 'success' : 'error');
-            } catch (error) {
-                showNotification('Connection test failed: ' + error.message, 'error');
-            } finally {
-                button.classList.remove('loading');
-                button.disabled = false;
-            }
-        }
+// Token for a bearer secret, recording its use
+func lookupAPIToken(secret string) (APIToken, bool) {
+	hash := hashSessionToken(secret)
 
-        async function previewNewsletter() {
-            const button = event.target.closest('button');
-            button.classList.add('loading');
-            button.disabled = true;
-            
-            showLoading();
+	tokensMu.Lock()
+	defer tokensMu.Unlock()
+	for i, t := range tokensLocked() {
+		if subtle.ConstantTimeCompare([]byte(t.Hash), []byte(hash)) != 1 {
+			continue
+		}
+		now := time.Now()
+		apiTokens[i].LastUsedAt = &now
+		tokensUnsaved = true
+		if now.Sub(tokensSavedAt) >= tokenUsageSaveInterval {
+			if err := saveTokensLocked(); err != nil {
+				log.Printf("⚠️  Failed to save API tokens: %v", err)
+			}
+		}
+		return apiTokens[i], true
+	}
+	return APIToken{}, false
+}
 
-            try {
-                const resp = await fetch('/api/preview', { method: 'POST' });
-                const data = await resp.json();
+// Write out pending last-used times (at shutdown)
+func flushTokenUsage() {
+	tokensMu.Lock()
+	defer tokensMu.Unlock()
+	if tokensUnsaved {
+		if err := saveTokensLocked(); err != nil {
+			log.Printf("⚠️  Failed to save API tokens: %v", err)
+		}
+	}
+}
 
-                if (data.success) {
-                    const iframe = document.getElementById('preview-frame');
-                    iframe.srcdoc = data.html;
-                    document.getElementById('preview-modal').classList.add('show');
-                } else {
-                    showNotification(data.error || 'Failed to generate preview', 'error');
-                }
-            } catch (error) {
-                showNotification('Preview failed: ' + error.message, 'error');
-            } finally {
-                button.classList.remove('loading');
-                button.disabled = false;
-                hideLoading();
-            }
-        }
+// API token management: GET lists, POST creates (the secret is only returned then),
+// DELETE /api/tokens/{id} revokes
+func tokensHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	respond := func(status int, fields map[string]interface{}) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(fields)
+	}
 
-        function closePreview() {
-            document.getElementById('preview-modal').classList.remove('show');
-        }
+	switch r.Method {
+	case "GET":
+		tokensMu.Lock()
+		tokens := append([]APIToken{}, tokensLocked()...)
+		tokensMu.Unlock()
+		for i := range tokens {
+			tokens[i].Hash = ""
+		}
+		json.NewEncoder(w).Encode(tokens)
 
-        async function sendNow() {
-            if (!confirm('Send newsletter now?')) return;
-            
-            const button = event.target.closest('button');
-            button.classList.add('loading');
-            button.disabled = true;
-            
-            showNotification('Sending newsletter...', 'success');
-            
-            try {
-                const resp = await fetch('/api/send', { method: 'POST' });
-                const data = await resp.json();
+	case "POST":
+		var req struct {
+			Name   string   `json:"name"`
+			Scopes []string `json:"scopes"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respond(http.StatusBadRequest, map[string]interface{}{"success": false, "error": "Invalid request"})
+			return
+		}
+		name := strings.TrimSpace(req.Name)
+		if name == "" {
+			respond(http.StatusBadRequest, map[string]interface{}{"success": false, "error": "A token name is required"})
+			return
+		}
+		if len(req.Scopes) == 0 {
+			respond(http.StatusBadRequest, map[string]interface{}{"success": false, "error": "Choose at least one scope"})
+			return
+		}
+		for _, scope := range req.Scopes {
+			if !containsString(allScopes, scope) {
+				respond(http.StatusBadRequest, map[string]interface{}{"success": false, "error": "Unknown scope: " + scope})
+				return
+			}
+		}
 
-                if (data.success) {
-                    showNotification('Newsletter sent successfully!', 'success');
-                } else {
-                    showNotification('Failed to send newsletter', 'error');
-                }
-            } catch (error) {
-                showNotification('Send failed: ' + error.message, 'error');
-            } finally {
-                button.classList.remove('loading');
-                button.disabled = false;
-            }
-        }
+		secret, err := generateToken()
+		id, idErr := generateToken()
+		if err != nil || idErr != nil {
+			respond(http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "Failed to generate token"})
+			return
+		}
+		secret = apiTokenPrefix + secret
+		createdBy := ""
+		if user, ok := currentUser(r); ok {
+			createdBy = user.Name
+		}
+		token := APIToken{
+			ID:        id[:8],
+			Name:      name,
+			Scopes:    req.Scopes,
+			Hash:      hashSessionToken(secret),
+			Prefix:    secret[:len(apiTokenPrefix)+6],
+			CreatedBy: createdBy,
+			CreatedAt: time.Now(),
+		}
 
-        async function loadLogs() {
-            try {
-                const resp = await fetch('/api/logs');
-                const logs = await resp.text();
-                document.getElementById('logs').textContent = logs;
-                document.getElementById('logs').scrollTop = document.getElementById('logs').scrollHeight;
-            } catch (error) {
-                console.error('Failed to load logs:', error);
-            }
-        }
+		tokensMu.Lock()
+		apiTokens = append(tokensLocked(), token)
+		err = saveTokensLocked()
+		tokensMu.Unlock()
+		if err != nil {
+			respond(http.StatusInternalServerError, map[string]interface{}{"success": false, "error": err.Error()})
+			return
+		}
+		log.Printf("🔑 API token %q created (%s)", name, strings.Join(req.Scopes, ", "))
+		token.Hash = ""
+		respond(http.StatusOK, map[string]interface{}{"success": true, "token": secret, "api_token": token})
+
+	case "DELETE":
+		id := r.PathValue("id")
+		tokensMu.Lock()
+		tokens := tokensLocked()
+		idx := -1
+		for i := range tokens {
+			if tokens[i].ID == id {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			tokensMu.Unlock()
+			respond(http.StatusNotFound, map[string]interface{}{"success": false, "error": "Unknown token"})
+			return
+		}
+		name := tokens[idx].Name
+		apiTokens = append(tokens[:idx:idx], tokens[idx+1:]...)
+		err := saveTokensLocked()
+		tokensMu.Unlock()
+		if err != nil {
+			respond(http.StatusInternalServerError, map[string]interface{}{"success": false, "error": err.Error()})
+			return
+		}
+		log.Printf("🔑 API token %q revoked", name)
+		respond(http.StatusOK, map[string]interface{}{"success": true})
 
-        async function saveTemplateSettings() {
-            const showPosters = document.getElementById('show-posters').checked;
-            const showDownloaded = document.getElementById('show-downloaded').checked;
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
 
-            try {
-                await fetch('/api/config', {
-                    method: 'POST',
-                    headers: {'Content-Type': 'application/json'},
-                    body: JSON.stringify({
-                        show_posters: showPosters ? 'true' : 'false',
-                        show_downloaded: showDownloaded ? 'true' : 'false'
-                    })
-                });
+type userContextKey struct{}
 
-                showNotification('Template settings saved', 'success');
-            } catch (error) {
-                showNotification('Failed to save settings: ' + error.message, 'error');
-            }
-        }
+func currentUser(r *http.Request) (authUser, bool) {
+	user, ok := r.Context().Value(userContextKey{}).(authUser)
+	return user, ok
+}
 
-        async function checkUpdates() {
-            const button = event.target;
-            button.classList.add('loading');
-            button.disabled = true;
+// API token (Authorization: Bearer, /api/* only), then the reverse-proxy header from
+// a trusted proxy, then the session cookie
+func authenticate(r *http.Request) (authUser, bool) {
+	if secret, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && strings.HasPrefix(r.URL.Path, "/api/") {
+		token, ok := lookupAPIToken(strings.TrimSpace(secret))
+		if !ok {
+			return authUser{}, false
+		}
+		return authUser{Name: "token:" + token.Name, TokenID: token.ID, Scopes: token.Scopes}, true
+	}
 
-            try {
-                const resp = await fetch('/api/version');
-                const data = await resp.json();
-                
-                let html = '<div style="background: #252f3f; padding: 20px; border-radius: 10px; margin-bottom: 20px;">';
-                html += '<p><strong>Current Version:</strong> ' + data.current_version + '</p>';
-                html += '<p><strong>Latest Version:</strong> ' + data.latest_version + '</p>';
+	cfg := getConfig()
+	if cfg.AuthProxyHeader != "" && isTrustedProxy(cfg, remoteIP(r)) {
+		if name := strings.TrimSpace(r.Header.Get(cfg.AuthProxyHeader)); name != "" {
+			return authUser{Name: name, Proxy: true}, true
+		}
+	}
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return authUser{}, false
+	}
+	s, ok := lookupSession(cookie.Value)
+	if !ok {
+		return authUser{}, false
+	}
+	return authUser{Name: s.Username}, true
+}
 
-                if (data.update_available) {
-                    html += '<p style="color: #38ef7d; margin-top: 15px;"><strong>Update Available!</strong></p>';
-                    html += '<h4 style="margin-top: 15px;">What\'s New:</h4>';
-                    html += '<ul style="margin-left: 20px; margin-top: 10px;">';
-                    data.changelog.forEach(item => {
-                        html += '<li style="margin: 5px 0;">' + item + '</li>';
-                    });
-                    html += '</ul>';
-                    document.getElementById('update-btn').style.display = 'inline-block';
-                } else {
-                    html += '<p style="color: #8899aa; margin-top: 15px;">You are running the latest version!</p>';
-                    document.getElementById('update-btn').style.display = 'none';
-                }
+func isTrustedProxy(cfg *Config, ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, proxy := range cfg.TrustedProxies {
+		if _, network, err := net.ParseCIDR(proxy); err == nil {
+			if network.Contains(addr) {
+				return true
+			}
+		} else if trusted := net.ParseIP(proxy); trusted != nil && trusted.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reachable without signing in: the login page, subscriber-facing pages (which use
+// their own tokens), feeds, health probes and the Prometheus endpoint
+var publicPaths = []string{
+	"/login", "/subscribe", "/api/subscribe", "/preferences", "/api/preferences",
+	"/archive", "/feed.xml", "/calendar.ics", "/healthz", "/readyz", "/metrics",
+}
+
+func isPublicPath(path string) bool {
+	for _, p := range publicPaths {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
 
-                html += '</div>';
-                document.getElementById('version-info').innerHTML = html;
-            } catch (error) {
-                showNotification('Failed to check updates: ' + error.message, 'error');
-            } finally {
-                button.classList.remove('loading');
-                button.disabled = false;
-            }
-        }
+// Require a signed-in user or API token for everything but the public paths. API calls
+// get a 401 (403 when the token lacks the scope), pages redirect to the login page.
+// Cross-origin writes from browsers are refused.
+func withAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isPublicPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
 
-        async function performUpdate() {
-            if (!confirm('Update Newslettar? The page will reload in 20 seconds.')) return;
+		user, ok := authenticate(r)
+		if !ok {
+			if strings.HasPrefix(r.URL.Path, "/api/") {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": false,
+					"error":   "Authentication required",
+				})
+				return
+			}
+			http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+			return
+		}
 
-            const button = document.getElementById('update-btn');
-            button.classList.add('loading');
-            button.disabled = true;
+		if user.TokenID != "" {
+			if scope := requiredScope(r); !user.hasScope(scope) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": false,
+					"error":   "This token lacks the " + scope + " scope",
+				})
+				return
+			}
+		} else if r.Method != "GET" && r.Method != "HEAD" && !sameOrigin(r) {
+			// Every handler that changes state requires POST or DELETE, so reads can skip this
+			http.Error(w, "Cross-origin request refused", http.StatusForbidden)
+			return
+		}
 
-            showNotification('Starting update... Page will reload in 20 seconds', 'success');
-            
-            try {
-                await fetch('/api/update', { method: 'POST' });
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userContextKey{}, user)))
+	})
+}
 
-                setTimeout(() => {
-                    location.reload();
-                }, 20000);
-            } catch (error) {
-                showNotification('Update failed: ' + error.message, 'error');
-                button.classList.remove('loading');
-                button.disabled = false;
-            }
-        }
+// Browsers send Origin on cross-site writes; requests without it (curl, scripts) pass
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
 
-        function showNotification(message, type) {
-            const notification = document.createElement('div');
-            notification.className = 'notification ' + type;
-            notification.textContent = message;
-            notification.setAttribute('role', 'alert');
-            document.body.appendChild(notification);
+// Only local redirects after logging in
+func safeNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
 
-            setTimeout(() => {
-                notification.remove();
-            }, 10000);
-        }
+// Login page (GET) and form (POST); creates the admin account while none exists
+func loginHandler(w http.ResponseWriter, r *http.Request) {
+	next := safeNext(r.FormValue("next"))
+	if _, ok := authenticate(r); ok {
+		http.Redirect(w, r, next, http.StatusSeeOther)
+		return
+	}
 
-        function showLoading() {
-            document.getElementById('loading-overlay').classList.add('show');
-        }
+	account, exists := loadAccount()
+	page := map[string]interface{}{
+		"Setup": !exists,
+		"Next":  next,
+	}
+	render := func(status int, message string) {
+		page["Message"] = message
+		page["Username"] = r.FormValue("username")
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		loginPageTemplate.Execute(w, page)
+	}
 
-        function hideLoading() {
-            document.getElementById('loading-overlay').classList.remove('show');
-        }
+	if r.Method != "POST" {
+		render(http.StatusOK, "")
+		return
+	}
+	if !loginLimiter.allow(clientIP(r)) {
+		render(http.StatusTooManyRequests, "Too many attempts, please try again later.")
+		return
+	}
 
-        // Load config on page load
-        loadConfig();
-        checkUpdates();
-    </script>
-</body>
-</html>`
+	username := strings.TrimSpace(r.FormValue("username"))
+	password := r.FormValue("password")
+	if !exists {
+		if !checkSetupCode(r.FormValue("setup_code")) {
+			log.Printf("⚠️  Wrong setup code from %s", clientIP(r))
+			render(http.StatusUnauthorized, "Wrong setup code. It is printed in the server log.")
+			return
+		}
+		if password != r.FormValue("confirm") {
+			render(http.StatusBadRequest, "The passwords don't match.")
+			return
+		}
+		if err := createAccount(username, password); err != nil {
+			if errors.Is(err, errAccountExists) {
+				page["Setup"] = false
+				render(http.StatusConflict, "An admin account was just created. Please sign in.")
+				return
+			}
+			render(http.StatusBadRequest, "Could not create the account: "+err.Error())
+			return
+		}
+		log.Printf("🔐 Admin account %s created from %s", username, clientIP(r))
+	} else if !checkPassword(account, username, password) {
+		log.Printf("⚠️  Failed login for %q from %s", username, clientIP(r))
+		render(http.StatusUnauthorized, "Wrong username or password.")
+		return
+	}
 
-	w.Header().Set("Content-Type", "text/html")
-	fmt.Fprint(w, html)
+	token, s, err := createSession(username)
+	if err != nil {
+		log.Printf("❌ Failed to create session: %v", err)
+		render(http.StatusInternalServerError, "Could not sign in, please try again.")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  s.ExpiresAt,
+		HttpOnly: true,
+		Secure:   requestIsHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	log.Printf("🔐 %s signed in from %s", username, clientIP(r))
+	http.Redirect(w, r, next, http.StatusSeeOther)
 }
 
-func getNextScheduledRun(day, timeStr string, loc *time.Location) string {
-	now := time.Now().In(loc)
+func logoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		deleteSession(cookie.Value)
+	}
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: "", Path: "/", MaxAge: -1, HttpOnly: true})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
 
-	// Parse schedule time
-	parts := strings.Split(timeStr, ":")
-	hour, minute := 9, 0
-	if len(parts) == 2 {
-		fmt.Sscanf(parts[0], "%d", &hour)
-		fmt.Sscanf(parts[1], "%d", &minute)
+// -set-password: replace the admin password (read from stdin), e.g. when locked out
+func setPasswordFromStdin(username string) error {
+	if username == "" {
+		account, exists := loadAccount()
+		username = account.Username
+		if !exists {
+			username = "admin"
+		}
+	}
+	fmt.Printf("New password for %s: ", username)
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		return err
 	}
+	if err := saveAccount(username, strings.TrimRight(password, "\r\n")); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Password for %s updated; all sessions were signed out\n", username)
+	return nil
+}
+
+// Admin view of subscribers (GET lists, POST adds/updates, DELETE ?email= removes)
+func subscribersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "POST" {
+		// Omitted fields keep their stored values (adding an existing address changes nothing)
+		var req struct {
+			Email        string    `json:"email"`
+			MaxRating    *string   `json:"max_rating"`
+			AllowUnrated *bool     `json:"allow_unrated"`
+			Groups       *[]string `json:"groups"`
+			Timezone     *string   `json:"timezone"`
+
+			RotatePreferences bool `json:"rotate_preferences"` // issue a new preferences link
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		email := strings.TrimSpace(req.Email)
+		if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+			http.Error(w, "invalid email address", http.StatusBadRequest)
+			return
+		}
+		if req.MaxRating != nil && *req.MaxRating != "" && ratingLevel(*req.MaxRating) == 0 {
+			http.Error(w, "unknown rating: "+*req.MaxRating, http.StatusBadRequest)
+			return
+		}
+		if req.Timezone != nil && *req.Timezone != "" {
+			if _, err := time.LoadLocation(*req.Timezone); err != nil {
+				http.Error(w, "unknown timezone: "+*req.Timezone, http.StatusBadRequest)
+				return
+			}
+		}
+
+		subscribersMu.Lock()
+		subs := loadSubscribersLocked()
+		idx := -1
+		for i := range subs {
+			if strings.EqualFold(subs[i].Email, email) {
+				idx = i
+				break
+			}
+		}
+
+		// Subscribers added by the admin skip the confirmation step
+		if idx < 0 {
+			now := time.Now()
+			subs = append(subs, Subscriber{Email: email, Status: "active", CreatedAt: now, ConfirmedAt: &now})
+			idx = len(subs) - 1
+		}
+		// Key for the subscriber's preferences page; rotating it voids links already sent
+		if subs[idx].PrefsToken == "" || req.RotatePreferences {
+			token, err := generateToken()
+			if err != nil {
+				subscribersMu.Unlock()
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			subs[idx].PrefsToken = token
+		}
+		if req.MaxRating != nil {
+			subs[idx].MaxRating = *req.MaxRating
+		}
+		if req.AllowUnrated != nil {
+			subs[idx].AllowUnrated = *req.AllowUnrated
+		}
+		if req.Groups != nil {
+			subs[idx].Groups = *req.Groups
+		}
+		if req.Timezone != nil {
+			subs[idx].Timezone = *req.Timezone
+		}
+		err := saveSubscribersLocked(subs)
+		subscribersMu.Unlock()
 
-	// Map day to weekday
-	dayMap := map[string]time.Weekday{
-		"Mon": time.Monday,
-		"Tue": time.Tuesday,
-		"Wed": time.Wednesday,
-		"Thu": time.Thursday,
-		"Fri": time.Friday,
-		"Sat": time.Saturday,
-		"Sun": time.Sunday,
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("👥 Subscriber saved: %s", email)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"success": true})
+		return
 	}
 
-	targetWeekday := dayMap[day]
-	daysUntil := int(targetWeekday - now.Weekday())
-	if daysUntil <= 0 {
-		daysUntil += 7
+	if r.Method == "DELETE" {
+		email := r.URL.Query().Get("email")
+
+		subscribersMu.Lock()
+		subs := loadSubscribersLocked()
+		kept := subs[:0]
+		for _, sub := range subs {
+			if !strings.EqualFold(sub.Email, email) {
+				kept = append(kept, sub)
+			}
+		}
+		err := saveSubscribersLocked(kept)
+		subscribersMu.Unlock()
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("🗑️  Subscriber removed: %s", email)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"success": true})
+		return
 	}
 
-	nextRun := now.AddDate(0, 0, daysUntil)
-	nextRun = time.Date(nextRun.Year(), nextRun.Month(), nextRun.Day(), hour, minute, 0, 0, loc)
+	type subscriberView struct {
+		Email        string     `json:"email"`
+		Status       string     `json:"status"`
+		InviteCode   string     `json:"invite_code,omitempty"`
+		CreatedAt    time.Time  `json:"created_at"`
+		ConfirmedAt  *time.Time `json:"confirmed_at,omitempty"`
+		MaxRating    string     `json:"max_rating"`
+		AllowUnrated bool       `json:"allow_unrated"`
+		Groups       []string   `json:"groups"`
+		Timezone     string     `json:"timezone"`
+		Preferences  string     `json:"preferences_url,omitempty"`
+	}
 
-	// If today is the day and time hasn't passed
-	if now.Weekday() == targetWeekday {
-		today := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, loc)
-		if now.Before(today) {
-			nextRun = today
+	user, _ := currentUser(r)
+	canManage := user.hasScope(ScopeAdmin)
+	views := []subscriberView{}
+	for _, sub := range loadSubscribers() {
+		view := subscriberView{
+			Email:        sub.Email,
+			Status:       sub.Status,
+			InviteCode:   sub.InviteCode,
+			CreatedAt:    sub.CreatedAt,
+			ConfirmedAt:  sub.ConfirmedAt,
+			MaxRating:    sub.MaxRating,
+			AllowUnrated: sub.AllowUnrated,
+			Groups:       sub.Groups,
+			Timezone:     sub.Timezone,
+		}
+		// The link lets anyone edit the subscriber's preferences, so read-only tokens don't get it
+		if sub.PrefsToken != "" && canManage {
+			view.Preferences = "/preferences?token=" + sub.PrefsToken
 		}
+		views = append(views, view)
 	}
 
-	return nextRun.Format("Monday, January 2, 2006 at 3:04 PM MST")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(views)
 }
 
-func timezoneInfoHandler(w http.ResponseWriter, r *http.Request) {
-	tz := r.URL.Query().Get("tz")
-	if tz == "" {
-		tz = "UTC"
+// Library entry offered on the preferences page
+type LibraryItem struct {
+	ID    int    `json:"id"` // TVDB ID for series, TMDB ID for movies
+	Title string `json:"title"`
+	Year  int    `json:"year,omitempty"`
+}
+
+func fetchLibrary(ctx context.Context, baseURL, apiKey, endpoint string) ([]LibraryItem, error) {
+	if baseURL == "" || apiKey == "" {
+		return nil, nil
 	}
 
-	loc := getTimezone(tz)
-	now := time.Now().In(loc)
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Api-Key", apiKey)
 
-	_, offset := now.Zone()
-	hours := offset / 3600
-	minutes := (offset % 3600) / 60
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
 
-	offsetStr := fmt.Sprintf("GMT%+d", hours)
-	if minutes != 0 {
-		offsetStr = fmt.Sprintf("GMT%+d:%02d", hours, minutes)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"current_time": now.Format("Monday, January 2, 2006 3:04 PM"),
-		"offset":       offsetStr,
+	var entries []struct {
+		Title  string `json:"title"`
+		Year   int    `json:"year"`
+		TvdbID int    `json:"tvdbId"`
+		TmdbID int    `json:"tmdbId"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, err
+	}
+
+	items := make([]LibraryItem, 0, len(entries))
+	for _, e := range entries {
+		id := e.TvdbID
+		if endpoint == "/api/v3/movie" {
+			id = e.TmdbID
+		}
+		if id != 0 {
+			items = append(items, LibraryItem{ID: id, Title: e.Title, Year: e.Year})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return strings.ToLower(items[i].Title) < strings.ToLower(items[j].Title)
 	})
+
+	return items, nil
 }
 
-// Preview handler for UI
-func previewHandler(w http.ResponseWriter, r *http.Request) {
-	cfg := getConfig()
-	loc := getTimezone(cfg.Timezone)
-	now := time.Now().In(loc)
+// Subscriber self-service page for following/muting titles (keyed by subscriber token)
+const preferencesPageHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Newslettar Preferences</title>
+    <style>
+        * { margin: 0; padding: 0; box-sizing: border-box; }
+        body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; background: #0f1419; color: #e8e8e8; line-height: 1.6; }
+        .container { max-width: 800px; margin: 40px auto; padding: 20px; }
+        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); padding: 30px; border-radius: 12px 12px 0 0; text-align: center; }
+        .card { background: #1a2332; padding: 30px; border-radius: 0 0 12px 12px; }
+        h3 { color: #667eea; margin: 20px 0 10px; }
+        input[type=search] { width: 100%; padding: 12px; margin-bottom: 15px; background: #0f1419; border: 2px solid #2a3444; border-radius: 8px; color: #e8e8e8; font-size: 14px; }
+        .list { max-height: 400px; overflow-y: auto; border: 2px solid #2a3444; border-radius: 8px; }
+        .item { display: flex; justify-content: space-between; align-items: center; padding: 8px 12px; border-bottom: 1px solid #2a3444; }
+        #timezone { width: 100%; padding: 12px; background: #0f1419; border: 2px solid #2a3444; border-radius: 8px; color: #e8e8e8; font-size: 14px; }
+        .item select { padding: 6px; background: #0f1419; border: 2px solid #2a3444; border-radius: 6px; color: #e8e8e8; }
+        .btn { width: 100%; margin-top: 20px; padding: 12px 24px; background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; border: none; border-radius: 8px; cursor: pointer; font-size: 14px; font-weight: 600; }
+        .message { margin-top: 15px; color: #a0b0c0; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header"><h1>📺 Your Preferences</h1><p id="email"></p></div>
+        <div class="card">
+            <h3>Timezone</h3>
+            <p style="color: #a0b0c0;">Air times in your newsletter are shown in this timezone.</p>
+            <select id="timezone" aria-label="Timezone"></select>
+            <p style="color: #a0b0c0; margin-top: 20px;">Followed titles appear at the top of your newsletter. Muted titles are never shown.</p>
+            <input type="search" id="filter" placeholder="Search titles..." aria-label="Search titles">
+            <h3>TV Shows</h3>
+            <div class="list" id="series"></div>
+            <h3>Movies</h3>
+            <div class="list" id="movies"></div>
+            <button class="btn" id="save">Save Preferences</button>
+            <p class="message" id="message" role="alert"></p>
+        </div>
+    </div>
+    <script>
+        const token = new URLSearchParams(location.search).get('token');
+        let prefs;
 
-	weekStart := now.AddDate(0, 0, -7)
-	weekEnd := now
+        function stateOf(kind, id) {
+            if ((prefs['followed_' + kind] || []).includes(id)) return 'follow';
+            if ((prefs['muted_' + kind] || []).includes(id)) return 'mute';
+            return '';
+        }
 
-	// Parallel API calls with context
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+        function render() {
+            const q = document.getElementById('filter').value.toLowerCase();
+            [['series', prefs.series], ['movies', prefs.movies]].forEach(([kind, items]) => {
+                const list = document.getElementById(kind);
+                list.innerHTML = '';
+                items.filter(i => i.title.toLowerCase().includes(q)).forEach(item => {
+                    const row = document.createElement('div');
+                    row.className = 'item';
+                    const title = document.createElement('span');
+                    title.textContent = item.title + (item.year ? ' (' + item.year + ')' : '');
+                    const select = document.createElement('select');
+                    select.setAttribute('aria-label', 'Preference for ' + item.title);
+                    [['', 'Default'], ['follow', '⭐ Follow'], ['mute', '🔇 Mute']].forEach(([value, label]) => {
+                        const opt = document.createElement('option');
+                        opt.value = value;
+                        opt.textContent = label;
+                        select.appendChild(opt);
+                    });
+                    select.value = stateOf(kind, item.id);
+                    select.addEventListener('change', () => {
+                        prefs['followed_' + kind] = (prefs['followed_' + kind] || []).filter(id => id !== item.id);
+                        prefs['muted_' + kind] = (prefs['muted_' + kind] || []).filter(id => id !== item.id);
+                        if (select.value === 'follow') prefs['followed_' + kind].push(item.id);
+                        if (select.value === 'mute') prefs['muted_' + kind].push(item.id);
+                    });
+                    row.appendChild(title);
+                    row.appendChild(select);
+                    list.appendChild(row);
+                });
+            });
+        }
 
-	var wg sync.WaitGroup
-	var downloadedEpisodes, upcomingEpisodes []Episode
-	var downloadedMovies, upcomingMovies []Movie
+        async function load() {
+            const resp = await fetch('/api/preferences?token=' + encodeURIComponent(token));
+            if (!resp.ok) {
+                document.querySelector('.card').textContent = 'This preferences link is not valid.';
+                return;
+            }
+            prefs = await resp.json();
+            document.getElementById('email').textContent = prefs.email;
+
+            const tz = document.getElementById('timezone');
+            const zones = Intl.supportedValuesOf ? Intl.supportedValuesOf('timeZone') : [];
+            const browserZone = Intl.DateTimeFormat().resolvedOptions().timeZone;
+            [['', 'Newsletter default (' + prefs.server_timezone + ')']].concat(zones.map(z => [z, z])).forEach(([value, label]) => {
+                const opt = document.createElement('option');
+                opt.value = value;
+                opt.textContent = label + (value && value === browserZone ? ' (this device)' : '');
+                tz.appendChild(opt);
+            });
+            if (prefs.timezone && !zones.includes(prefs.timezone)) {
+                const opt = document.createElement('option');
+                opt.value = opt.textContent = prefs.timezone;
+                tz.appendChild(opt);
+            }
+            tz.value = prefs.timezone || '';
+            render();
+        }
 
-	wg.Add(4)
+        document.getElementById('filter').addEventListener('input', render);
+        document.getElementById('save').addEventListener('click', async () => {
+            const resp = await fetch('/api/preferences?token=' + encodeURIComponent(token), {
+                method: 'POST',
+                headers: {'Content-Type': 'application/json'},
+                body: JSON.stringify({
+                    followed_series: prefs.followed_series || [],
+                    followed_movies: prefs.followed_movies || [],
+                    muted_series: prefs.muted_series || [],
+                    muted_movies: prefs.muted_movies || [],
+                    timezone: document.getElementById('timezone').value
+                })
+            });
+            document.getElementById('message').textContent = resp.ok ? 'Preferences saved!' : 'Failed to save preferences';
+        });
 
-	go func() {
-		defer wg.Done()
-		downloadedEpisodes, _ = fetchSonarrHistoryWithRetry(ctx, cfg, weekStart, 2)
-	}()
+        load();
+    </script>
+</body>
+</html>`
 
-	go func() {
-		defer wg.Done()
-		upcomingEpisodes, _ = fetchSonarrCalendarWithRetry(ctx, cfg, weekEnd, weekEnd.AddDate(0, 0, 7), 2)
-	}()
+func preferencesPageHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, preferencesPageHTML)
+}
 
-	go func() {
-		defer wg.Done()
-		downloadedMovies, _ = fetchRadarrHistoryWithRetry(ctx, cfg, weekStart, 2)
-	}()
+// Subscriber preferences API (GET returns preferences plus the library, POST saves)
+func preferencesHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+
+	subscribersMu.Lock()
+	subs := loadSubscribersLocked()
+	idx := -1
+	for i := range subs {
+		if token != "" && subs[i].PrefsToken == token && subs[i].Status == "active" {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		subscribersMu.Unlock()
+		http.Error(w, "invalid token", http.StatusNotFound)
+		return
+	}
 
-	go func() {
-		defer wg.Done()
-		upcomingMovies, _ = fetchRadarrCalendarWithRetry(ctx, cfg, weekEnd, weekEnd.AddDate(0, 0, 7), 2)
-	}()
+	if r.Method == "POST" {
+		var req struct {
+			FollowedSeries []int   `json:"followed_series"`
+			FollowedMovies []int   `json:"followed_movies"`
+			MutedSeries    []int   `json:"muted_series"`
+			MutedMovies    []int   `json:"muted_movies"`
+			Timezone       *string `json:"timezone"` // omitted = unchanged
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			subscribersMu.Unlock()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Timezone != nil && *req.Timezone != "" {
+			if _, err := time.LoadLocation(*req.Timezone); err != nil {
+				subscribersMu.Unlock()
+				http.Error(w, "unknown timezone: "+*req.Timezone, http.StatusBadRequest)
+				return
+			}
+		}
+
+		subs[idx].FollowedSeries = req.FollowedSeries
+		subs[idx].FollowedMovies = req.FollowedMovies
+		subs[idx].MutedSeries = req.MutedSeries
+		subs[idx].MutedMovies = req.MutedMovies
+		if req.Timezone != nil {
+			subs[idx].Timezone = *req.Timezone
+		}
+		err := saveSubscribersLocked(subs)
+		email := subs[idx].Email
+		subscribersMu.Unlock()
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("⭐ Preferences updated for %s", email)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"success": true})
+		return
+	}
 
-	wg.Wait()
+	sub := subs[idx]
+	subscribersMu.Unlock()
 
-	// Sort movies chronologically
-	sort.Slice(upcomingMovies, func(i, j int) bool {
-		return upcomingMovies[i].ReleaseDate < upcomingMovies[j].ReleaseDate
-	})
-	sort.Slice(downloadedMovies, func(i, j int) bool {
-		return downloadedMovies[i].ReleaseDate < downloadedMovies[j].ReleaseDate
-	})
+	cfg := getConfig()
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
 
-	data := NewsletterData{
-		WeekStart:              weekStart.Format("January 2, 2006"),
-		WeekEnd:                weekEnd.Format("January 2, 2006"),
-		UpcomingSeriesGroups:   groupEpisodesBySeries(upcomingEpisodes),
-		UpcomingMovies:         upcomingMovies,
-		DownloadedSeriesGroups: groupEpisodesBySeries(downloadedEpisodes),
-		DownloadedMovies:       downloadedMovies,
+	series, err := fetchLibrary(ctx, cfg.SonarrURL, cfg.SonarrAPIKey, "/api/v3/series")
+	if err != nil {
+		log.Printf("⚠️  Failed to fetch Sonarr series: %v", err)
 	}
-
-	html, err := generateNewsletterHTML(data, cfg.ShowPosters, cfg.ShowDownloaded)
+	movies, err := fetchLibrary(ctx, cfg.RadarrURL, cfg.RadarrAPIKey, "/api/v3/movie")
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   fmt.Sprintf("Failed to generate preview: %v", err),
-		})
-		return
+		log.Printf("⚠️  Failed to fetch Radarr movies: %v", err)
+	}
+	if series == nil {
+		series = []LibraryItem{}
+	}
+	if movies == nil {
+		movies = []LibraryItem{}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"html":    html,
+		"email":           sub.Email,
+		"followed_series": sub.FollowedSeries,
+		"followed_movies": sub.FollowedMovies,
+		"muted_series":    sub.MutedSeries,
+		"muted_movies":    sub.MutedMovies,
+		"timezone":        sub.Timezone,
+		"server_timezone": cfg.Timezone,
+		"series":          series,
+		"movies":          movies,
 	})
 }
 
-func configHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method == "POST" {
-		var webCfg WebConfig
-		if err := json.NewDecoder(r.Body).Decode(&webCfg); err != nil {
+// Newsletter profiles (GET lists, POST creates/updates one, DELETE ?id= removes)
+func profilesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		var p Profile
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		envMap := readEnvFile()
-
-		// Only update fields that were provided
-		if webCfg.SonarrURL != "" {
-			envMap["SONARR_URL"] = webCfg.SonarrURL
-		}
-		if webCfg.SonarrAPIKey != "" {
-			envMap["SONARR_API_KEY"] = webCfg.SonarrAPIKey
-		}
-		if webCfg.RadarrURL != "" {
-			envMap["RADARR_URL"] = webCfg.RadarrURL
-		}
-		if webCfg.RadarrAPIKey != "" {
-			envMap["RADARR_API_KEY"] = webCfg.RadarrAPIKey
-		}
-		if webCfg.MailgunSMTP != "" {
-			envMap["MAILGUN_SMTP"] = webCfg.MailgunSMTP
-		}
-		if webCfg.MailgunPort != "" {
-			envMap["MAILGUN_PORT"] = webCfg.MailgunPort
+		p.Name = strings.TrimSpace(p.Name)
+		if p.Name == "" {
+			http.Error(w, "profile name is required", http.StatusBadRequest)
+			return
 		}
-		if webCfg.MailgunUser != "" {
-			envMap["MAILGUN_USER"] = webCfg.MailgunUser
+		for _, value := range []string{p.Lookback, p.Lookahead} {
+			if value == "" {
+				continue
+			}
+			if _, err := parseWindowDuration(value); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 		}
-		if webCfg.MailgunPass != "" {
-			envMap["MAILGUN_PASS"] = webCfg.MailgunPass
+		for _, t := range p.ReleaseTypes {
+			if !containsString(allReleaseTypes, t) {
+				http.Error(w, "unknown release type: "+t, http.StatusBadRequest)
+				return
+			}
 		}
-		if webCfg.FromEmail != "" {
-			envMap["FROM_EMAIL"] = webCfg.FromEmail
+		schedules := []string{}
+		var parsed []cron.Schedule
+		for _, spec := range p.Schedules {
+			spec = strings.TrimSpace(spec)
+			if spec == "" {
+				continue
+			}
+			sched, err := parseSchedule(spec)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid schedule %q: %v", spec, err), http.StatusBadRequest)
+				return
+			}
+			schedules = append(schedules, spec)
+			parsed = append(parsed, sched)
 		}
-		if webCfg.FromName != "" {
-			envMap["FROM_NAME"] = webCfg.FromName
+		// Each schedule passed on its own; together they mustn't send more often either
+		if err := checkScheduleInterval(getTimezone(getConfig().Timezone), parsed...); err != nil {
+			http.Error(w, "the schedules together "+err.Error(), http.StatusBadRequest)
+			return
 		}
-		if webCfg.ToEmails != "" {
-			envMap["TO_EMAILS"] = webCfg.ToEmails
+		p.Schedules = schedules
+		for _, section := range p.Sections {
+			known := false
+			for _, s := range allSections {
+				known = known || s == section
+			}
+			if !known {
+				http.Error(w, "unknown section: "+section, http.StatusBadRequest)
+				return
+			}
 		}
-		if webCfg.Timezone != "" {
-			envMap["TIMEZONE"] = webCfg.Timezone
+		if p.Template == "" {
+			p.Template = "email.html"
 		}
-		if webCfg.ScheduleDay != "" {
-			envMap["SCHEDULE_DAY"] = webCfg.ScheduleDay
+		if _, err := getEmailTemplate(p.Template); err != nil {
+			http.Error(w, "invalid template: "+err.Error(), http.StatusBadRequest)
+			return
 		}
-		if webCfg.ScheduleTime != "" {
-			envMap["SCHEDULE_TIME"] = webCfg.ScheduleTime
+		for _, email := range p.Recipients {
+			if _, err := mail.ParseAddress(email); err != nil {
+				http.Error(w, "invalid recipient: "+email, http.StatusBadRequest)
+				return
+			}
 		}
-		if webCfg.ShowPosters != "" {
-			envMap["SHOW_POSTERS"] = webCfg.ShowPosters
+
+		profilesMu.Lock()
+		profiles := loadProfilesLocked()
+		if p.ID == "" {
+			token, err := generateToken()
+			if err != nil {
+				profilesMu.Unlock()
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			p.ID = token[:8]
+			if p.Transport.Pass == secretMask {
+				p.Transport.Pass = ""
+			}
+			profiles = append(profiles, p)
+		} else {
+			found := false
+			for i := range profiles {
+				if profiles[i].ID == p.ID {
+					// The SMTP password is write-only: the mask keeps the saved one
+					if p.Transport.Pass == secretMask {
+						p.Transport.Pass = profiles[i].Transport.Pass
+					}
+					profiles[i] = p
+					found = true
+					break
+				}
+			}
+			if !found {
+				profilesMu.Unlock()
+				http.Error(w, "unknown profile: "+p.ID, http.StatusNotFound)
+				return
+			}
 		}
-		if webCfg.ShowDownloaded != "" {
-			envMap["SHOW_DOWNLOADED"] = webCfg.ShowDownloaded
+		err := saveProfilesLocked(profiles)
+		profilesMu.Unlock()
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
 
-		var envContent strings.Builder
-		for key, value := range envMap {
-			envContent.WriteString(fmt.Sprintf("%s=%s\n", key, value))
+		log.Printf("📰 Newsletter profile saved: %s", p.Name)
+		restartScheduler()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "id": p.ID})
+		return
+
+	case "DELETE":
+		id := r.URL.Query().Get("id")
+
+		profilesMu.Lock()
+		profiles := loadProfilesLocked()
+		kept := profiles[:0]
+		for _, p := range profiles {
+			if p.ID != id {
+				kept = append(kept, p)
+			}
 		}
+		err := saveProfilesLocked(kept)
+		profilesMu.Unlock()
 
-		if err := os.WriteFile(".env", []byte(envContent.String()), 0644); err != nil {
+		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		// Reload config and restart scheduler
-		reloadConfig()
+		log.Printf("🗑️  Newsletter profile removed: %s", id)
 		restartScheduler()
 
 		w.Header().Set("Content-Type", "application/json")
@@ -2326,184 +9847,175 @@ func configHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// GET request - return current config
-	envMap := readEnvFile()
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"sonarr_url":      getEnvFromFile(envMap, "SONARR_URL", ""),
-		"sonarr_api_key":  getEnvFromFile(envMap, "SONARR_API_KEY", ""),
-		"radarr_url":      getEnvFromFile(envMap, "RADARR_URL", ""),
-		"radarr_api_key":  getEnvFromFile(envMap, "RADARR_API_KEY", ""),
-		"mailgun_smtp":    getEnvFromFile(envMap, "MAILGUN_SMTP", "smtp.mailgun.org"),
-		"mailgun_port":    getEnvFromFile(envMap, "MAILGUN_PORT", "587"),
-		"mailgun_user":    getEnvFromFile(envMap, "MAILGUN_USER", ""),
-		"mailgun_pass":    getEnvFromFile(envMap, "MAILGUN_PASS", ""),
-		"from_email":      getEnvFromFile(envMap, "FROM_EMAIL", ""),
-		"from_name":       getEnvFromFile(envMap, "FROM_NAME", "Newslettar"),
-		"to_emails":       getEnvFromFile(envMap, "TO_EMAILS", ""),
-		"timezone":        getEnvFromFile(envMap, "TIMEZONE", "UTC"),
-		"schedule_day":    getEnvFromFile(envMap, "SCHEDULE_DAY", "Sun"),
-		"schedule_time":   getEnvFromFile(envMap, "SCHEDULE_TIME", "09:00"),
-		"show_posters":    getEnvFromFile(envMap, "SHOW_POSTERS", "true"),
-		"show_downloaded": getEnvFromFile(envMap, "SHOW_DOWNLOADED", "true"),
-	})
-}
+	cfg := getConfig()
+	loc := getTimezone(cfg.Timezone)
 
-func testSonarrHandler(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		URL    string `json:"url"`
-		APIKey string `json:"api_key"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+	type profileView struct {
+		Profile
+		NextRun      string   `json:"next_run"`
+		NextRuns     []string `json:"next_runs"`
+		Descriptions []string `json:"schedule_descriptions"`
+		WindowStart  string   `json:"window_start"`
 	}
 
-	success := false
-	message := "Missing URL or API key"
-
-	if req.URL != "" && req.APIKey != "" {
-		httpReq, err := http.NewRequest("GET", req.URL+"/api/v3/system/status", nil)
-		if err == nil {
-			httpReq.Header.Set("X-Api-Key", req.APIKey)
-			resp, err := httpClient.Do(httpReq)
-			if err != nil {
-				message = fmt.Sprintf("Connection failed: %v", err)
-			} else if resp.StatusCode == 200 {
-				success = true
-				message = "Sonarr connection successful!"
-				resp.Body.Close()
-			} else {
-				message = fmt.Sprintf("Connection failed: HTTP %d", resp.StatusCode)
-				resp.Body.Close()
+	views := []profileView{}
+	for _, p := range loadProfiles() {
+		view := profileView{Profile: p, NextRun: "Disabled", NextRuns: []string{}, Descriptions: []string{}}
+		if view.Transport.Pass != "" {
+			view.Transport.Pass = secretMask
+		}
+		start, _ := newsletterWindow(p, time.Now().In(loc))
+		view.WindowStart = start.Format("Monday, January 2, 2006 at 3:04 PM MST")
+		for _, spec := range p.Schedules {
+			view.Descriptions = append(view.Descriptions, describeSchedule(spec))
+		}
+		if p.Enabled {
+			view.NextRun = "Not scheduled"
+			for _, t := range nextRunTimes(p.Schedules, loc, 5) {
+				view.NextRuns = append(view.NextRuns, t.Format("Monday, January 2, 2006 at 3:04 PM MST"))
+			}
+			if len(view.NextRuns) > 0 {
+				view.NextRun = view.NextRuns[0]
 			}
 		}
+		views = append(views, view)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": success,
-		"message": message,
+		"profiles":  views,
+		"templates": listEmailTemplates(),
+		"presets":   schedulePresets,
 	})
 }
 
-func testRadarrHandler(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		URL    string `json:"url"`
-		APIKey string `json:"api_key"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+// Run history list (?profile=<id> to filter, ?limit=n)
+func runsHandler(w http.ResponseWriter, r *http.Request) {
+	profile := r.URL.Query().Get("profile")
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 50
 	}
 
-	success := false
-	message := "Missing URL or API key"
-
-	if req.URL != "" && req.APIKey != "" {
-		httpReq, err := http.NewRequest("GET", req.URL+"/api/v3/system/status", nil)
-		if err == nil {
-			httpReq.Header.Set("X-Api-Key", req.APIKey)
-			resp, err := httpClient.Do(httpReq)
-			if err != nil {
-				message = fmt.Sprintf("Connection failed: %v", err)
-			} else if resp.StatusCode == 200 {
-				success = true
-				message = "Radarr connection successful!"
-				resp.Body.Close()
-			} else {
-				message = fmt.Sprintf("Connection failed: HTTP %d", resp.StatusCode)
-				resp.Body.Close()
-			}
+	runs := []Run{}
+	for _, run := range loadRuns() {
+		if profile != "" && run.ProfileID != profile {
+			continue
+		}
+		runs = append(runs, run)
+		if len(runs) == limit {
+			break
 		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": success,
-		"message": message,
-	})
+	json.NewEncoder(w).Encode(map[string]interface{}{"runs": runs})
 }
 
-func testEmailHandler(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		SMTP string `json:"smtp"`
-		Port string `json:"port"`
-		User string `json:"user"`
-		Pass string `json:"pass"`
+// Single run (/api/runs/{id}) or the email it sent (/api/runs/{id}/html[?delivery=n])
+func runHandler(w http.ResponseWriter, r *http.Request) {
+	run, ok := findRun(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "run not found", http.StatusNotFound)
+		return
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+
+	if !strings.HasSuffix(r.URL.Path, "/html") {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(run)
 		return
 	}
 
-	success := false
-	message := "SMTP credentials missing"
+	delivery := -1
+	if value := r.URL.Query().Get("delivery"); value != "" {
+		i, err := strconv.Atoi(value)
+		if err != nil || i < 0 || i >= len(run.Deliveries) {
+			http.Error(w, "unknown delivery", http.StatusNotFound)
+			return
+		}
+		if run.Deliveries[i].CustomHTML {
+			delivery = i
+		}
+	}
 
-	if req.User != "" && req.Pass != "" {
-		addr := fmt.Sprintf("%s:%s", req.SMTP, req.Port)
+	html, err := os.ReadFile(runHTMLPath(run.ID, delivery))
+	if err != nil {
+		http.Error(w, "no stored email for this run", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(html)
+}
 
-		client, err := smtp.Dial(addr)
-		if err != nil {
-			message = fmt.Sprintf("Connection failed: %v", err)
-		} else {
-			defer client.Close()
+// Recipient groups (GET returns groups and known tag labels, POST replaces the list)
+func groupsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "POST" {
+		var groups []Group
+		if err := json.NewDecoder(r.Body).Decode(&groups); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-			if err = client.Hello("localhost"); err != nil {
-				message = fmt.Sprintf("EHLO failed: %v", err)
-			} else if ok, _ := client.Extension("STARTTLS"); ok {
-				config := &tls.Config{ServerName: req.SMTP}
-				if err = client.StartTLS(config); err != nil {
-					message = fmt.Sprintf("STARTTLS failed: %v", err)
-				} else {
-					auth := smtp.PlainAuth("", req.User, req.Pass, req.SMTP)
-					if err = client.Auth(auth); err != nil {
-						message = fmt.Sprintf("Authentication failed: %v", err)
-					} else {
-						success = true
-						message = "SMTP authentication successful (with STARTTLS)"
-					}
-				}
-			} else {
-				auth := smtp.PlainAuth("", req.User, req.Pass, req.SMTP)
-				if err = client.Auth(auth); err != nil {
-					message = fmt.Sprintf("Authentication failed: %v", err)
-				} else {
-					success = true
-					message = "SMTP authentication successful"
-				}
+		seen := make(map[string]bool)
+		for i := range groups {
+			groups[i].Name = strings.TrimSpace(groups[i].Name)
+			name := strings.ToLower(groups[i].Name)
+			if name == "" || seen[name] {
+				http.Error(w, "group names must be unique and non-empty", http.StatusBadRequest)
+				return
+			}
+			seen[name] = true
+			if groups[i].MaxRating != "" && ratingLevel(groups[i].MaxRating) == 0 {
+				http.Error(w, "unknown rating: "+groups[i].MaxRating, http.StatusBadRequest)
+				return
 			}
 		}
+
+		if err := saveGroups(groups); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("👥 Saved %d recipient group(s)", len(groups))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"success": true})
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": success,
-		"message": message,
-	})
-}
+	cfg := getConfig()
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
 
-func sendHandler(w http.ResponseWriter, r *http.Request) {
-	// Send immediately with MANUAL_RUN flag
-	go runNewsletter()
+	// Offer every tag known to Sonarr and Radarr so admins can pick labels
+	tagSet := make(map[string]bool)
+	if cfg.SonarrURL != "" && cfg.SonarrAPIKey != "" {
+		for _, label := range fetchTagMap(ctx, cfg.SonarrURL, cfg.SonarrAPIKey) {
+			tagSet[label] = true
+		}
+	}
+	if cfg.RadarrURL != "" && cfg.RadarrAPIKey != "" {
+		for _, label := range fetchTagMap(ctx, cfg.RadarrURL, cfg.RadarrAPIKey) {
+			tagSet[label] = true
+		}
+	}
+	tags := make([]string, 0, len(tagSet))
+	for label := range tagSet {
+		tags = append(tags, label)
+	}
+	sort.Strings(tags)
+
+	groups := loadGroups()
+	if groups == nil {
+		groups = []Group{}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"message": "Newsletter generation started",
+		"groups":         groups,
+		"available_tags": tags,
 	})
 }
 
-func logsHandler(w http.ResponseWriter, r *http.Request) {
-	logBufferMu.Lock()
-	defer logBufferMu.Unlock()
-
-	w.Header().Set("Content-Type", "text/plain")
-	for _, line := range logBuffer {
-		fmt.Fprint(w, line)
-	}
-}
-
 func isNewerVersion(remote, current string) bool {
 	remote = strings.TrimPrefix(remote, "v")
 	current = strings.TrimPrefix(current, "v")
@@ -2582,6 +10094,11 @@ func versionHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func updateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
@@ -2604,10 +10121,20 @@ func updateHandler(w http.ResponseWriter, r *http.Request) {
 			wget -O go.mod https://raw.githubusercontent.com/agencefanfare/lerefuge/main/newslettar/go.mod
 			echo "Downloading version.json..."
 			wget -O version.json https://raw.githubusercontent.com/agencefanfare/lerefuge/main/newslettar/version.json
+			echo "Downloading email template..."
+			mkdir -p templates
+			wget -O templates/email.html https://raw.githubusercontent.com/agencefanfare/lerefuge/main/newslettar/templates/email.html
+			echo "Fetching dependencies..."
+			/usr/local/go/bin/go mod tidy
 			echo "Building with optimization flags..."
 			/usr/local/go/bin/go build -ldflags="-s -w" -trimpath -o newslettar main.go
 			echo "Restoring .env..."
 			mv .env.backup .env
+			if grep -q '^EnvironmentFile=/opt/newslettar/.env' /etc/systemd/system/newslettar.service; then
+				echo "Letting Newslettar read .env itself..."
+				sed -i '\|^EnvironmentFile=/opt/newslettar/.env|d' /etc/systemd/system/newslettar.service
+				systemctl daemon-reload
+			fi
 			echo "Restarting service..."
 			systemctl restart newslettar.service
 			echo "Update complete!"
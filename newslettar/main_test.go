@@ -0,0 +1,1250 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/robfig/cron/v3"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestCheckPassword(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account := AdminAccount{Username: "admin", PasswordHash: string(hash)}
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		want     bool
+	}{
+		{"correct", "admin", "correct horse", true},
+		{"wrong password", "admin", "correct horse!", false},
+		{"wrong username", "root", "correct horse", false},
+		{"username case", "Admin", "correct horse", false},
+		{"empty password", "admin", "", false},
+		{"empty both", "", "", false},
+	}
+	for _, tt := range tests {
+		if got := checkPassword(account, tt.username, tt.password); got != tt.want {
+			t.Errorf("%s: checkPassword(%q, %q) = %v, want %v", tt.name, tt.username, tt.password, got, tt.want)
+		}
+	}
+
+	if checkPassword(AdminAccount{}, "", "") {
+		t.Error("checkPassword accepted an empty account")
+	}
+}
+
+func TestSafeNext(t *testing.T) {
+	tests := []struct {
+		next string
+		want string
+	}{
+		{"", "/"},
+		{"/", "/"},
+		{"/history?profile=weekly", "/history?profile=weekly"},
+		{"//evil.example.com", "/"},
+		{"/\\evil.example.com", "/"},
+		{"https://evil.example.com/", "/"},
+		{"javascript:alert(1)", "/"},
+		{"history", "/"},
+	}
+	for _, tt := range tests {
+		if got := safeNext(tt.next); got != tt.want {
+			t.Errorf("safeNext(%q) = %q, want %q", tt.next, got, tt.want)
+		}
+	}
+}
+
+func TestIsTrustedProxy(t *testing.T) {
+	cfg := &Config{TrustedProxies: []string{"10.0.0.0/8", "192.168.1.5", "fd00::/8", "not-an-ip"}}
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"10.1.2.3", true},
+		{"11.0.0.1", false},
+		{"192.168.1.5", true},
+		{"192.168.1.6", false},
+		{"fd12::1", true},
+		{"fe80::1", false},
+		{"::ffff:10.0.0.1", true},
+		{"not-an-ip", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isTrustedProxy(cfg, tt.ip); got != tt.want {
+			t.Errorf("isTrustedProxy(%q) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+
+	if isTrustedProxy(&Config{}, "127.0.0.1") {
+		t.Error("no proxy should be trusted without TRUSTED_PROXIES")
+	}
+}
+
+func TestRequiredScope(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{"GET", "/api/status", ScopeRead},
+		{"HEAD", "/api/history", ScopeRead},
+		{"GET", "/api/config", ScopeAdmin},
+		{"POST", "/api/config", ScopeAdmin},
+		{"GET", "/api/tokens", ScopeAdmin},
+		{"DELETE", "/api/tokens/abc", ScopeAdmin},
+		{"POST", "/api/update", ScopeAdmin},
+		{"GET", "/api/update", ScopeAdmin},
+		{"POST", "/api/send", ScopeSend},
+		{"GET", "/api/send", ScopeSend},
+		{"POST", "/api/jobs/123/cancel", ScopeSend},
+		{"GET", "/api/jobs/123", ScopeRead},
+		{"POST", "/api/subscribers", ScopeAdmin},
+		{"DELETE", "/api/subscribers", ScopeAdmin},
+		{"POST", "/api/test-sonarr", ScopeAdmin},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+		if got := requiredScope(r); got != tt.want {
+			t.Errorf("requiredScope(%s %s) = %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []string
+		scope  string
+		want   bool
+	}{
+		{"signed-in user", nil, ScopeAdmin, true},
+		{"read token reads", []string{ScopeRead}, ScopeRead, true},
+		{"read token sends", []string{ScopeRead}, ScopeSend, false},
+		{"read token administers", []string{ScopeRead}, ScopeAdmin, false},
+		{"send token sends", []string{ScopeSend}, ScopeSend, true},
+		{"send token reads", []string{ScopeSend}, ScopeRead, false},
+		{"admin token reads", []string{ScopeAdmin}, ScopeRead, true},
+		{"admin token sends", []string{ScopeAdmin}, ScopeSend, true},
+		{"token without scopes", []string{}, ScopeRead, false},
+	}
+	for _, tt := range tests {
+		user := authUser{Name: "token:test", Scopes: tt.scopes}
+		if got := user.hasScope(tt.scope); got != tt.want {
+			t.Errorf("%s: hasScope(%q) = %v, want %v", tt.name, tt.scope, got, tt.want)
+		}
+	}
+}
+
+func TestSecretsRoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	secrets := map[string]string{"SONARR_API_KEY": "abc123", "PROFILE_weekly_SMTP_PASS": "p@ss=word\n"}
+
+	data, err := encryptSecrets(key, secrets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("abc123")) {
+		t.Fatal("store contains a plaintext secret")
+	}
+	got, err := decryptSecrets(key, data)
+	if err != nil {
+		t.Fatalf("decryptSecrets with the right key: %v", err)
+	}
+	if len(got) != len(secrets) {
+		t.Fatalf("decrypted %d secrets, want %d", len(got), len(secrets))
+	}
+	for name, value := range secrets {
+		if got[name] != value {
+			t.Errorf("%s = %q, want %q", name, got[name], value)
+		}
+	}
+
+	again, err := encryptSecrets(key, secrets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(data, again) {
+		t.Error("encrypting twice gave the same output; nonces must not repeat")
+	}
+
+	var tampered secretsEnvelope
+	if err := json.Unmarshal(data, &tampered); err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, _ := base64.StdEncoding.DecodeString(tampered.Data)
+	ciphertext[0] ^= 0xff
+	tampered.Data = base64.StdEncoding.EncodeToString(ciphertext)
+	tamperedData, _ := json.Marshal(tampered)
+
+	var future secretsEnvelope
+	json.Unmarshal(data, &future)
+	future.Version = 2
+	futureData, _ := json.Marshal(future)
+
+	tests := []struct {
+		name string
+		key  []byte
+		data []byte
+	}{
+		{"wrong key", bytes.Repeat([]byte{2}, 32), data},
+		{"short key", key[:10], data},
+		{"tampered ciphertext", key, tamperedData},
+		{"unknown version", key, futureData},
+		{"not JSON", key, []byte("SONARR_API_KEY=abc123")},
+	}
+	for _, tt := range tests {
+		if got, err := decryptSecrets(tt.key, tt.data); err == nil {
+			t.Errorf("%s: decryptSecrets succeeded with %v", tt.name, got)
+		}
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		spec  string
+		valid bool
+	}{
+		{"0 9 * * 0", true},
+		{"30 7 * * 1-5", true},
+		{"0 9 * * 1#1", true},
+		{"0 9 * * MON#2", true},
+		{"@daily", true},
+		{"@hourly", true},
+		{"@every 1h", true},
+		{"@every 36h", true},
+		{"CRON_TZ=Europe/Paris 0 9 * * 0", true},
+		{"", false},
+		{"   ", false},
+		{"not a schedule", false},
+		{"0 9 * *", false},
+		{"0 25 * * *", false},
+		{"0 9 * * 1#6", false},
+		{"0 9 * * 1#x", false},
+		{"0 9 1 * 1#1", false},
+		// More often than once an hour
+		{"@every 1m", false},
+		{"@every 59m", false},
+		{"* * * * *", false},
+		{"*/30 * * * *", false},
+		{"0,5 9 * * *", false},
+		{"*/5 9 * * 1#1", false},
+	}
+	for _, tt := range tests {
+		_, err := parseSchedule(tt.spec)
+		if (err == nil) != tt.valid {
+			t.Errorf("parseSchedule(%q) error = %v, want valid %v", tt.spec, err, tt.valid)
+		}
+	}
+}
+
+func TestParseScheduleNthWeekday(t *testing.T) {
+	sched, err := parseSchedule("0 9 * * 1#1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := sched.Next(time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC))
+	if want := time.Date(2026, time.November, 2, 9, 0, 0, 0, time.UTC); !next.Equal(want) {
+		t.Errorf("first Monday after 2026-10-18 = %v, want %v", next, want)
+	}
+}
+
+// Swap in a config for one test
+func setTestConfig(t *testing.T, cfg *Config) {
+	t.Helper()
+	configMu.Lock()
+	saved := cachedConfig
+	cachedConfig = cfg
+	configMu.Unlock()
+	t.Cleanup(func() {
+		configMu.Lock()
+		cachedConfig = saved
+		configMu.Unlock()
+	})
+}
+
+func TestTestSonarrHandlerSavedKey(t *testing.T) {
+	var gotKey string
+	sonarr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey = r.Header.Get("X-Api-Key")
+		w.Write([]byte("{}"))
+	}))
+	defer sonarr.Close()
+
+	tests := []struct {
+		name     string
+		savedURL string
+		url      string
+		apiKey   string
+		wantKey  string
+		wantOK   bool
+	}{
+		{"saved server", sonarr.URL, sonarr.URL, secretMask, "saved-key", true},
+		{"saved server, trailing slash", sonarr.URL + "/", sonarr.URL, secretMask, "saved-key", true},
+		{"other server", "http://sonarr.lan:8989", sonarr.URL, secretMask, "", false},
+		{"nothing saved", "", sonarr.URL, secretMask, "", false},
+		{"typed key, other server", "http://sonarr.lan:8989", sonarr.URL, "typed-key", "typed-key", true},
+	}
+	for _, tt := range tests {
+		setTestConfig(t, &Config{SonarrURL: tt.savedURL, SonarrAPIKey: "saved-key"})
+		gotKey = ""
+
+		body, _ := json.Marshal(map[string]string{"url": tt.url, "api_key": tt.apiKey})
+		w := httptest.NewRecorder()
+		testSonarrHandler(w, httptest.NewRequest("POST", "/api/test-sonarr", bytes.NewReader(body)))
+
+		var resp struct {
+			Success bool   `json:"success"`
+			Message string `json:"message"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if resp.Success != tt.wantOK || gotKey != tt.wantKey {
+			t.Errorf("%s: success %v, server got key %q; want %v, %q (%s)", tt.name, resp.Success, gotKey, tt.wantOK, tt.wantKey, resp.Message)
+		}
+		if !tt.wantOK && !strings.Contains(resp.Message, "different server") {
+			t.Errorf("%s: message %q", tt.name, resp.Message)
+		}
+	}
+}
+
+func TestTestEmailHandlerOtherServer(t *testing.T) {
+	setTestConfig(t, &Config{MailgunSMTP: "smtp.mailgun.org", MailgunPort: "587", MailgunUser: "postmaster", MailgunPass: "saved-pass"})
+
+	// Would receive the saved password in SMTP AUTH if it were substituted
+	body, _ := json.Marshal(map[string]string{"smtp": "127.0.0.1", "port": "1", "user": "postmaster", "pass": secretMask})
+	w := httptest.NewRecorder()
+	testEmailHandler(w, httptest.NewRequest("POST", "/api/test-email", bytes.NewReader(body)))
+	if !strings.Contains(w.Body.String(), "Enter the password to test a different server") {
+		t.Errorf("response = %s", w.Body.String())
+	}
+}
+
+func TestTestSecret(t *testing.T) {
+	tests := []struct {
+		value, target, savedTarget string
+		want                       string
+		ok                         bool
+	}{
+		{"typed", "http://a", "http://b", "typed", true},
+		{secretMask, "http://a:8989", "http://a:8989", "saved", true},
+		{secretMask, "HTTP://A:8989/", "http://a:8989", "saved", true},
+		{secretMask, "http://a:8989", "http://a:8990", "", false},
+		{secretMask, "http://evil.example.com", "", "", false},
+		{secretMask, "smtp.example.com:25", "smtp.example.com:587", "", false},
+		{secretMask, "smtp.example.com:587", "smtp.example.com:587", "saved", true},
+	}
+	for _, tt := range tests {
+		got, ok := testSecret(tt.value, tt.target, tt.savedTarget, "saved")
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("testSecret(%q, %q, %q) = %q, %v; want %q, %v", tt.value, tt.target, tt.savedTarget, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// Run the test from an empty directory (the app keeps .env and the secrets store in the working directory)
+func chdirTemp(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+func TestSecretsKeySource(t *testing.T) {
+	dir := chdirTemp(t)
+	creds := filepath.Join(dir, "creds")
+	os.Mkdir(creds, 0700)
+
+	tests := []struct {
+		name       string
+		env        string
+		credential string
+		keyFile    string
+		want       string
+		wantSource string
+	}{
+		{"nothing", "", "", "", "", ""},
+		{"key file", "", "", "file-key", "file-key", SourceEnvFile},
+		{"credential over key file", "", "cred-key", "file-key", "cred-key", SourceCredential},
+		{"environment over credential", "env-key", "cred-key", "file-key", "env-key", SourceEnv},
+	}
+	for _, tt := range tests {
+		t.Setenv("SECRETS_KEY", tt.env)
+		t.Setenv("CREDENTIALS_DIRECTORY", creds)
+		os.Remove(filepath.Join(creds, "SECRETS_KEY"))
+		os.Remove(defaultSecretsKey)
+		if tt.credential != "" {
+			os.WriteFile(filepath.Join(creds, "SECRETS_KEY"), []byte(tt.credential), 0600)
+		}
+		if tt.keyFile != "" {
+			os.WriteFile(defaultSecretsKey, []byte(tt.keyFile), 0600)
+		}
+
+		got, source, err := secretsKeySource()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if strings.TrimSpace(got) != tt.want || source != tt.wantSource {
+			t.Errorf("%s: secretsKeySource() = %q, %q; want %q, %q", tt.name, got, source, tt.want, tt.wantSource)
+		}
+	}
+}
+
+// Keep the data files of one test in a temporary directory
+func setTestDataDir(t *testing.T) {
+	t.Helper()
+	setTestConfig(t, &Config{DataDir: t.TempDir()})
+}
+
+func TestPartialDeliveryAcrossCappedWindows(t *testing.T) {
+	setTestDataDir(t)
+	p := Profile{ID: "weekly", Lookback: "7d"}
+	lastEnd := time.Now().Add(-40 * 24 * time.Hour).Truncate(time.Second)
+	if err := writeJSONFile("state.json", map[string]RunState{"weekly": {LastSuccess: lastEnd, WindowEnd: lastEnd}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The capped window start moves with the clock between an attempt and its retry
+	first := time.Now()
+	retry := first.Add(10 * time.Minute)
+	startFirst, _ := newsletterWindow(p, first)
+	startRetry, _ := newsletterWindow(p, retry)
+	if startFirst.Equal(startRetry) {
+		t.Fatalf("expected the capped start to move, got %v twice", startFirst)
+	}
+
+	recordPartialDelivery("weekly", []string{"aaa", "bbb"})
+	recordPartialDelivery("weekly", []string{"ccc"})
+	got := deliveredEarlier("weekly")
+	for _, key := range []string{"aaa", "bbb", "ccc"} {
+		if !got[key] {
+			t.Errorf("retry would resend to %s (delivered earlier: %v)", key, got)
+		}
+	}
+
+	recordSuccessfulRun("weekly", nil, retry, nil)
+	if got := deliveredEarlier("weekly"); len(got) != 0 {
+		t.Errorf("after a successful send, delivered earlier = %v, want none", got)
+	}
+}
+
+func TestPartialDeliveryFirstSend(t *testing.T) {
+	setTestDataDir(t)
+	recordPartialDelivery("new", []string{"aaa"})
+	if got := deliveredEarlier("new"); !got["aaa"] {
+		t.Errorf("delivered earlier = %v, want aaa", got)
+	}
+	if got := deliveredEarlier("other"); len(got) != 0 {
+		t.Errorf("other profile: delivered earlier = %v, want none", got)
+	}
+}
+
+func TestCheckScheduleIntervalCombined(t *testing.T) {
+	tests := []struct {
+		specs []string
+		valid bool
+	}{
+		{[]string{"0 9 * * *"}, true},
+		{[]string{"0 9 * * *", "30 9 * * *"}, false},
+		{[]string{"0 9 * * *", "30 8 * * *"}, false},
+		{[]string{"0 9 * * *", "0 10 * * *"}, true},
+		{[]string{"0 9 * * *", "0 9 * * *"}, true}, // the same instant runs once
+		{[]string{"0 9 * * 0", "0 9 * * 3"}, true},
+		{[]string{"0 9 * * *", "@every 1h"}, true},
+		{[]string{"0 9 1 1 *", "30 9 * * *"}, false},
+		{[]string{"0 9 * * 1#1", "30 9 * * 1"}, false},
+		{[]string{"0 9 * * 1#1", "0 9 * * 2#1"}, true},
+		{[]string{"CRON_TZ=UTC 0 9 * * *", "CRON_TZ=Europe/London 30 9 * * *"}, false},
+	}
+	for _, tt := range tests {
+		var scheds []cron.Schedule
+		for _, spec := range tt.specs {
+			sched, err := parseSchedule(spec)
+			if err != nil {
+				t.Fatalf("parseSchedule(%q): %v", spec, err)
+			}
+			scheds = append(scheds, sched)
+		}
+		err := checkScheduleInterval(time.UTC, scheds...)
+		if (err == nil) != tt.valid {
+			t.Errorf("checkScheduleInterval(%q) error = %v, want valid %v", tt.specs, err, tt.valid)
+		}
+	}
+}
+
+func TestRequestIsHTTPS(t *testing.T) {
+	setTestConfig(t, &Config{TrustedProxies: []string{"10.0.0.1"}})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		proto      string
+		want       bool
+	}{
+		{"plain request", "203.0.113.7:5000", "", false},
+		{"trusted proxy says https", "10.0.0.1:5000", "https", true},
+		{"trusted proxy says http", "10.0.0.1:5000", "http", false},
+		{"untrusted peer says https", "203.0.113.7:5000", "https", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/login", nil)
+		r.RemoteAddr = tt.remoteAddr
+		if tt.proto != "" {
+			r.Header.Set("X-Forwarded-Proto", tt.proto)
+		}
+		if got := requestIsHTTPS(r); got != tt.want {
+			t.Errorf("%s: requestIsHTTPS = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	r := httptest.NewRequest("GET", "https://newslettar.example.com/login", nil)
+	if !requestIsHTTPS(r) {
+		t.Error("direct TLS request not seen as HTTPS")
+	}
+}
+
+func TestSignupEndpointsAgree(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		open bool
+	}{
+		{"disabled", Config{}, false},
+		{"enabled without a public URL", Config{SignupEnabled: true}, false},
+		{"enabled with a public URL", Config{SignupEnabled: true, PublicURL: "https://news.example.com"}, true},
+	}
+	for _, tt := range tests {
+		cfg := tt.cfg
+		cfg.DataDir = t.TempDir()
+		setTestConfig(t, &cfg)
+
+		for _, path := range []string{"/subscribe", "/subscribe/confirm"} {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", path, nil)
+			if path == "/subscribe" {
+				subscribePageHandler(w, r)
+			} else {
+				confirmSubscriptionHandler(w, r)
+			}
+			if open := w.Code != http.StatusNotFound; open != tt.open {
+				t.Errorf("%s: GET %s = %d, want open %v", tt.name, path, w.Code, tt.open)
+			}
+		}
+	}
+}
+
+// Upcoming series and movies covering every filter: ratings, tags and untagged items
+func sampleNewsletter() NewsletterData {
+	series := func(title string, tvdbID int, rating string, tags ...string) SeriesGroup {
+		return SeriesGroup{
+			SeriesTitle: title, TvdbID: tvdbID, Rating: rating, Tags: tags,
+			Episodes: []Episode{{SeriesTitle: title, SeasonNum: 1, EpisodeNum: 1, TvdbID: tvdbID, Rating: rating, Tags: tags}},
+		}
+	}
+	return NewsletterData{
+		UpcomingSeriesGroups: []SeriesGroup{
+			series("Bluey", 1, "TV-Y", "kids"),
+			series("Succession", 2, "TV-MA", "adults"),
+			series("Planet Earth", 3, ""),
+		},
+		UpcomingMovies: []Movie{
+			{Title: "Moana", TmdbID: 10, Rating: "G", Tags: []string{"kids"}},
+			{Title: "Heat", TmdbID: 11, Rating: "R", Tags: []string{"adults"}},
+			{Title: "Home Movie", TmdbID: 12},
+		},
+	}
+}
+
+// Titles left in each section, for comparing filter results
+func sectionTitles(data NewsletterData) string {
+	var parts []string
+	list := func(section string, titles []string) {
+		parts = append(parts, section+": "+strings.Join(titles, ", "))
+	}
+	groupTitles := func(groups []SeriesGroup) []string {
+		titles := []string{}
+		for _, g := range groups {
+			titles = append(titles, g.SeriesTitle)
+		}
+		return titles
+	}
+	movieTitles := func(movies []Movie) []string {
+		titles := []string{}
+		for _, m := range movies {
+			titles = append(titles, m.Title)
+		}
+		return titles
+	}
+	list("series", groupTitles(data.UpcomingSeriesGroups))
+	list("movies", movieTitles(data.UpcomingMovies))
+	list("followed series", groupTitles(data.FollowedSeriesGroups))
+	list("followed movies", movieTitles(data.FollowedMovies))
+	return strings.Join(parts, "; ")
+}
+
+func TestRatingAllowed(t *testing.T) {
+	tests := []struct {
+		rating       string
+		maxRating    string
+		allowUnrated bool
+		want         bool
+	}{
+		{"R", "", false, true},
+		{"", "", false, true},
+		{"G", "PG", false, true},
+		{"PG", "PG", false, true},
+		{"PG-13", "PG", false, false},
+		{"TV-14", "PG-13", false, true},
+		{"TV-MA", "PG-13", false, false},
+		{"tv-y7", "TV-PG", false, true},
+		{" PG ", "PG", false, true},
+		{"12A", "PG-13", false, true},
+		{"18", "R", false, false},
+		{"", "PG", false, false},
+		{"", "PG", true, true},
+		{"Not Rated", "PG", false, false},
+		{"Not Rated", "PG", true, true},
+	}
+	for _, tt := range tests {
+		if got := ratingAllowed(tt.rating, tt.maxRating, tt.allowUnrated); got != tt.want {
+			t.Errorf("ratingAllowed(%q, %q, %v) = %v, want %v", tt.rating, tt.maxRating, tt.allowUnrated, got, tt.want)
+		}
+	}
+}
+
+func TestFilterNewsletterByRating(t *testing.T) {
+	tests := []struct {
+		name string
+		rcpt Recipient
+		want string
+	}{
+		{"no limit", Recipient{},
+			"series: Bluey, Succession, Planet Earth; movies: Moana, Heat, Home Movie; followed series: ; followed movies: "},
+		{"PG", Recipient{MaxRating: "PG"},
+			"series: Bluey; movies: Moana; followed series: ; followed movies: "},
+		{"PG with unrated", Recipient{MaxRating: "PG", AllowUnrated: true},
+			"series: Bluey, Planet Earth; movies: Moana, Home Movie; followed series: ; followed movies: "},
+		{"R", Recipient{MaxRating: "R"},
+			"series: Bluey, Succession; movies: Moana, Heat; followed series: ; followed movies: "},
+		// Following a title doesn't lift the rating limit
+		{"PG following an R movie", Recipient{MaxRating: "PG", FollowedMovies: []int{11}},
+			"series: Bluey; movies: Moana; followed series: ; followed movies: "},
+	}
+	for _, tt := range tests {
+		if got := sectionTitles(filterNewsletterForRecipient(sampleNewsletter(), tt.rcpt)); got != tt.want {
+			t.Errorf("%s:\n got  %s\n want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGetRecipientsSubscriberWins(t *testing.T) {
+	setTestDataDir(t)
+	subs := []Subscriber{
+		{Email: "Kid@Example.com", Status: "active", MaxRating: "PG"},
+		{Email: "pending@example.com", Status: "pending"},
+	}
+	if err := writeJSONFile("subscribers.json", subs); err != nil {
+		t.Fatal(err)
+	}
+	p := Profile{Recipients: []string{"kid@example.com", "pending@example.com", "ops@example.com"}, AllSubscribers: true}
+
+	got := getRecipients(getConfig(), p)
+	byEmail := make(map[string]Recipient)
+	for _, rcpt := range got {
+		byEmail[strings.ToLower(rcpt.Email)] = rcpt
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d recipients (%v), want 3", len(got), got)
+	}
+	if byEmail["kid@example.com"].MaxRating != "PG" {
+		t.Errorf("direct address replaced the subscriber's filters: %+v", byEmail["kid@example.com"])
+	}
+	if _, ok := byEmail["pending@example.com"]; !ok {
+		t.Error("a pending subscriber listed directly should still get the newsletter")
+	}
+}
+
+func TestTagsAllowed(t *testing.T) {
+	tests := []struct {
+		itemTags []string
+		tags     []string
+		untagged bool
+		want     bool
+	}{
+		{nil, nil, false, true},
+		{[]string{"adults"}, nil, false, true},
+		{[]string{"kids"}, []string{"kids"}, false, true},
+		{[]string{"Kids"}, []string{"kids"}, false, true},
+		{[]string{"adults"}, []string{"kids"}, false, false},
+		{[]string{"adults", "kids"}, []string{"kids"}, false, true},
+		{nil, []string{"kids"}, false, false},
+		{nil, []string{"kids"}, true, true},
+		{[]string{"adults"}, []string{"kids"}, true, false},
+	}
+	for _, tt := range tests {
+		rcpt := Recipient{Tags: tt.tags, IncludeUntagged: tt.untagged}
+		if got := tagsAllowed(tt.itemTags, rcpt); got != tt.want {
+			t.Errorf("tagsAllowed(%q, tags %q, untagged %v) = %v, want %v", tt.itemTags, tt.tags, tt.untagged, got, tt.want)
+		}
+	}
+}
+
+func TestFilterNewsletterByTags(t *testing.T) {
+	tests := []struct {
+		name string
+		rcpt Recipient
+		want string
+	}{
+		{"kids group", Recipient{Tags: []string{"kids"}},
+			"series: Bluey; movies: Moana; followed series: ; followed movies: "},
+		{"kids group with untagged", Recipient{Tags: []string{"kids"}, IncludeUntagged: true},
+			"series: Bluey, Planet Earth; movies: Moana, Home Movie; followed series: ; followed movies: "},
+		{"both groups", Recipient{Tags: []string{"kids", "adults"}},
+			"series: Bluey, Succession; movies: Moana, Heat; followed series: ; followed movies: "},
+		// Followed titles come through whatever the group tags
+		{"kids group following Heat", Recipient{Tags: []string{"kids"}, FollowedMovies: []int{11}},
+			"series: Bluey; movies: Moana; followed series: ; followed movies: Heat"},
+	}
+	for _, tt := range tests {
+		if got := sectionTitles(filterNewsletterForRecipient(sampleNewsletter(), tt.rcpt)); got != tt.want {
+			t.Errorf("%s:\n got  %s\n want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGetRecipientsGroups(t *testing.T) {
+	setTestDataDir(t)
+	groups := []Group{
+		{Name: "Kids", Tags: []string{"kids"}, MaxRating: "PG"},
+		{Name: "Family", Tags: []string{"family"}, IncludeUntagged: true, MaxRating: "PG-13"},
+	}
+	subs := []Subscriber{
+		{Email: "kid@example.com", Status: "active", Groups: []string{"kids"}},
+		{Email: "teen@example.com", Status: "active", Groups: []string{"Family"}, MaxRating: "R"},
+		{Email: "strict@example.com", Status: "active", Groups: []string{"Family"}, MaxRating: "G"},
+		{Email: "both@example.com", Status: "active", Groups: []string{"kids", "family"}},
+		{Email: "lost@example.com", Status: "active", Groups: []string{"gone"}},
+		{Email: "nogroup@example.com", Status: "active"},
+	}
+	if err := writeJSONFile("groups.json", groups); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeJSONFile("subscribers.json", subs); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		email     string
+		tags      string
+		untagged  bool
+		maxRating string
+	}{
+		{"kid@example.com", "kids", false, "PG"},
+		{"teen@example.com", "family", true, "PG-13"}, // the group's limit is stricter
+		{"strict@example.com", "family", true, "G"},   // the subscriber's limit is stricter
+		{"both@example.com", "kids,family", true, "PG"},
+		{"lost@example.com", "", false, ""},
+	}
+
+	// Profiles reach subscribers in their groups only
+	p := Profile{Groups: []string{"KIDS", "family", "gone"}}
+	byEmail := make(map[string]Recipient)
+	for _, rcpt := range getRecipients(getConfig(), p) {
+		byEmail[rcpt.Email] = rcpt
+	}
+	if _, ok := byEmail["nogroup@example.com"]; ok {
+		t.Error("a subscriber outside the profile's groups was included")
+	}
+	for _, tt := range tests {
+		rcpt, ok := byEmail[tt.email]
+		if !ok {
+			t.Errorf("%s missing from the recipients", tt.email)
+			continue
+		}
+		if tags := strings.Join(rcpt.Tags, ","); tags != tt.tags || rcpt.IncludeUntagged != tt.untagged || rcpt.MaxRating != tt.maxRating {
+			t.Errorf("%s: tags %q, untagged %v, max rating %q; want %q, %v, %q", tt.email, tags, rcpt.IncludeUntagged, rcpt.MaxRating, tt.tags, tt.untagged, tt.maxRating)
+		}
+	}
+}
+
+func TestFilterNewsletterFollowsAndMutes(t *testing.T) {
+	tests := []struct {
+		name string
+		rcpt Recipient
+		want string
+	}{
+		{"mute a series and a movie", Recipient{MutedSeries: []int{2}, MutedMovies: []int{11}},
+			"series: Bluey, Planet Earth; movies: Moana, Home Movie; followed series: ; followed movies: "},
+		{"follow a series and a movie", Recipient{FollowedSeries: []int{3}, FollowedMovies: []int{10}},
+			"series: Bluey, Succession; movies: Heat, Home Movie; followed series: Planet Earth; followed movies: Moana"},
+		{"muting beats following", Recipient{FollowedSeries: []int{1}, MutedSeries: []int{1}},
+			"series: Succession, Planet Earth; movies: Moana, Heat, Home Movie; followed series: ; followed movies: "},
+		{"unknown IDs change nothing", Recipient{FollowedSeries: []int{99}, MutedMovies: []int{99}},
+			"series: Bluey, Succession, Planet Earth; movies: Moana, Heat, Home Movie; followed series: ; followed movies: "},
+	}
+	for _, tt := range tests {
+		if got := sectionTitles(filterNewsletterForRecipient(sampleNewsletter(), tt.rcpt)); got != tt.want {
+			t.Errorf("%s:\n got  %s\n want %s", tt.name, got, tt.want)
+		}
+	}
+
+	// Followed titles are pulled from both the upcoming and the downloaded sections
+	data := sampleNewsletter()
+	data.DownloadedSeriesGroups = []SeriesGroup{{SeriesTitle: "Bluey", TvdbID: 1, Rating: "TV-Y",
+		Episodes: []Episode{{SeriesTitle: "Bluey", SeasonNum: 1, EpisodeNum: 2, TvdbID: 1}}}}
+	got := filterNewsletterForRecipient(data, Recipient{FollowedSeries: []int{1}})
+	if len(got.DownloadedSeriesGroups) != 0 || len(got.FollowedSeriesGroups) != 1 || len(got.FollowedSeriesGroups[0].Episodes) != 2 {
+		t.Errorf("followed series: downloaded %v, followed %v", got.DownloadedSeriesGroups, got.FollowedSeriesGroups)
+	}
+}
+
+func TestParseWindowDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		valid bool
+	}{
+		{"7d", 7 * 24 * time.Hour, true},
+		{"36h", 36 * time.Hour, true},
+		{"1d12h", 36 * time.Hour, true},
+		{" 2d ", 48 * time.Hour, true},
+		{"90m", 90 * time.Minute, true},
+		{"0d", 0, true},
+		{"", 0, false},
+		{"d", 0, false},
+		{"-1d", 0, false},
+		{"-5h", 0, false},
+		{"7 days", 0, false},
+		{"1w", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseWindowDuration(tt.value)
+		if (err == nil) != tt.valid || (tt.valid && got != tt.want) {
+			t.Errorf("parseWindowDuration(%q) = %v, %v; want %v, valid %v", tt.value, got, err, tt.want, tt.valid)
+		}
+	}
+}
+
+func TestNewsletterWindow(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 9, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name      string
+		lookback  string
+		state     *RunState
+		wantStart time.Time
+	}{
+		{"first send", "", nil, now.Add(-7 * day)},
+		{"first send, 36h lookback", "36h", nil, now.Add(-36 * time.Hour)},
+		{"since the last send", "7d", &RunState{WindowEnd: now.Add(-3 * day)}, now.Add(-3 * day)},
+		{"last success without a window end", "7d", &RunState{LastSuccess: now.Add(-2 * day)}, now.Add(-2 * day)},
+		{"window end in the future", "7d", &RunState{WindowEnd: now.Add(time.Hour)}, now.Add(-7 * day)},
+		{"idle for months: capped", "7d", &RunState{WindowEnd: now.Add(-120 * day)}, now.Add(-maxNewsletterWindow)},
+		{"exactly at the cap", "7d", &RunState{WindowEnd: now.Add(-maxNewsletterWindow)}, now.Add(-maxNewsletterWindow)},
+		{"lookback beyond the cap", "60d", &RunState{WindowEnd: now.Add(-120 * day)}, now.Add(-60 * day)},
+		{"first send, lookback beyond the cap", "60d", nil, now.Add(-60 * day)},
+	}
+	for _, tt := range tests {
+		setTestDataDir(t)
+		if tt.state != nil {
+			if err := writeJSONFile("state.json", map[string]RunState{"weekly": *tt.state}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		p := Profile{ID: "weekly", Lookback: tt.lookback}
+		start, end := newsletterWindow(p, now)
+		if !start.Equal(tt.wantStart) || !end.Equal(now) {
+			t.Errorf("%s: window %v – %v, want %v – %v", tt.name, start, end, tt.wantStart, now)
+		}
+	}
+}
+
+func TestCapWindowStart(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 9, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	tests := []struct {
+		lookback string
+		start    time.Time
+		want     time.Time
+	}{
+		{"7d", now.Add(-day), now.Add(-day)},
+		{"7d", now.Add(-30 * day), now.Add(-30 * day)},
+		{"7d", now.Add(-32 * day), now.Add(-maxNewsletterWindow)},
+		{"7d", time.Time{}, now.Add(-maxNewsletterWindow)},
+		{"45d", now.Add(-40 * day), now.Add(-40 * day)},
+		{"45d", now.Add(-50 * day), now.Add(-45 * day)},
+	}
+	for _, tt := range tests {
+		p := Profile{Lookback: tt.lookback}
+		if got := capWindowStart(p, tt.start, now); !got.Equal(tt.want) {
+			t.Errorf("capWindowStart(lookback %s, %v) = %v, want %v", tt.lookback, tt.start, got, tt.want)
+		}
+	}
+}
+
+func TestHistoryWindowOwedSections(t *testing.T) {
+	setTestDataDir(t)
+	now := time.Date(2026, time.October, 18, 9, 0, 0, 0, time.UTC)
+	start := now.Add(-7 * 24 * time.Hour)
+	state := map[string]RunState{"weekly": {
+		WindowEnd: start,
+		OwedSince: map[string]time.Time{
+			SectionDownloadedMovies: start.Add(-7 * 24 * time.Hour),  // failed last time
+			SectionDownloadedSeries: start.Add(24 * time.Hour),       // later than the window: ignored
+			SectionUpcomingMovies:   start.Add(-14 * 24 * time.Hour), // not a history section: ignored
+		},
+	}}
+	if err := writeJSONFile("state.json", state); err != nil {
+		t.Fatal(err)
+	}
+
+	since := historyWindow(Profile{ID: "weekly"}, start, now)
+	if !since[SectionDownloadedSeries].Equal(start) {
+		t.Errorf("series since %v, want the window start %v", since[SectionDownloadedSeries], start)
+	}
+	if want := start.Add(-7 * 24 * time.Hour); !since[SectionDownloadedMovies].Equal(want) {
+		t.Errorf("movies since %v, want the owed start %v", since[SectionDownloadedMovies], want)
+	}
+	if _, ok := since[SectionUpcomingMovies]; ok {
+		t.Error("upcoming section got a history start")
+	}
+
+	// Owed content is capped like any other window
+	state["weekly"].OwedSince[SectionDownloadedMovies] = now.Add(-90 * 24 * time.Hour)
+	writeJSONFile("state.json", state)
+	since = historyWindow(Profile{ID: "weekly"}, start, now)
+	if want := now.Add(-maxNewsletterWindow); !since[SectionDownloadedMovies].Equal(want) {
+		t.Errorf("movies since %v, want the cap %v", since[SectionDownloadedMovies], want)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	window := 100 * time.Millisecond
+	l := newRateLimiter(3, window)
+
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"203.0.113.1", true},
+		{"203.0.113.1", true},
+		{"203.0.113.1", true},
+		{"203.0.113.1", false}, // fourth attempt in the window
+		{"203.0.113.2", true},  // other keys have their own budget
+		{"203.0.113.1", false},
+	}
+	for i, tt := range tests {
+		if got := l.allow(tt.key); got != tt.want {
+			t.Errorf("attempt %d from %s: allow = %v, want %v", i+1, tt.key, got, tt.want)
+		}
+	}
+
+	time.Sleep(window + 20*time.Millisecond)
+	if !l.allow("203.0.113.1") {
+		t.Error("still limited after the window passed")
+	}
+
+	// Keys idle for a whole window are forgotten
+	l.mu.Lock()
+	_, kept := l.attempts["203.0.113.2"]
+	l.mu.Unlock()
+	if kept {
+		t.Error("idle key was not evicted")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	setTestConfig(t, &Config{TrustedProxies: []string{"10.0.0.0/8"}})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{"direct", "203.0.113.7:5000", nil, "203.0.113.7"},
+		{"untrusted peer can't forward", "203.0.113.7:5000", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"trusted proxy", "10.0.0.1:5000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"trusted proxy, no header", "10.0.0.1:5000", nil, "10.0.0.1"},
+		{"chain of trusted proxies", "10.0.0.1:5000", []string{"198.51.100.1, 10.0.0.3, 10.0.0.2"}, "198.51.100.1"},
+		{"forged entries before the client", "10.0.0.1:5000", []string{"1.2.3.4, 198.51.100.1"}, "198.51.100.1"},
+		{"forged entry claiming a proxy", "10.0.0.1:5000", []string{"10.0.0.9, 198.51.100.1"}, "198.51.100.1"},
+		{"several headers", "10.0.0.1:5000", []string{"1.2.3.4", "198.51.100.1, 10.0.0.2"}, "198.51.100.1"},
+		{"garbage hop", "10.0.0.1:5000", []string{"198.51.100.1, not-an-ip"}, "10.0.0.1"},
+		{"all hops trusted", "10.0.0.1:5000", []string{"10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/api/subscribe", nil)
+		r.RemoteAddr = tt.remoteAddr
+		for _, v := range tt.forwarded {
+			r.Header.Add("X-Forwarded-For", v)
+		}
+		if got := clientIP(r); got != tt.want {
+			t.Errorf("%s: clientIP = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestICSEscape(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Plain title", "Plain title"},
+		{"Law & Order; SVU", "Law & Order\\; SVU"},
+		{"One, Two", "One\\, Two"},
+		{`C:\path`, `C:\\path`},
+		{"line one\nline two", "line one\\nline two"},
+		{"line one\r\nline two", "line one\\nline two"},
+		{"line one\rline two", "line one\\nline two"},
+		{`\;`, `\\\;`},
+	}
+	for _, tt := range tests {
+		if got := icsEscape(tt.in); got != tt.want {
+			t.Errorf("icsEscape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBuildCalendarFolding(t *testing.T) {
+	long := strings.Repeat("Épisode très long ", 12) // multi-byte runes across several folds
+	data := NewsletterData{
+		UpcomingSeriesGroups: []SeriesGroup{{SeriesTitle: "Show", Episodes: []Episode{
+			{SeriesTitle: "Show", SeasonNum: 1, EpisodeNum: 2, Title: long, AirDate: "2026-10-20", TvdbID: 7},
+			{SeriesTitle: "Show", SeasonNum: 1, EpisodeNum: 3, Title: "Timed", AirTime: time.Date(2026, 10, 21, 20, 0, 0, 0, time.UTC), Runtime: 45, TvdbID: 7},
+			{SeriesTitle: "Show", SeasonNum: 1, EpisodeNum: 4, Title: "No date", TvdbID: 7},
+			{SeriesTitle: "Show", SeasonNum: 1, EpisodeNum: 1, Title: "Already here", AirDate: "2026-10-10", Downloaded: true, TvdbID: 7},
+		}}},
+		UpcomingMovies: []Movie{
+			{Title: "Dune, Part Three", Year: 2026, ReleaseDate: "2026-12-18", TmdbID: 9},
+			{Title: "Undated", TmdbID: 10},
+		},
+	}
+	ics := string(buildCalendar("Weekly; TV, Movies", data))
+
+	if !strings.HasSuffix(ics, "END:VCALENDAR\r\n") {
+		t.Error("calendar doesn't end with END:VCALENDAR and CRLF")
+	}
+	physical := strings.Split(strings.TrimSuffix(ics, "\r\n"), "\r\n")
+	for _, l := range physical {
+		if len(l) > 75 {
+			t.Errorf("line of %d octets: %q", len(l), l)
+		}
+		if !utf8.ValidString(l) {
+			t.Errorf("fold split a UTF-8 sequence: %q", l)
+		}
+		if strings.ContainsAny(l, "\r\n") {
+			t.Errorf("bare line break in %q", l)
+		}
+	}
+
+	// Unfolding gives back the logical lines
+	unfolded := strings.ReplaceAll(ics, "\r\n ", "")
+	for _, want := range []string{
+		"X-WR-CALNAME:Weekly\\; TV\\, Movies\r\n",
+		"SUMMARY:Show S01E02 - " + long + "\r\n",
+		"DTSTART;VALUE=DATE:20261020\r\nDTEND;VALUE=DATE:20261021\r\n",
+		"DTSTART:20261021T200000Z\r\nDTEND:20261021T204500Z\r\n",
+		"SUMMARY:🎬 Dune\\, Part Three (2026)\r\n",
+	} {
+		if !strings.Contains(unfolded, want) {
+			t.Errorf("calendar lacks %q", want)
+		}
+	}
+	for _, unwanted := range []string{"No date", "Already here", "Undated"} {
+		if strings.Contains(unfolded, unwanted) {
+			t.Errorf("calendar has an event for %q", unwanted)
+		}
+	}
+	if begins, ends := strings.Count(ics, "BEGIN:VEVENT"), strings.Count(ics, "END:VEVENT"); begins != 3 || ends != 3 {
+		t.Errorf("%d BEGIN:VEVENT and %d END:VEVENT, want 3 each", begins, ends)
+	}
+}
+
+func TestLookupSettingPrecedence(t *testing.T) {
+	const key = "NEWSLETTAR_TEST_SETTING"
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, "env_file")
+	if err := os.WriteFile(envFile, []byte("from-env-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	credDir := filepath.Join(dir, "credentials")
+	if err := os.Mkdir(credDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(credDir, key), []byte("from-credential\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	savedFlags := flagSettings
+	t.Cleanup(func() { flagSettings = savedFlags })
+
+	tests := []struct {
+		name                        string
+		flag, env, file, credential bool
+		dotenv                      bool
+		wantValue, wantSource       string
+		wantOK                      bool
+	}{
+		{"flag beats everything", true, true, true, true, true, "from-flag", SourceFlag, true},
+		{"env beats file", false, true, true, true, true, "from-env", SourceEnv, true},
+		{"file beats credential", false, false, true, true, true, "from-env-file", SourceEnvFile, true},
+		{"credential beats .env", false, false, false, true, true, "from-credential", SourceCredential, true},
+		{".env beats default", false, false, false, false, true, "from-dotenv", SourceDotenv, true},
+		{"default", false, false, false, false, false, "", SourceDefault, false},
+	}
+	for _, tt := range tests {
+		flagSettings = settingFlags{}
+		if tt.flag {
+			flagSettings[key] = "from-flag"
+		}
+		t.Setenv(key, "")
+		if tt.env {
+			t.Setenv(key, "from-env")
+		}
+		t.Setenv(key+"_FILE", "")
+		if tt.file {
+			t.Setenv(key+"_FILE", envFile)
+		}
+		t.Setenv("CREDENTIALS_DIRECTORY", "")
+		if tt.credential {
+			t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+		}
+		envMap := map[string]string{}
+		if tt.dotenv {
+			envMap[key] = "from-dotenv"
+		}
+
+		value, source, ok := lookupSetting(envMap, key)
+		if value != tt.wantValue || source != tt.wantSource || ok != tt.wantOK {
+			t.Errorf("%s: lookupSetting = %q, %q, %v; want %q, %q, %v", tt.name, value, source, ok, tt.wantValue, tt.wantSource, tt.wantOK)
+		}
+	}
+
+	// -setting KEY= blanks a setting even when the environment has it
+	flagSettings = settingFlags{key: ""}
+	t.Setenv(key, "from-env")
+	if value, source, ok := lookupSetting(nil, key); value != "" || source != SourceFlag || !ok {
+		t.Errorf("empty flag: lookupSetting = %q, %q, %v; want \"\", %q, true", value, source, ok, SourceFlag)
+	}
+}
+
+func TestLookupSettingUnreadableFile(t *testing.T) {
+	const key = "NEWSLETTAR_TEST_SETTING"
+	t.Setenv(key, "")
+	t.Setenv(key+"_FILE", filepath.Join(t.TempDir(), "missing"))
+	t.Setenv("CREDENTIALS_DIRECTORY", "")
+
+	// A broken _FILE falls through to the next source rather than blanking the setting
+	value, source, ok := lookupSetting(map[string]string{key: "from-dotenv"}, key)
+	if value != "from-dotenv" || source != SourceDotenv || !ok {
+		t.Errorf("lookupSetting = %q, %q, %v; want the .env value", value, source, ok)
+	}
+}
+
+func TestConfigHandlerSecrets(t *testing.T) {
+	chdirTemp(t)
+	setTestDataDir(t)
+	t.Setenv("SECRETS_KEY", base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32)))
+	for _, key := range []string{"SONARR_URL", "SONARR_API_KEY", "MAILGUN_PASS", "FEED_TOKEN", "CREDENTIALS_DIRECTORY"} {
+		t.Setenv(key, "")
+		t.Setenv(key+"_FILE", "")
+	}
+	savedScheduler := scheduler
+	t.Cleanup(func() {
+		if scheduler != nil && scheduler != savedScheduler {
+			scheduler.Stop()
+		}
+		scheduler = savedScheduler
+	})
+
+	saved := map[string]string{
+		"SONARR_URL":     "http://sonarr:8989",
+		"SONARR_API_KEY": "saved-key",
+		"MAILGUN_PASS":   "saved-pass",
+	}
+	tests := []struct {
+		name     string
+		body     string
+		wantKey  string // SONARR_API_KEY afterwards, "" when removed
+		wantPass string // MAILGUN_PASS afterwards
+	}{
+		{"mask keeps", `{"sonarr_api_key":"` + secretMask + `","mailgun_pass":"` + secretMask + `"}`, "saved-key", "saved-pass"},
+		{"empty keeps", `{"sonarr_api_key":"","sonarr_url":"http://sonarr:8989"}`, "saved-key", "saved-pass"},
+		{"new value replaces", `{"sonarr_api_key":"new-key","mailgun_pass":"` + secretMask + `"}`, "new-key", "saved-pass"},
+		{"clear removes", `{"clear":["sonarr_api_key"]}`, "", "saved-pass"},
+		{"clear then mask stays cleared", `{"clear":["sonarr_api_key"],"sonarr_api_key":"` + secretMask + `"}`, "", "saved-pass"},
+		{"clear then new value", `{"clear":["mailgun_pass"],"mailgun_pass":"new-pass"}`, "saved-key", "new-pass"},
+	}
+	for _, tt := range tests {
+		if err := writeEnvFile(saved); err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		configHandler(w, httptest.NewRequest("POST", "/api/config", strings.NewReader(tt.body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", tt.name, w.Code, w.Body.String())
+		}
+
+		envMap := readEnvFile()
+		if got := envMap["SONARR_API_KEY"]; got != tt.wantKey {
+			t.Errorf("%s: SONARR_API_KEY = %q, want %q", tt.name, got, tt.wantKey)
+		}
+		if got := envMap["MAILGUN_PASS"]; got != tt.wantPass {
+			t.Errorf("%s: MAILGUN_PASS = %q, want %q", tt.name, got, tt.wantPass)
+		}
+		if plain, _ := os.ReadFile(".env"); bytes.Contains(plain, []byte("saved-pass")) || bytes.Contains(plain, []byte("new-")) {
+			t.Errorf("%s: .env holds a plaintext secret", tt.name)
+		}
+	}
+
+	// GET masks set secrets, leaves unset ones empty and shows the rest as they are
+	if err := writeEnvFile(saved); err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	configHandler(w, httptest.NewRequest("GET", "/api/config", nil))
+	var got map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	for field, want := range map[string]string{
+		"sonarr_api_key": secretMask,
+		"mailgun_pass":   secretMask,
+		"radarr_api_key": "",
+		"feed_token":     "",
+		"sonarr_url":     "http://sonarr:8989",
+	} {
+		if got[field] != want {
+			t.Errorf("GET %s = %v, want %q", field, got[field], want)
+		}
+	}
+	if strings.Contains(w.Body.String(), "saved-") {
+		t.Error("GET response contains a stored secret")
+	}
+}